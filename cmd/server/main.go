@@ -2,25 +2,52 @@ package main
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata" // profile time zones must resolve in minimal images
 
 	"dekamond/internal/config"
+	webhookdomain "dekamond/internal/domain/webhook"
 	apphttp "dekamond/internal/http"
+	"dekamond/internal/infra/auditlog"
 	"dekamond/internal/infra/cache"
 	"dekamond/internal/infra/db/postgres"
+	postgresrepositories "dekamond/internal/infra/db/postgres/repositories"
+	"dekamond/internal/infra/jwtkeys"
+	"dekamond/internal/infra/otpsender"
+	"dekamond/internal/infra/ratelimit"
+	webhookinfra "dekamond/internal/infra/webhook"
+	"dekamond/internal/logging"
+	"dekamond/internal/metrics"
+	userusecase "dekamond/internal/usecase/user"
+	webhookusecase "dekamond/internal/usecase/webhook"
 )
 
 func main() {
-    conf := config.Load()
+    conf, warnings := config.Load()
+    logger := logging.New(os.Stdout, conf.LogLevel)
+    slog.SetDefault(logger)
+    for _, w := range warnings {
+        logger.Warn(w)
+    }
+    // Real codes going out means this is no development setup, and public
+    // secrets would let anyone reverse stored OTPs and audit hashes.
+    if len(conf.DevSecrets) > 0 && conf.OTPDelivery.SMSDriver != "dev" {
+        fatal("refusing to start with development secrets", fmt.Errorf("set %s", strings.Join(conf.DevSecrets, ", ")))
+    }
+    if conf.MetricsToken == "" {
+        logger.Warn("METRICS_TOKEN is not set, /metrics is readable by anyone who can reach the server")
+    }
 
     pg, err := postgres.NewPostgres(conf)
     if err != nil {
-        log.Fatalf("failed to connect to postgres: %v", err)
+        fatal("failed to connect to postgres", err)
     }
     defer pg.Close()
 
@@ -35,11 +62,43 @@ func main() {
     redis := cache.NewRedis(conf)
     defer func() {
         if err := redis.Close(); err != nil {
-            log.Println("failed to close redis:", err)
+            slog.Error("failed to close redis", "err", err)
         }
     }()
 
-    router := apphttp.NewRouter(conf, pg, redis)
+    otpSender, err := otpsender.New(conf)
+    if err != nil {
+        fatal("failed to configure otp delivery", err)
+    }
+
+    keys, err := jwtkeys.New(conf)
+    if err != nil {
+        fatal("failed to load jwt keys", err)
+    }
+    go reloadKeysOnHangup(keys)
+
+    limiter, err := ratelimit.New(conf, redis)
+    if err != nil {
+        fatal("failed to configure rate limiting", err)
+    }
+
+    metrics.Registry.MustRegister(postgres.NewPoolCollector(pg), cache.NewPoolCollector(redis))
+
+    auditLog := auditlog.NewWriter(postgresrepositories.NewPostgresAuditRepository(pg), conf.Audit)
+
+    router := apphttp.NewRouter(conf, logger, pg, redis, otpSender, keys, limiter, auditLog)
+
+    purgeCtx, stopPurge := context.WithCancel(context.Background())
+    defer stopPurge()
+    if conf.AccountPurgeInterval > 0 {
+        users := userusecase.New(postgresrepositories.NewPostgresUserRepository(pg), conf.AccountDeletionGrace, conf.CursorSecret, conf.AuditPhoneKey, auditLog)
+        go purgeDeletedUsers(purgeCtx, users, conf.AccountPurgeInterval)
+    }
+    if conf.Webhook.PollInterval > 0 {
+        webhooks := webhookusecase.New(postgresrepositories.NewPostgresWebhookRepository(pg),
+            webhookinfra.NewHTTPSender(conf.Webhook.Timeout, webhookdomain.NewAddressPolicy(conf.Webhook.AllowedNetworks)), conf.Webhook)
+        go dispatchWebhooks(purgeCtx, webhooks, conf.Webhook.PollInterval)
+    }
 
     server := &http.Server{
         Addr:              ":" + conf.HTTPPort,
@@ -51,9 +110,9 @@ func main() {
     }
 
     go func() {
-        log.Printf("server listening on :%s", conf.HTTPPort)
+        slog.Info("server listening", "port", conf.HTTPPort)
         if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-            log.Fatalf("listen: %v", err)
+            fatal("listen failed", err)
         }
     }()
 
@@ -63,8 +122,71 @@ func main() {
     ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
     defer cancel()
     if err := server.Shutdown(ctx); err != nil {
-        log.Printf("server shutdown error: %v", err)
+        slog.Error("server shutdown failed", "err", err)
+    }
+    if err := auditLog.Close(ctx); err != nil {
+        slog.Error("audit log shutdown failed", "err", err)
+    }
+}
+
+// purgeDeletedUsers hard-deletes accounts whose deletion grace period is
+// over. Every instance runs it; the DELETE is idempotent.
+func purgeDeletedUsers(ctx context.Context, users *userusecase.UserUsecase, every time.Duration) {
+    ticker := time.NewTicker(every)
+    defer ticker.Stop()
+    for {
+        n, err := users.PurgeDeleted(ctx)
+        if err != nil && ctx.Err() == nil {
+            slog.Error("purging deleted users failed", "err", err)
+        } else if n > 0 {
+            slog.Info("purged deleted users", "count", n)
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
+    }
+}
+
+// dispatchWebhooks delivers outbox events to webhook endpoints. Every instance
+// runs it; deliveries are claimed with SKIP LOCKED so each is sent by one.
+// After a round that delivered anything the next one starts straight away.
+func dispatchWebhooks(ctx context.Context, webhooks *webhookusecase.WebhookUsecase, every time.Duration) {
+    ticker := time.NewTicker(every)
+    defer ticker.Stop()
+    for {
+        n, err := webhooks.DispatchOnce(ctx)
+        if err != nil && ctx.Err() == nil {
+            slog.Error("dispatching webhooks failed", "err", err)
+        }
+        if err == nil && n > 0 {
+            continue
+        }
+        select {
+        case <-ctx.Done():
+            return
+        case <-ticker.C:
+        }
     }
 }
 
+// reloadKeysOnHangup re-reads the JWT key files on SIGHUP so a new signing key
+// can be rolled out without a restart.
+func reloadKeysOnHangup(keys *jwtkeys.KeySet) {
+    hup := make(chan os.Signal, 1)
+    signal.Notify(hup, syscall.SIGHUP)
+    for range hup {
+        if err := keys.Reload(); err != nil {
+            slog.Error("jwt key reload failed, keeping current keys", "err", err)
+            continue
+        }
+        slog.Info("jwt keys reloaded")
+    }
+}
 
+// fatal logs err and exits; slog has no Fatal.
+func fatal(msg string, err error) {
+    slog.Error(msg, "err", err)
+    os.Exit(1)
+}
@@ -0,0 +1,63 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadRejectsNonPositiveOTPSettings(t *testing.T) {
+	t.Setenv("OTP_TTL", "-1m")
+	t.Setenv("OTP_MAX_SENDS", "0")
+	t.Setenv("OTP_MAX_VERIFY_ATTEMPTS", "-3")
+	t.Setenv("OTP_SEND_WINDOW", "0s")
+	t.Setenv("OTP_IP_MAX_SENDS", "-1")
+	t.Setenv("OTP_DEVICE_WINDOW", "0s")
+	t.Setenv("OTP_COUNTRY_MAX_SENDS", "0")
+
+	cfg, warnings := Load()
+
+	p := cfg.OTPPolicy
+	if p.TTL != 2*time.Minute || p.MaxSends != 3 || p.MaxVerifyAttempts != 5 || p.SendWindow != 10*time.Minute {
+		t.Errorf("OTPPolicy = %+v, want defaults for non-positive values", p)
+	}
+	q := cfg.OTPQuota
+	if q.IP.Max != 10 {
+		t.Errorf("OTPQuota.IP.Max = %d, want 10", q.IP.Max)
+	}
+	if q.Device.Window != time.Hour {
+		t.Errorf("OTPQuota.Device.Window = %s, want 1h", q.Device.Window)
+	}
+	if q.Country.Max != 0 {
+		t.Errorf("OTPQuota.Country.Max = %d, want 0 to stay disabled", q.Country.Max)
+	}
+
+	want := map[string]bool{
+		`env OTP_TTL=-1m0s is not positive, using 2m0s`:           true,
+		`env OTP_MAX_SENDS=0 is not positive, using 3`:            true,
+		`env OTP_MAX_VERIFY_ATTEMPTS=-3 is not positive, using 5`: true,
+		`env OTP_SEND_WINDOW=0s is not positive, using 10m0s`:     true,
+		`env OTP_IP_MAX_SENDS=-1 is negative, using 10`:           true,
+		`env OTP_DEVICE_WINDOW=0s is not positive, using 1h0m0s`:  true,
+	}
+	for _, w := range warnings {
+		delete(want, w)
+	}
+	for w := range want {
+		t.Errorf("missing warning %q in %q", w, warnings)
+	}
+}
+
+func TestLoadRecordsDevelopmentSecrets(t *testing.T) {
+	t.Setenv("OTP_PEPPER", "")
+	t.Setenv("CURSOR_SECRET", "set-in-production")
+	t.Setenv("AUDIT_PHONE_KEY", "")
+
+	cfg, _ := Load()
+
+	if len(cfg.DevSecrets) != 2 || cfg.DevSecrets[0] != "OTP_PEPPER" || cfg.DevSecrets[1] != "AUDIT_PHONE_KEY" {
+		t.Errorf("DevSecrets = %v, want [OTP_PEPPER AUDIT_PHONE_KEY]", cfg.DevSecrets)
+	}
+	if cfg.CursorSecret != "set-in-production" {
+		t.Errorf("CursorSecret = %q", cfg.CursorSecret)
+	}
+}
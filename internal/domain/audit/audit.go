@@ -0,0 +1,140 @@
+package audit
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"regexp"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+)
+
+type Action string
+
+const (
+	ActionOTPRequested Action = "otp.requested"
+	// ActionLogin is a code verification; failures are wrong or expired
+	// codes and logins refused because of a restriction.
+	ActionLogin         Action = "auth.login"
+	ActionUserCreated   Action = "user.created"
+	ActionUserRestored  Action = "user.restored"
+	ActionUserViewed    Action = "admin.user_viewed"
+	ActionUsersListed   Action = "admin.users_listed"
+	ActionUsersExported Action = "admin.users_exported"
+	ActionUserSuspended Action = "admin.user_suspended"
+	ActionUserBanned    Action = "admin.user_banned"
+	ActionUserUnbanned  Action = "admin.user_unbanned"
+	ActionUserLoggedOut Action = "admin.user_logged_out"
+)
+
+type Outcome string
+
+const (
+	OutcomeSuccess Outcome = "success"
+	OutcomeFailure Outcome = "failure"
+)
+
+// Event is one row of the audit log. Target is a user ID, or PhoneTarget of
+// the phone number when no user is known yet. Details holds action-specific
+// values such as the error code of a failure.
+type Event struct {
+	ID         int64             `json:"id"`
+	OccurredAt time.Time         `json:"occurred_at"`
+	ActorID    string            `json:"actor_id,omitempty"`
+	Action     Action            `json:"action"`
+	Target     string            `json:"target,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+	UserAgent  string            `json:"user_agent,omitempty"`
+	RequestID  string            `json:"request_id,omitempty"`
+	Outcome    Outcome           `json:"outcome"`
+	Details    map[string]string `json:"details,omitempty"`
+}
+
+// PhoneTarget stands in for a phone number in the audit log. The log is
+// append-only and outlives purged users, so it keeps a keyed hash that can
+// still be searched for rather than the number itself.
+func PhoneTarget(key []byte, phone string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(phone))
+	return "phone:" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// Recorder accepts events for the audit log. Record must not block or fail
+// the caller; events that can't be stored are dropped and counted.
+type Recorder interface {
+	Record(ctx context.Context, e Event)
+}
+
+// Filter selects events for Repository.List, newest first. Zero values do not
+// filter; BeforeID pages backwards from an earlier result.
+type Filter struct {
+	ActorID  string
+	Action   Action
+	Target   string
+	Outcome  Outcome
+	From     *time.Time
+	To       *time.Time
+	BeforeID int64
+	Limit    int
+}
+
+// Repository only appends; the table rejects updates and deletes.
+type Repository interface {
+	Insert(ctx context.Context, events []Event) error
+	List(ctx context.Context, f Filter) ([]Event, error)
+}
+
+// Finish sets the outcome of e from the error of the action it describes,
+// adding the error code to Details on failure.
+func Finish(e Event, err error) Event {
+	e.Outcome = OutcomeSuccess
+	if err != nil {
+		e.Outcome = OutcomeFailure
+		if e.Details == nil {
+			e.Details = map[string]string{}
+		}
+		e.Details["error"] = ErrorCode(err)
+	}
+	return e
+}
+
+var errorCodePattern = regexp.MustCompile(`^[a-z]+(_[a-z]+)*$`)
+
+// ErrorCode is the first snake_case sentinel in err's chain, or
+// "internal_error". The log is append-only, so provider responses, URLs and
+// other free text in wrapped errors must never reach it.
+func ErrorCode(err error) string {
+	for ; err != nil; err = errors.Unwrap(err) {
+		if msg := err.Error(); errorCodePattern.MatchString(msg) {
+			return msg
+		}
+	}
+	return "internal_error"
+}
+
+// FromContext fills in the time, the acting user and the client details of
+// the request in ctx where e leaves them empty.
+func FromContext(ctx context.Context, e Event) Event {
+	if e.OccurredAt.IsZero() {
+		e.OccurredAt = time.Now()
+	}
+	if e.ActorID == "" {
+		if p, ok := authdomain.PrincipalFromContext(ctx); ok {
+			e.ActorID = p.UserID
+		}
+	}
+	client := authdomain.ClientInfoFromContext(ctx)
+	if e.IP == "" {
+		e.IP = client.IP
+	}
+	if e.UserAgent == "" {
+		e.UserAgent = client.UserAgent
+	}
+	if e.RequestID == "" {
+		e.RequestID = client.RequestID
+	}
+	return e
+}
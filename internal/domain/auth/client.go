@@ -0,0 +1,24 @@
+package auth
+
+import "context"
+
+// ClientInfo describes where a request came from. DeviceID is the
+// client-supplied device fingerprint, if any, and RequestID identifies the
+// request in the audit log.
+type ClientInfo struct {
+	IP        string
+	UserAgent string
+	DeviceID  string
+	RequestID string
+}
+
+type clientInfoKey struct{}
+
+func WithClientInfo(ctx context.Context, c ClientInfo) context.Context {
+	return context.WithValue(ctx, clientInfoKey{}, c)
+}
+
+func ClientInfoFromContext(ctx context.Context) ClientInfo {
+	c, _ := ctx.Value(clientInfoKey{}).(ClientInfo)
+	return c
+}
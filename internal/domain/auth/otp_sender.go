@@ -0,0 +1,39 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+type OTPChannel string
+
+const (
+	OTPChannelSMS      OTPChannel = "sms"
+	OTPChannelWhatsApp OTPChannel = "whatsapp"
+	OTPChannelVoice    OTPChannel = "voice"
+)
+
+var ErrUnsupportedChannel = errors.New("unsupported otp channel")
+
+func ParseOTPChannel(s string) (OTPChannel, bool) {
+	switch OTPChannel(s) {
+	case "":
+		return OTPChannelSMS, true
+	case OTPChannelSMS, OTPChannelWhatsApp, OTPChannelVoice:
+		return OTPChannel(s), true
+	}
+	return "", false
+}
+
+type OTPMessage struct {
+	Phone   string
+	Code    string
+	Channel OTPChannel
+	TTL     time.Duration
+}
+
+// OTPSender delivers a one-time code to the user over msg.Channel.
+type OTPSender interface {
+	Send(ctx context.Context, msg OTPMessage) error
+}
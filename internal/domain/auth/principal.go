@@ -0,0 +1,25 @@
+package auth
+
+import "context"
+
+// Principal is the authenticated caller, as described by a verified access
+// token.
+type Principal struct {
+	UserID  string
+	Phone   string
+	Roles   []string
+	TokenID string
+	// SessionID is empty for tokens issued before sessions existed.
+	SessionID string
+}
+
+type principalKey struct{}
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
@@ -0,0 +1,80 @@
+package auth
+
+const (
+	RoleUser    = "user"
+	RoleSupport = "support"
+	RoleAdmin   = "admin"
+)
+
+type Permission string
+
+const (
+	PermUsersReadSelf Permission = "users:read_self"
+	PermUsersRead     Permission = "users:read"
+	PermUsersList     Permission = "users:list"
+	// PermUsersModerate covers suspending users and forcing them to log out.
+	PermUsersModerate Permission = "users:moderate"
+	// PermUsersBan covers banning users and lifting bans and suspensions.
+	PermUsersBan Permission = "users:ban"
+	// PermUsersExport covers bulk exports of the user table.
+	PermUsersExport Permission = "users:export"
+	// PermAuditRead covers reading the audit log.
+	PermAuditRead Permission = "audit:read"
+	// PermWebhooksManage covers registering webhook endpoints and inspecting
+	// their deliveries.
+	PermWebhooksManage Permission = "webhooks:manage"
+)
+
+var rolePermissions = map[string][]Permission{
+	RoleUser:    {PermUsersReadSelf},
+	RoleSupport: {PermUsersReadSelf, PermUsersRead, PermUsersModerate},
+	RoleAdmin:   {PermUsersReadSelf, PermUsersRead, PermUsersList, PermUsersModerate, PermUsersBan, PermUsersExport, PermAuditRead, PermWebhooksManage},
+}
+
+// roleRank orders the roles for moderation: users can only be moderated by
+// someone whose highest role ranks above theirs.
+var roleRank = map[string]int{
+	RoleUser:    0,
+	RoleSupport: 1,
+	RoleAdmin:   2,
+}
+
+// Outranks reports whether the highest of roles ranks above the highest of
+// other.
+func Outranks(roles, other []string) bool {
+	return highestRank(roles) > highestRank(other)
+}
+
+func highestRank(roles []string) int {
+	rank := 0
+	for _, r := range roles {
+		rank = max(rank, roleRank[r])
+	}
+	return rank
+}
+
+// IsRole reports whether role is one of the known roles.
+func IsRole(role string) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+func (p *Principal) HasRole(role string) bool {
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Principal) HasPermission(perm Permission) bool {
+	for _, r := range p.Roles {
+		for _, granted := range rolePermissions[r] {
+			if granted == perm {
+				return true
+			}
+		}
+	}
+	return false
+}
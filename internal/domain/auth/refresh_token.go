@@ -0,0 +1,28 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// RefreshToken is stored by hash only. All tokens rotated from the same login
+// share a FamilyID so that a replayed token can take the whole chain down.
+type RefreshToken struct {
+	ID        string
+	UserID    string
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+}
+
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *RefreshToken) error
+	GetByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	// MarkUsed reports false if the token was already used or revoked.
+	MarkUsed(ctx context.Context, id string) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID string) error
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+// Session is one login. Its ID is the FamilyID of the refresh tokens rotated
+// from that login and the sid claim of the access tokens issued with them.
+// LastSeenAt moves on every refresh.
+type Session struct {
+	ID         string     `json:"id"`
+	UserID     string     `json:"-"`
+	DeviceName string     `json:"device_name,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+	// Current marks the session of the caller's own access token.
+	Current bool `json:"current"`
+}
+
+type SessionRepository interface {
+	// Create stores s, fills in its ID and timestamps and writes a
+	// user.logged_in event.
+	Create(ctx context.Context, s *Session) error
+	// Touch records a refresh of the session from ip and userAgent.
+	Touch(ctx context.Context, id, ip, userAgent string, expiresAt time.Time) error
+	// ListActive returns the user's sessions that are neither revoked nor
+	// expired, most recently seen first.
+	ListActive(ctx context.Context, userID string) ([]Session, error)
+	// Revoke returns pgx.ErrNoRows if the user has no such active session.
+	Revoke(ctx context.Context, userID, id string) error
+	RevokeAllForUser(ctx context.Context, userID string) error
+}
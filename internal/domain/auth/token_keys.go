@@ -0,0 +1,10 @@
+package auth
+
+import "github.com/golang-jwt/jwt/v5"
+
+// TokenKeys signs access tokens with the current key and resolves the key
+// that verifies a parsed token, so rotation stays out of the usecase.
+type TokenKeys interface {
+	Sign(claims jwt.Claims) (string, error)
+	Keyfunc(token *jwt.Token) (any, error)
+}
@@ -12,6 +12,12 @@ type CacheStore interface {
 	Set(ctx context.Context, key string, value string, ttl time.Duration) error
 	Get(ctx context.Context, key string) (string, error)
 	Delete(ctx context.Context, key string) error
-	Increment(ctx context.Context, key string) (int64, error)
+	// Increment atomically increments key and gives it ttl if it has no
+	// expiry yet, so a counter can never be left without one.
+	Increment(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// IncrementIfExists atomically increments counterKey, giving it key's
+	// expiry, as long as key exists. Otherwise it returns ErrNotFound and
+	// leaves counterKey alone.
+	IncrementIfExists(ctx context.Context, key, counterKey string) (int64, error)
 	SetExpiry(ctx context.Context, key string, ttl time.Duration) error
 }
\ No newline at end of file
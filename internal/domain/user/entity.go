@@ -6,4 +6,79 @@ type User struct {
 	ID        string    `json:"id"`
 	Phone     string    `json:"phone"`
 	CreatedAt time.Time `json:"created_at"`
-}
\ No newline at end of file
+	UpdatedAt time.Time `json:"updated_at"`
+	Roles     []string  `json:"roles,omitempty"`
+	Profile
+	Restriction
+	// Version is bumped on every update and backs the ETag.
+	Version int64 `json:"-"`
+}
+
+// Profile holds the user-editable fields. Empty means unset.
+type Profile struct {
+	DisplayName string `json:"display_name,omitempty"`
+	Email       string `json:"email,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+	Timezone    string `json:"timezone,omitempty"`
+}
+
+type Status string
+
+const (
+	StatusActive    Status = "active"
+	StatusSuspended Status = "suspended"
+	StatusBanned    Status = "banned"
+)
+
+// Restriction is set by moderators. Until is nil for a restriction that
+// lasts until it is lifted.
+type Restriction struct {
+	Status       Status     `json:"status"`
+	StatusReason string     `json:"status_reason,omitempty"`
+	StatusUntil  *time.Time `json:"status_until,omitempty"`
+}
+
+// StatusAt returns the status in effect at now, treating expired
+// restrictions as lifted.
+func (r Restriction) StatusAt(now time.Time) Status {
+	if r.Status == "" || (r.StatusUntil != nil && !now.Before(*r.StatusUntil)) {
+		return StatusActive
+	}
+	return r.Status
+}
+
+// StatusChange is a moderator's decision about a user.
+type StatusChange struct {
+	Restriction
+	ChangedBy string
+}
+
+// PhoneChange is the audit record of a user moving to a new number.
+type PhoneChange struct {
+	UserID    string    `json:"-"`
+	OldPhone  string    `json:"old_phone"`
+	NewPhone  string    `json:"new_phone"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// Export is everything stored about a user, as handed out on a data export
+// request. Sessions are listed with their device details but no tokens.
+type Export struct {
+	ExportedAt   time.Time       `json:"exported_at"`
+	User         User            `json:"user"`
+	Sessions     []SessionRecord `json:"sessions"`
+	PhoneChanges []PhoneChange   `json:"phone_changes"`
+}
+
+type SessionRecord struct {
+	DeviceName string     `json:"device_name,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
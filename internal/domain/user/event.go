@@ -0,0 +1,25 @@
+package user
+
+import "time"
+
+type EventType string
+
+const (
+	EventCreated      EventType = "user.created"
+	EventLoggedIn     EventType = "user.logged_in"
+	EventPhoneChanged EventType = "user.phone_changed"
+	EventDeleted      EventType = "user.deleted"
+)
+
+// EventTypes lists every event a webhook can subscribe to.
+var EventTypes = []EventType{EventCreated, EventLoggedIn, EventPhoneChanged, EventDeleted}
+
+// Event is a change to a user. Repositories write it to the outbox in the
+// same transaction as the change itself, so it is published if and only if
+// the change is committed.
+type Event struct {
+	ID         string         `json:"id"`
+	Type       EventType      `json:"type"`
+	OccurredAt time.Time      `json:"occurred_at"`
+	Data       map[string]any `json:"data"`
+}
@@ -0,0 +1,41 @@
+package user
+
+import "time"
+
+type SortField string
+
+const (
+	SortCreatedAt   SortField = "created_at"
+	SortUpdatedAt   SortField = "updated_at"
+	SortPhone       SortField = "phone"
+	SortDisplayName SortField = "display_name"
+)
+
+// ListFilter selects and orders users for Repository.List. Zero values do not
+// filter. Results are ordered by Sort and then by ID, so pages are stable.
+type ListFilter struct {
+	Phone         string // exact E.164 match
+	PhonePrefix   string // E.164 prefix, e.g. "+98912"
+	PhoneContains string // digits anywhere in the number
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	// Status matches the status in effect, so an expired suspension counts
+	// as active.
+	Status Status
+	Role   string
+	Sort   SortField
+	Desc   bool
+	// After and Before restrict the result to rows that sort strictly after
+	// or before a keyset. Rows are returned in sort order either way.
+	After  *Keyset
+	Before *Keyset
+	Limit  int
+	Offset int
+}
+
+// Keyset is a position in the listing order: the sort column of a row as text
+// (RFC 3339 for timestamps) and its ID as the tiebreaker.
+type Keyset struct {
+	Value string
+	ID    string
+}
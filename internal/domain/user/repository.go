@@ -1,10 +1,44 @@
 package user
 
-import "context"
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrVersionConflict is returned by UpdateProfile when the row was changed
+	// since the given version was read.
+	ErrVersionConflict = errors.New("version_conflict")
+	// ErrPhoneTaken is returned by ChangePhone when another user has the number.
+	ErrPhoneTaken = errors.New("phone taken")
+)
 
 type Repository interface {
 	GetByPhone(ctx context.Context, phone string) (*User, error)
+	// Create, ChangePhone and SoftDelete also write the matching Event.
 	Create(ctx context.Context, phone string) (*User, error)
 	GetByID(ctx context.Context, id string) (*User, error)
-	List(ctx context.Context, phone string, limit, offset int) ([]User, int, error)
+	List(ctx context.Context, f ListFilter) ([]User, error)
+	// Count returns how many users match f, ignoring its keysets and paging.
+	// Unless exact is set it may return a planner estimate instead.
+	Count(ctx context.Context, f ListFilter, exact bool) (int, error)
+	// Stream calls fn for every user matching f, in f's order, ignoring its
+	// keysets and paging. It stops at the first error fn returns.
+	Stream(ctx context.Context, f ListFilter, fn func(User) error) error
+	UpdateProfile(ctx context.Context, id string, p Profile, version int64) (*User, error)
+	// ChangePhone moves the user from c.OldPhone to c.NewPhone and records c
+	// in the same transaction. It returns pgx.ErrNoRows if the user no longer
+	// has c.OldPhone.
+	ChangePhone(ctx context.Context, c PhoneChange) (*User, error)
+
+	// SoftDelete hides the user from every other method until Restore or
+	// PurgeDeleted, and returns when it was deleted.
+	SoftDelete(ctx context.Context, id string) (time.Time, error)
+	// Restore undeletes the most recently deleted user with the phone.
+	Restore(ctx context.Context, phone string) (*User, error)
+	// PurgeDeleted removes users deleted before the cutoff for good.
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
+	Export(ctx context.Context, id string) (*Export, error)
+	SetStatus(ctx context.Context, id string, c StatusChange) (*User, error)
 }
\ No newline at end of file
@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"errors"
+	"log/slog"
+	"net/netip"
+)
+
+var ErrForbiddenAddress = errors.New("forbidden_address")
+
+// blockedPrefixes are the non-public ranges netip has no predicate for.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 can reach any IPv4 address
+}
+
+// AddressPolicy decides which addresses webhooks may be sent to. Loopback,
+// private, link-local, multicast and other non-public addresses are refused
+// unless they fall in one of the allowed networks.
+type AddressPolicy struct {
+	allow []netip.Prefix
+}
+
+// NewAddressPolicy allows the given CIDRs or single addresses on top of the
+// public internet. Invalid entries are logged and ignored.
+func NewAddressPolicy(allow []string) AddressPolicy {
+	var p AddressPolicy
+	for _, item := range allow {
+		if prefix, err := netip.ParsePrefix(item); err == nil {
+			p.allow = append(p.allow, prefix.Masked())
+			continue
+		}
+		if a, err := netip.ParseAddr(item); err == nil {
+			p.allow = append(p.allow, netip.PrefixFrom(a.Unmap(), a.Unmap().BitLen()))
+			continue
+		}
+		slog.Warn("ignoring invalid webhook network", "network", item)
+	}
+	return p
+}
+
+func (p AddressPolicy) Permits(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range p.allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
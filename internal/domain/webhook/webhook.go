@@ -0,0 +1,88 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+)
+
+// Endpoint receives the user events it subscribed to, or all of them when
+// Events is empty. Secret signs every request and is only shown on creation.
+type Endpoint struct {
+	ID        string                 `json:"id"`
+	URL       string                 `json:"url"`
+	Secret    string                 `json:"secret,omitempty"`
+	Events    []userdomain.EventType `json:"events"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type DeliveryStatus string
+
+const (
+	StatusPending   DeliveryStatus = "pending"
+	StatusDelivered DeliveryStatus = "delivered"
+	// StatusDead marks deliveries that ran out of attempts. They stay until
+	// redelivered by hand.
+	StatusDead DeliveryStatus = "dead"
+)
+
+// Delivery is one event on its way to one endpoint. URL and Secret are only
+// filled in by ClaimDue.
+type Delivery struct {
+	ID            int64            `json:"id"`
+	EndpointID    string           `json:"endpoint_id"`
+	URL           string           `json:"-"`
+	Secret        string           `json:"-"`
+	Event         userdomain.Event `json:"event"`
+	Status        DeliveryStatus   `json:"status"`
+	Attempts      int              `json:"attempts"`
+	NextAttemptAt time.Time        `json:"next_attempt_at"`
+	LastStatus    int              `json:"last_status,omitempty"`
+	LastError     string           `json:"last_error,omitempty"`
+	CreatedAt     time.Time        `json:"created_at"`
+	DeliveredAt   *time.Time       `json:"delivered_at,omitempty"`
+}
+
+type Repository interface {
+	CreateEndpoint(ctx context.Context, e *Endpoint) error
+	// ListEndpoints leaves out the secrets.
+	ListEndpoints(ctx context.Context) ([]Endpoint, error)
+	// DeleteEndpoint drops the endpoint and its deliveries. It returns
+	// pgx.ErrNoRows if there is no such endpoint.
+	DeleteEndpoint(ctx context.Context, id string) error
+
+	// FanOut takes up to limit outbox events that have not been dispatched
+	// yet and queues a pending delivery of each to every endpoint subscribed
+	// to it. It returns how many events it took.
+	FanOut(ctx context.Context, limit int) (int, error)
+	// ClaimDue returns up to limit pending deliveries whose next attempt is
+	// due and pushes that attempt back by lease, so other dispatchers skip
+	// them while they are being sent.
+	ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error)
+	MarkDelivered(ctx context.Context, id int64, status int) error
+	// MarkFailed records a failed attempt and schedules the next one at
+	// retryAt, or moves the delivery to StatusDead if retryAt is nil.
+	MarkFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error
+
+	// ListDeliveries returns the endpoint's deliveries, newest first,
+	// optionally only those with the given status.
+	ListDeliveries(ctx context.Context, endpointID string, status DeliveryStatus, limit int) ([]Delivery, error)
+	// Redeliver moves a dead delivery back to pending with its attempts
+	// reset. It returns pgx.ErrNoRows if the endpoint has no such dead
+	// delivery.
+	Redeliver(ctx context.Context, endpointID string, id int64) error
+}
+
+// Request is a signed webhook call.
+type Request struct {
+	URL     string
+	Body    []byte
+	Headers map[string]string
+}
+
+// Sender makes one webhook call and returns the response status. A non-nil
+// error means no response was received.
+type Sender interface {
+	Send(ctx context.Context, req Request) (int, error)
+}
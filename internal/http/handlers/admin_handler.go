@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+	authuc "dekamond/internal/usecase/auth"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// AdminHandler serves the moderation endpoints under /api/admin/users.
+type AdminHandler struct {
+	authUsecase *authuc.AuthUsecase
+}
+
+func NewAdminHandler(authUsecase *authuc.AuthUsecase) *AdminHandler {
+	return &AdminHandler{authUsecase: authUsecase}
+}
+
+type restrictRequest struct {
+	Reason string     `json:"reason"`
+	Until  *time.Time `json:"until"`
+}
+
+func (h *AdminHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	actor, body, ok := h.restrictRequest(w, r)
+	if !ok {
+		return
+	}
+	if body.Until == nil {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: authuc.ErrInvalidUntil.Error()})
+		return
+	}
+	user, err := h.authUsecase.Suspend(r.Context(), actor.UserID, chi.URLParam(r, "id"), body.Reason, *body.Until)
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "user_suspended", Data: user})
+}
+
+func (h *AdminHandler) Ban(w http.ResponseWriter, r *http.Request) {
+	actor, body, ok := h.restrictRequest(w, r)
+	if !ok {
+		return
+	}
+	user, err := h.authUsecase.Ban(r.Context(), actor.UserID, chi.URLParam(r, "id"), body.Reason, body.Until)
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "user_banned", Data: user})
+}
+
+func (h *AdminHandler) Unban(w http.ResponseWriter, r *http.Request) {
+	actor, ok := authdomain.PrincipalFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return
+	}
+	user, err := h.authUsecase.Unban(r.Context(), actor.UserID, chi.URLParam(r, "id"))
+	if err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "user_unbanned", Data: user})
+}
+
+// ForceLogout revokes every token of the user.
+func (h *AdminHandler) ForceLogout(w http.ResponseWriter, r *http.Request) {
+	actor, ok := authdomain.PrincipalFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return
+	}
+	if err := h.authUsecase.ForceLogout(r.Context(), actor.UserID, chi.URLParam(r, "id")); err != nil {
+		writeModerationError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "logged_out"})
+}
+
+func (h *AdminHandler) restrictRequest(w http.ResponseWriter, r *http.Request) (*authdomain.Principal, restrictRequest, bool) {
+	var body restrictRequest
+	actor, ok := authdomain.PrincipalFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return nil, body, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_payload"})
+		return nil, body, false
+	}
+	return actor, body, true
+}
+
+func writeModerationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, authuc.ErrUserNotFound):
+		WriteJSON(w, http.StatusNotFound, ApiResponse{Error: "not_found"})
+	case errors.Is(err, authuc.ErrReasonRequired), errors.Is(err, authuc.ErrReasonTooLong),
+		errors.Is(err, authuc.ErrInvalidUntil), errors.Is(err, authuc.ErrCannotModerateSelf):
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: err.Error()})
+	case errors.Is(err, authuc.ErrCannotModerateRole):
+		WriteJSON(w, http.StatusForbidden, ApiResponse{Error: err.Error()})
+	default:
+		WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+	}
+}
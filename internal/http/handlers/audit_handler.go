@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dekamond/internal/logging"
+	audituc "dekamond/internal/usecase/audit"
+)
+
+// AuditHandler serves the audit log under /api/admin/audit-events.
+type AuditHandler struct {
+	auditUsecase *audituc.AuditUsecase
+}
+
+func NewAuditHandler(auditUsecase *audituc.AuditUsecase) *AuditHandler {
+	return &AuditHandler{auditUsecase: auditUsecase}
+}
+
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := audituc.Query{
+		ActorID: query.Get("actor"),
+		Action:  query.Get("action"),
+		Target:  query.Get("target"),
+		Outcome: query.Get("outcome"),
+		Limit:   atoiDefault(query.Get("limit"), 0),
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"from", &q.From}, {"to", &q.To}} {
+		raw := query.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		t, err := parseTimeParam(raw)
+		if err != nil {
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_" + p.name})
+			return
+		}
+		*p.dst = &t
+	}
+	if raw := query.Get("before"); raw != "" {
+		before, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil || before < 1 {
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_before"})
+			return
+		}
+		q.Before = before
+	}
+
+	page, err := h.auditUsecase.List(r.Context(), q)
+	if err != nil {
+		switch {
+		case errors.Is(err, audituc.ErrInvalidActor), errors.Is(err, audituc.ErrInvalidOutcome), errors.Is(err, audituc.ErrInvalidRange):
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: err.Error()})
+		default:
+			logging.FromContext(r.Context()).Error("listing audit events failed", "err", err)
+			WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		}
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Data: page})
+}
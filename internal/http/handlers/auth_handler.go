@@ -2,49 +2,306 @@ package handlers
 
 import (
 	"encoding/json"
-	"log"
+	"errors"
 	"net/http"
-	"regexp"
 	"strings"
 
+	authdomain "dekamond/internal/domain/auth"
+	"dekamond/internal/phone"
 	authuc "dekamond/internal/usecase/auth"
+
+	"github.com/go-chi/chi/v5"
 )
 
 type AuthHandler struct {
-	authUsecase *authuc.AuthUsecase
+	authUsecase   *authuc.AuthUsecase
+	defaultRegion string
 }
 
-func NewAuthHandler(authUsecase *authuc.AuthUsecase) *AuthHandler {
-	return &AuthHandler{authUsecase: authUsecase}
+// NewAuthHandler takes the region used to read phone numbers given without
+// a calling code.
+func NewAuthHandler(authUsecase *authuc.AuthUsecase, defaultRegion string) *AuthHandler {
+	return &AuthHandler{authUsecase: authUsecase, defaultRegion: defaultRegion}
 }
 
 func (h *AuthHandler) RequestOTP(w http.ResponseWriter, req *http.Request) {
-	var body struct{ Phone string `json:"phone"` }
-	if !h.decodeAndValidate(w, req, &body) || !isValidE164(body.Phone) {
+	var body struct {
+		Phone   string `json:"phone"`
+		Channel string `json:"channel"`
+	}
+	if !h.decodeAndValidate(w, req, &body) {
 		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_phone"})
 		return
 	}
-	code, err := h.authUsecase.RequestOTP(req.Context(), body.Phone)
+	number, err := phone.Normalize(body.Phone, h.defaultRegion)
 	if err != nil {
-		WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: err.Error()})
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: err.Error()})
+		return
+	}
+	channel, ok := authdomain.ParseOTPChannel(body.Channel)
+	if !ok {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: authuc.ErrUnsupportedChannel.Error()})
 		return
 	}
-	log.Printf("OTP for %s: %s", body.Phone, code)
-	WriteJSON(w, http.StatusOK, ApiResponse{Message: "otp_sent"})
+	issued, err := h.authUsecase.RequestOTP(req.Context(), number, channel)
+	if err != nil {
+		var retry *authuc.RetryError
+		switch {
+		case errors.As(err, &retry):
+			setRetryAfter(w, retry.RetryAfter)
+			WriteJSON(w, http.StatusTooManyRequests, ApiResponse{Error: retry.Error()})
+		case errors.Is(err, authuc.ErrUnsupportedChannel):
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: authuc.ErrUnsupportedChannel.Error()})
+		case errors.Is(err, authuc.ErrCountryNotAllowed):
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: authuc.ErrCountryNotAllowed.Error()})
+		case errors.Is(err, authuc.ErrOTPDeliveryFailed):
+			WriteJSON(w, http.StatusBadGateway, ApiResponse{Error: authuc.ErrOTPDeliveryFailed.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		}
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "otp_sent", Data: map[string]any{
+		"expires_in":   int(issued.ExpiresIn.Seconds()),
+		"resend_after": int(issued.ResendAfter.Seconds()),
+	}})
 }
 
 func (h *AuthHandler) VerifyOTP(w http.ResponseWriter, req *http.Request) {
-	var body struct{ Phone, Code string }
-	if !h.decodeAndValidate(w, req, &body) || !isValidE164(body.Phone) || strings.TrimSpace(body.Code) == "" {
+	var body struct {
+		Phone      string `json:"phone"`
+		Code       string `json:"code"`
+		DeviceName string `json:"device_name"`
+	}
+	if !h.decodeAndValidate(w, req, &body) || strings.TrimSpace(body.Code) == "" {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_payload"})
+		return
+	}
+	number, err := phone.Normalize(body.Phone, h.defaultRegion)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_payload"})
+		return
+	}
+	tokens, user, err := h.authUsecase.VerifyOTPAndIssueToken(req.Context(), number, body.Code, body.DeviceName)
+	if err != nil {
+		var retry *authuc.RetryError
+		switch {
+		case errors.As(err, &retry):
+			setRetryAfter(w, retry.RetryAfter)
+			WriteJSON(w, http.StatusTooManyRequests, ApiResponse{Error: retry.Error()})
+		case errors.Is(err, authuc.ErrInvalidOTP):
+			WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: authuc.ErrInvalidOTP.Error()})
+		case errors.Is(err, authuc.ErrAccountSuspended), errors.Is(err, authuc.ErrAccountBanned):
+			WriteJSON(w, http.StatusForbidden, ApiResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		}
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Data: map[string]any{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
+		"user":          user,
+	}})
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if !h.decodeAndValidate(w, req, &body) || strings.TrimSpace(body.RefreshToken) == "" {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_payload"})
+		return
+	}
+	tokens, err := h.authUsecase.Refresh(req.Context(), body.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, authuc.ErrInvalidRefreshToken), errors.Is(err, authuc.ErrRefreshTokenReused):
+			WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: err.Error()})
+		case errors.Is(err, authuc.ErrAccountSuspended), errors.Is(err, authuc.ErrAccountBanned):
+			WriteJSON(w, http.StatusForbidden, ApiResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		}
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Data: tokens})
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if req.ContentLength != 0 && !h.decodeAndValidate(w, req, &body) {
 		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_payload"})
 		return
 	}
-	token, user, err := h.authUsecase.VerifyOTPAndIssueToken(req.Context(), body.Phone, body.Code)
+	accessToken, _ := BearerToken(req)
+	if err := h.authUsecase.Logout(req.Context(), accessToken, body.RefreshToken); err != nil {
+		writeLogoutError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "logged_out"})
+}
+
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, req *http.Request) {
+	accessToken, _ := BearerToken(req)
+	if err := h.authUsecase.LogoutAll(req.Context(), accessToken); err != nil {
+		writeLogoutError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "logged_out"})
+}
+
+// ListSessions shows where the caller is logged in.
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, req *http.Request) {
+	principal, ok := authdomain.PrincipalFromContext(req.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return
+	}
+	sessions, err := h.authUsecase.ListSessions(req.Context(), principal.UserID, principal.SessionID)
+	if err != nil {
+		WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Data: sessions})
+}
+
+// RevokeSession logs one of the caller's sessions out, which may be the
+// current one.
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, req *http.Request) {
+	principal, ok := authdomain.PrincipalFromContext(req.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return
+	}
+	err := h.authUsecase.RevokeSession(req.Context(), principal.UserID, chi.URLParam(req, "id"))
 	if err != nil {
+		if errors.Is(err, authuc.ErrSessionNotFound) {
+			WriteJSON(w, http.StatusNotFound, ApiResponse{Error: err.Error()})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "session_revoked"})
+}
+
+func writeLogoutError(w http.ResponseWriter, err error) {
+	if errors.Is(err, authuc.ErrInvalidToken) {
 		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: err.Error()})
 		return
 	}
-	WriteJSON(w, http.StatusOK, ApiResponse{Data: map[string]any{"token": token, "user": user}})
+	WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+}
+
+// StartPhoneChange sends codes for moving the caller to a new number.
+func (h *AuthHandler) StartPhoneChange(w http.ResponseWriter, req *http.Request) {
+	principal, ok := authdomain.PrincipalFromContext(req.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return
+	}
+	var body struct {
+		Phone   string `json:"phone"`
+		Channel string `json:"channel"`
+	}
+	if !h.decodeAndValidate(w, req, &body) {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_phone"})
+		return
+	}
+	number, err := phone.Normalize(body.Phone, h.defaultRegion)
+	if err != nil {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: err.Error()})
+		return
+	}
+	channel, ok := authdomain.ParseOTPChannel(body.Channel)
+	if !ok {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: authuc.ErrUnsupportedChannel.Error()})
+		return
+	}
+	started, err := h.authUsecase.StartPhoneChange(req.Context(), principal.UserID, number, channel)
+	if err != nil {
+		var retry *authuc.RetryError
+		switch {
+		case errors.As(err, &retry):
+			setRetryAfter(w, retry.RetryAfter)
+			WriteJSON(w, http.StatusTooManyRequests, ApiResponse{Error: retry.Error()})
+		case errors.Is(err, authuc.ErrPhoneTaken):
+			WriteJSON(w, http.StatusConflict, ApiResponse{Error: err.Error()})
+		case errors.Is(err, authuc.ErrSamePhone), errors.Is(err, authuc.ErrUnsupportedChannel), errors.Is(err, authuc.ErrCountryNotAllowed):
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: err.Error()})
+		case errors.Is(err, authuc.ErrInvalidToken):
+			WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: err.Error()})
+		case errors.Is(err, authuc.ErrOTPDeliveryFailed):
+			WriteJSON(w, http.StatusBadGateway, ApiResponse{Error: authuc.ErrOTPDeliveryFailed.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		}
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "otp_sent", Data: map[string]any{
+		"expires_in":   int(started.ExpiresIn.Seconds()),
+		"resend_after": int(started.ResendAfter.Seconds()),
+		"confirm_old":  started.ConfirmOld,
+	}})
+}
+
+// ConfirmPhoneChange completes the change and logs out every session,
+// including the caller's.
+func (h *AuthHandler) ConfirmPhoneChange(w http.ResponseWriter, req *http.Request) {
+	principal, ok := authdomain.PrincipalFromContext(req.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return
+	}
+	var body struct {
+		Code    string `json:"code"`
+		OldCode string `json:"old_code"`
+	}
+	if !h.decodeAndValidate(w, req, &body) || strings.TrimSpace(body.Code) == "" {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_payload"})
+		return
+	}
+	user, err := h.authUsecase.ConfirmPhoneChange(req.Context(), principal.UserID, body.Code, body.OldCode)
+	if err != nil {
+		var retry *authuc.RetryError
+		switch {
+		case errors.As(err, &retry):
+			setRetryAfter(w, retry.RetryAfter)
+			WriteJSON(w, http.StatusTooManyRequests, ApiResponse{Error: retry.Error()})
+		case errors.Is(err, authuc.ErrTooManyAttempts):
+			WriteJSON(w, http.StatusTooManyRequests, ApiResponse{Error: err.Error()})
+		case errors.Is(err, authuc.ErrInvalidOTP):
+			WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: err.Error()})
+		case errors.Is(err, authuc.ErrPhoneTaken):
+			WriteJSON(w, http.StatusConflict, ApiResponse{Error: err.Error()})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		}
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "phone_changed", Data: user})
+}
+
+// DeleteAccount schedules the caller's account for deletion and logs out
+// every session.
+func (h *AuthHandler) DeleteAccount(w http.ResponseWriter, req *http.Request) {
+	principal, ok := authdomain.PrincipalFromContext(req.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return
+	}
+	purgeAt, err := h.authUsecase.DeleteAccount(req.Context(), principal.UserID)
+	if err != nil {
+		writeLogoutError(w, err)
+		return
+	}
+	WriteJSON(w, http.StatusAccepted, ApiResponse{Message: "account_deleted", Data: map[string]any{
+		"purge_after": purgeAt.UTC(),
+	}})
 }
 
 func (h *AuthHandler) decodeAndValidate(_ http.ResponseWriter, req *http.Request, body any) bool {
@@ -52,11 +309,4 @@ func (h *AuthHandler) decodeAndValidate(_ http.ResponseWriter, req *http.Request
 		return false
 	}
 	return true
-}
-
-var e164Re = regexp.MustCompile(`^\+[1-9]\d{7,14}$`)
-
-func isValidE164(phone string) bool {
-	phone = strings.TrimSpace(phone)
-	return e164Re.MatchString(phone)
 }
\ No newline at end of file
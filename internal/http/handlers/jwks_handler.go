@@ -0,0 +1,22 @@
+package handlers
+
+import (
+	"net/http"
+
+	"dekamond/internal/infra/jwtkeys"
+)
+
+type JWKSHandler struct {
+	keys *jwtkeys.KeySet
+}
+
+func NewJWKSHandler(keys *jwtkeys.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// Get serves the public keys as a bare JWK Set (RFC 7517), not wrapped in
+// ApiResponse, so standard JWT libraries can consume it directly.
+func (h *JWKSHandler) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	WriteJSON(w, http.StatusOK, h.keys.JWKS())
+}
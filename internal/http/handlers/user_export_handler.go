@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/logging"
+	useruc "dekamond/internal/usecase/user"
+)
+
+const (
+	// exportFlushRows is how often the export pushes buffered rows to the
+	// client.
+	exportFlushRows = 500
+	// exportWriteTimeout is how long the client gets to take each flushed
+	// batch. It replaces the server's WriteTimeout, which would cut off any
+	// export that takes longer than a normal request.
+	exportWriteTimeout = 30 * time.Second
+)
+
+// rowEncoder writes users in one export format.
+type rowEncoder interface {
+	header() error
+	encode(u userdomain.User) error
+	flush() error
+}
+
+var csvColumns = []string{
+	"id", "phone", "status", "status_reason", "status_until", "roles",
+	"display_name", "email", "avatar_url", "locale", "timezone", "created_at", "updated_at",
+}
+
+type csvEncoder struct{ w *csv.Writer }
+
+func (e csvEncoder) header() error { return e.w.Write(csvColumns) }
+
+func (e csvEncoder) encode(u userdomain.User) error {
+	var until string
+	if u.StatusUntil != nil {
+		until = u.StatusUntil.UTC().Format(time.RFC3339)
+	}
+	return e.w.Write([]string{
+		u.ID, u.Phone, string(u.Status), csvSafe(u.StatusReason), until, strings.Join(u.Roles, ";"),
+		csvSafe(u.DisplayName), csvSafe(u.Email), csvSafe(u.AvatarURL), u.Locale, u.Timezone,
+		u.CreatedAt.UTC().Format(time.RFC3339), u.UpdatedAt.UTC().Format(time.RFC3339),
+	})
+}
+
+func (e csvEncoder) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// csvSafe stops spreadsheets from running free-text fields as formulas.
+func csvSafe(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+type ndjsonEncoder struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (e ndjsonEncoder) header() error                  { return nil }
+func (e ndjsonEncoder) encode(u userdomain.User) error { return e.enc.Encode(u) }
+func (e ndjsonEncoder) flush() error                   { return e.w.Flush() }
+
+// ExportUsers streams every user matching the List filters as CSV (the
+// default) or NDJSON. Nothing is sent until the first row is ready, so errors
+// up to then still get a JSON response. Later errors abort the connection so
+// a partial file can't pass for a complete one.
+func (h *UserHandler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	q, errCode := h.listQuery(r)
+	if errCode != "" {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: errCode})
+		return
+	}
+
+	var enc rowEncoder
+	format := r.URL.Query().Get("format")
+	switch format {
+	case "", "csv":
+		format = "csv"
+		enc = csvEncoder{w: csv.NewWriter(w)}
+	case "ndjson":
+		bw := bufio.NewWriter(w)
+		enc = ndjsonEncoder{w: bw, enc: json.NewEncoder(bw)}
+	default:
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_format"})
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	flush := func() error {
+		if err := enc.flush(); err != nil {
+			return err
+		}
+		_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		return nil
+	}
+	started := false
+	start := func() error {
+		started = true
+		contentType := map[string]string{"csv": "text/csv; charset=utf-8", "ndjson": "application/x-ndjson"}[format]
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="users-%s.%s"`,
+			time.Now().UTC().Format("20060102T150405Z"), format))
+		w.Header().Set("Cache-Control", "no-store")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		_ = rc.SetWriteDeadline(time.Now().Add(exportWriteTimeout))
+		w.WriteHeader(http.StatusOK)
+		return enc.header()
+	}
+
+	rows := 0
+	err := h.uuc.StreamUsers(r.Context(), q, func(u userdomain.User) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		}
+		if err := enc.encode(u); err != nil {
+			return err
+		}
+		rows++
+		if rows%exportFlushRows == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil && !started {
+		err = start()
+	}
+	if err == nil {
+		err = flush()
+	}
+	if err == nil {
+		return
+	}
+	if !started {
+		var fieldErr *useruc.FieldError
+		if errors.As(err, &fieldErr) {
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: fieldErr.Error()})
+			return
+		}
+		WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "export_failed"})
+		return
+	}
+	logging.FromContext(r.Context()).Warn("user export aborted", "rows", rows, "err", err)
+	panic(http.ErrAbortHandler)
+}
@@ -1,26 +1,40 @@
 package handlers
 
 import (
-	"log"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/phone"
 	useruc "dekamond/internal/usecase/user"
 )
 
 type UserHandler struct {
-	uuc *useruc.UserUsecase
+	uuc           *useruc.UserUsecase
+	defaultRegion string
 }
 
-func NewUserHandler(uuc *useruc.UserUsecase) *UserHandler {
-	return &UserHandler{uuc: uuc}
+func NewUserHandler(uuc *useruc.UserUsecase, defaultRegion string) *UserHandler {
+	return &UserHandler{uuc: uuc, defaultRegion: defaultRegion}
 }
 
 func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	principal, ok := authdomain.PrincipalFromContext(r.Context())
+	if !ok || (principal.UserID != id && !principal.HasPermission(authdomain.PermUsersRead)) {
+		WriteJSON(w, http.StatusForbidden, ApiResponse{Error: "forbidden"})
+		return
+	}
+
 	u, err := h.uuc.GetByID(r.Context(), id)
 	if err != nil {
 		switch err {
@@ -33,25 +47,173 @@ func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 		}
 		return
 	}
+	setETag(w, u.Version)
+	WriteJSON(w, http.StatusOK, ApiResponse{Data: u})
+}
+
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authdomain.PrincipalFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return
+	}
+
+	u, err := h.uuc.GetByID(r.Context(), principal.UserID)
+	if err != nil {
+		switch err {
+		case useruc.ErrInvalidID, useruc.ErrNotFound:
+			WriteJSON(w, http.StatusNotFound, ApiResponse{Error: "not_found"})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		}
+		return
+	}
+	setETag(w, u.Version)
+	if v, ok := etagVersion(r.Header.Get("If-None-Match")); ok && v == u.Version {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Data: u})
+}
+
+// UpdateMe applies a partial profile update. If-Match is required: the ETag
+// from GET /me, or "*" to overwrite whatever is stored.
+func (h *UserHandler) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authdomain.PrincipalFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return
+	}
+	if strings.TrimSpace(r.Header.Get("If-Match")) == "" {
+		WriteJSON(w, http.StatusPreconditionRequired, ApiResponse{Error: "precondition_required"})
+		return
+	}
+	ifMatch, ok := etagVersion(r.Header.Get("If-Match"))
+	if !ok {
+		WriteJSON(w, http.StatusPreconditionFailed, ApiResponse{Error: useruc.ErrPreconditionFailed.Error()})
+		return
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_payload"})
+		return
+	}
+	var upd useruc.ProfileUpdate
+	fields := map[string]**string{
+		"display_name": &upd.DisplayName,
+		"email":        &upd.Email,
+		"avatar_url":   &upd.AvatarURL,
+		"locale":       &upd.Locale,
+		"timezone":     &upd.Timezone,
+	}
+	for name, raw := range body {
+		dst, ok := fields[name]
+		if !ok {
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "unknown_field", Message: name})
+			return
+		}
+		var v *string
+		if err := json.Unmarshal(raw, &v); err != nil {
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_" + name})
+			return
+		}
+		if v == nil {
+			v = new(string)
+		}
+		*dst = v
+	}
+
+	u, err := h.uuc.UpdateProfile(r.Context(), principal.UserID, upd, ifMatch)
+	if err != nil {
+		var fieldErr *useruc.FieldError
+		switch {
+		case errors.As(err, &fieldErr):
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: fieldErr.Error()})
+		case errors.Is(err, useruc.ErrPreconditionFailed):
+			WriteJSON(w, http.StatusPreconditionFailed, ApiResponse{Error: err.Error()})
+		case errors.Is(err, userdomain.ErrVersionConflict):
+			WriteJSON(w, http.StatusConflict, ApiResponse{Error: err.Error()})
+		case errors.Is(err, useruc.ErrNotFound), errors.Is(err, useruc.ErrInvalidID):
+			WriteJSON(w, http.StatusNotFound, ApiResponse{Error: "not_found"})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		}
+		return
+	}
+	setETag(w, u.Version)
 	WriteJSON(w, http.StatusOK, ApiResponse{Data: u})
 }
 
 func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
-	log.Printf("server listening on :%s", r.URL.Query().Get("phone"))
-	q := useruc.ListQuery{
-		Phone: r.URL.Query().Get("phone"),
-		Page:  atoiDefault(r.URL.Query().Get("page"), 0),
-		Limit: atoiDefault(r.URL.Query().Get("limit"), 0),
+	q, errCode := h.listQuery(r)
+	if errCode != "" {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: errCode})
+		return
 	}
 
 	page, err := h.uuc.List(r.Context(), q)
 	if err != nil {
+		var fieldErr *useruc.FieldError
+		if errors.As(err, &fieldErr) {
+			WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: fieldErr.Error()})
+			return
+		}
 		WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "list_failed"})
 		return
 	}
 	WriteJSON(w, http.StatusOK, ApiResponse{Data: page})
 }
 
+// parseTimeParam accepts an RFC 3339 timestamp or a plain date, read as
+// midnight UTC.
+func parseTimeParam(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+// listQuery reads the List filters from the query string. It returns an
+// error code when a value can't be parsed.
+func (h *UserHandler) listQuery(r *http.Request) (useruc.ListQuery, string) {
+	query := r.URL.Query()
+	q := useruc.ListQuery{
+		PhonePrefix:   query.Get("phone_prefix"),
+		PhoneContains: query.Get("phone_contains"),
+		Status:        query.Get("status"),
+		Role:          query.Get("role"),
+		Sort:          query.Get("sort"),
+		After:         query.Get("after"),
+		Before:        query.Get("before"),
+		Count:         query.Get("count"),
+		Page:          atoiDefault(query.Get("page"), 0),
+		Limit:         atoiDefault(query.Get("limit"), 0),
+	}
+	if raw := query.Get("phone"); raw != "" {
+		n, err := phone.Parse(raw, h.defaultRegion)
+		if err != nil {
+			return q, "invalid_phone"
+		}
+		q.Phone = n.E164()
+	}
+	for _, p := range []struct {
+		name string
+		dst  **time.Time
+	}{{"created_after", &q.CreatedAfter}, {"created_before", &q.CreatedBefore}} {
+		raw := query.Get(p.name)
+		if raw == "" {
+			continue
+		}
+		t, err := parseTimeParam(raw)
+		if err != nil {
+			return q, "invalid_" + p.name
+		}
+		*p.dst = &t
+	}
+	return q, ""
+}
+
 func atoiDefault(s string, def int) int {
 	if s == "" {
 		return def
@@ -61,4 +223,29 @@ func atoiDefault(s string, def int) int {
 		return def
 	}
 	return n
+}
+
+// Export sends everything stored about the caller as a JSON download.
+func (h *UserHandler) Export(w http.ResponseWriter, r *http.Request) {
+	principal, ok := authdomain.PrincipalFromContext(r.Context())
+	if !ok {
+		WriteJSON(w, http.StatusUnauthorized, ApiResponse{Error: "missing_token"})
+		return
+	}
+	export, err := h.uuc.Export(r.Context(), principal.UserID)
+	if err != nil {
+		switch {
+		case errors.Is(err, useruc.ErrNotFound), errors.Is(err, useruc.ErrInvalidID):
+			WriteJSON(w, http.StatusNotFound, ApiResponse{Error: "not_found"})
+		default:
+			WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+		}
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%s-export.json"`, export.User.ID))
+	w.Header().Set("Cache-Control", "no-store")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(export)
 }
\ No newline at end of file
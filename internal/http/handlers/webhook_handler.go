@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"dekamond/internal/logging"
+	webhookuc "dekamond/internal/usecase/webhook"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// WebhookHandler manages webhook endpoints under /api/admin/webhooks.
+type WebhookHandler struct {
+	webhookUsecase *webhookuc.WebhookUsecase
+}
+
+func NewWebhookHandler(webhookUsecase *webhookuc.WebhookUsecase) *WebhookHandler {
+	return &WebhookHandler{webhookUsecase: webhookUsecase}
+}
+
+type createWebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var body createWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: "invalid_payload"})
+		return
+	}
+	endpoint, err := h.webhookUsecase.CreateEndpoint(r.Context(), body.URL, body.Events)
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusCreated, ApiResponse{Message: "webhook_created", Data: endpoint})
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	endpoints, err := h.webhookUsecase.ListEndpoints(r.Context())
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Data: endpoints})
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.webhookUsecase.DeleteEndpoint(r.Context(), chi.URLParam(r, "id")); err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Message: "webhook_deleted"})
+}
+
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	deliveries, err := h.webhookUsecase.ListDeliveries(r.Context(), chi.URLParam(r, "id"),
+		query.Get("status"), atoiDefault(query.Get("limit"), 0))
+	if err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, ApiResponse{Data: deliveries})
+}
+
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(chi.URLParam(r, "deliveryID"), 10, 64)
+	if err != nil {
+		WriteJSON(w, http.StatusNotFound, ApiResponse{Error: webhookuc.ErrDeliveryNotFound.Error()})
+		return
+	}
+	if err := h.webhookUsecase.Redeliver(r.Context(), chi.URLParam(r, "id"), id); err != nil {
+		writeWebhookError(w, r, err)
+		return
+	}
+	WriteJSON(w, http.StatusAccepted, ApiResponse{Message: "delivery_requeued"})
+}
+
+func writeWebhookError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, webhookuc.ErrInvalidURL), errors.Is(err, webhookuc.ErrUnknownEvent), errors.Is(err, webhookuc.ErrInvalidStatus):
+		WriteJSON(w, http.StatusBadRequest, ApiResponse{Error: err.Error()})
+	case errors.Is(err, webhookuc.ErrEndpointNotFound), errors.Is(err, webhookuc.ErrDeliveryNotFound):
+		WriteJSON(w, http.StatusNotFound, ApiResponse{Error: err.Error()})
+	default:
+		logging.FromContext(r.Context()).Error("webhook request failed", "err", err)
+		WriteJSON(w, http.StatusInternalServerError, ApiResponse{Error: "server_error"})
+	}
+}
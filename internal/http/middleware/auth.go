@@ -1,34 +1,37 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
-	"strings"
 
+	authdomain "dekamond/internal/domain/auth"
 	"dekamond/internal/http/handlers"
-	"github.com/golang-jwt/jwt/v5"
+	authuc "dekamond/internal/usecase/auth"
 )
 
-func JwtAuth(secret string) func(http.Handler) http.Handler {
+func JwtAuth(verifier TokenVerifier) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			auth := r.Header.Get("Authorization")
-			parts := strings.SplitN(auth, " ", 2)
-			if len(parts) != 2 || !strings.EqualFold(parts[0], "Bearer") {
+			tokenStr, ok := handlers.BearerToken(r)
+			if !ok {
 				handlers.WriteJSON(w, http.StatusUnauthorized, handlers.ApiResponse{Error: "missing_token"})
 				return
 			}
-			tokenStr := parts[1]
-			_, err := jwt.Parse(tokenStr, func(token *jwt.Token) (any, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, http.ErrAbortHandler
-				}
-				return []byte(secret), nil
-			})
+			principal, err := verifier.VerifyAccessToken(r.Context(), tokenStr)
 			if err != nil {
-				handlers.WriteJSON(w, http.StatusUnauthorized, handlers.ApiResponse{Error: "invalid_token"})
+				switch {
+				case errors.Is(err, authuc.ErrTokenRevoked):
+					handlers.WriteJSON(w, http.StatusUnauthorized, handlers.ApiResponse{Error: "token_revoked"})
+				case errors.Is(err, authuc.ErrInvalidToken):
+					handlers.WriteJSON(w, http.StatusUnauthorized, handlers.ApiResponse{Error: "invalid_token"})
+				case errors.Is(err, authuc.ErrAccountSuspended), errors.Is(err, authuc.ErrAccountBanned):
+					handlers.WriteJSON(w, http.StatusForbidden, handlers.ApiResponse{Error: err.Error()})
+				default:
+					handlers.WriteJSON(w, http.StatusServiceUnavailable, handlers.ApiResponse{Error: "auth_unavailable"})
+				}
 				return
 			}
-			next.ServeHTTP(w, r)
+			next.ServeHTTP(w, r.WithContext(authdomain.WithPrincipal(r.Context(), principal)))
 		})
 	}
-}
\ No newline at end of file
+}
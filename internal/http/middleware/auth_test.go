@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authdomain "dekamond/internal/domain/auth"
+	authuc "dekamond/internal/usecase/auth"
+)
+
+type fakeVerifier struct {
+	principal *authdomain.Principal
+	err       error
+}
+
+func (f *fakeVerifier) VerifyAccessToken(ctx context.Context, token string) (*authdomain.Principal, error) {
+	return f.principal, f.err
+}
+
+func TestJwtAuth(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		verifier   *fakeVerifier
+		wantStatus int
+		wantUserID string
+	}{
+		{
+			name:       "missing header",
+			verifier:   &fakeVerifier{},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "invalid token",
+			header:     "Bearer abc",
+			verifier:   &fakeVerifier{err: authuc.ErrInvalidToken},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "revoked token",
+			header:     "Bearer abc",
+			verifier:   &fakeVerifier{err: authuc.ErrTokenRevoked},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "suspended user",
+			header:     "Bearer abc",
+			verifier:   &fakeVerifier{err: authuc.ErrAccountSuspended},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "banned user",
+			header:     "Bearer abc",
+			verifier:   &fakeVerifier{err: authuc.ErrAccountBanned},
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "revocation store down",
+			header:     "Bearer abc",
+			verifier:   &fakeVerifier{err: errors.New("connection refused")},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "valid token",
+			header:     "bearer abc",
+			verifier:   &fakeVerifier{principal: &authdomain.Principal{UserID: "user-1"}},
+			wantStatus: http.StatusOK,
+			wantUserID: "user-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID string
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if p, ok := authdomain.PrincipalFromContext(r.Context()); ok {
+					gotUserID = p.UserID
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest("GET", "/api/users/me", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			JwtAuth(tt.verifier)(handler).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if gotUserID != tt.wantUserID {
+				t.Errorf("principal user = %q, want %q", gotUserID, tt.wantUserID)
+			}
+		})
+	}
+}
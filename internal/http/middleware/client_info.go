@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	authdomain "dekamond/internal/domain/auth"
+	"dekamond/internal/logging"
+)
+
+const maxDeviceIDLen = 128
+
+// ClientInfo records the caller's IP, user agent and device ID in the request
+// context, along with the ID set by RequestID. X-Forwarded-For and X-Real-IP
+// are only honoured when the request comes from one of trustedProxies (IPs or
+// CIDRs); the client is the right-most forwarded address that is not itself a
+// trusted proxy.
+func ClientInfo(trustedProxies []string, deviceHeader string) func(http.Handler) http.Handler {
+	trusted := parsePrefixes(trustedProxies)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			info := authdomain.ClientInfo{
+				IP:        clientIP(r, trusted),
+				UserAgent: r.UserAgent(),
+				RequestID: logging.RequestID(r.Context()),
+			}
+			if deviceHeader != "" {
+				if id := strings.TrimSpace(r.Header.Get(deviceHeader)); len(id) <= maxDeviceIDLen {
+					info.DeviceID = id
+				}
+			}
+			ctx := authdomain.WithClientInfo(r.Context(), info)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func clientIP(r *http.Request, trusted []netip.Prefix) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+	if !isTrusted(ip, trusted) {
+		return ip
+	}
+
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop := strings.TrimSpace(hops[i])
+			if _, err := netip.ParseAddr(hop); err != nil {
+				break
+			}
+			ip = hop
+			if !isTrusted(hop, trusted) {
+				break
+			}
+		}
+		return ip
+	}
+	if real := strings.TrimSpace(r.Header.Get("X-Real-IP")); real != "" {
+		if _, err := netip.ParseAddr(real); err == nil {
+			return real
+		}
+	}
+	return ip
+}
+
+func isTrusted(ip string, trusted []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range trusted {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+func parsePrefixes(items []string) []netip.Prefix {
+	var out []netip.Prefix
+	for _, item := range items {
+		if p, err := netip.ParsePrefix(item); err == nil {
+			out = append(out, p.Masked())
+			continue
+		}
+		if a, err := netip.ParseAddr(item); err == nil {
+			out = append(out, netip.PrefixFrom(a, a.BitLen()))
+			continue
+		}
+		slog.Warn("ignoring invalid trusted proxy", "proxy", item)
+	}
+	return out
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	authdomain "dekamond/internal/domain/auth"
+	"dekamond/internal/logging"
+)
+
+func TestClientInfo(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "192.168.1.1"}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		headers    map[string]string
+		wantIP     string
+		wantDevice string
+	}{
+		{
+			name:       "direct connection",
+			remoteAddr: "203.0.113.7:5000",
+			wantIP:     "203.0.113.7",
+		},
+		{
+			name:       "forwarded header from untrusted peer is ignored",
+			remoteAddr: "203.0.113.7:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			wantIP:     "203.0.113.7",
+		},
+		{
+			name:       "trusted proxy",
+			remoteAddr: "10.1.2.3:5000",
+			headers:    map[string]string{"X-Forwarded-For": "198.51.100.1"},
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "spoofed hops left of the first untrusted address are ignored",
+			remoteAddr: "10.1.2.3:5000",
+			headers:    map[string]string{"X-Forwarded-For": "1.1.1.1, 198.51.100.1, 192.168.1.1"},
+			wantIP:     "198.51.100.1",
+		},
+		{
+			name:       "real ip header from trusted proxy",
+			remoteAddr: "192.168.1.1:5000",
+			headers:    map[string]string{"X-Real-IP": "198.51.100.9"},
+			wantIP:     "198.51.100.9",
+		},
+		{
+			name:       "device header",
+			remoteAddr: "203.0.113.7:5000",
+			headers:    map[string]string{"X-Device-ID": " ios-abc "},
+			wantIP:     "203.0.113.7",
+			wantDevice: "ios-abc",
+		},
+		{
+			name:       "oversized device header is dropped",
+			remoteAddr: "203.0.113.7:5000",
+			headers:    map[string]string{"X-Device-ID": strings.Repeat("x", 200)},
+			wantIP:     "203.0.113.7",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got authdomain.ClientInfo
+			handler := ClientInfo(trusted, "X-Device-ID")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = authdomain.ClientInfoFromContext(r.Context())
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			req = req.WithContext(logging.WithRequestID(req.Context(), "req-1"))
+			req.RemoteAddr = tt.remoteAddr
+			for k, v := range tt.headers {
+				req.Header.Set(k, v)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got.IP != tt.wantIP {
+				t.Errorf("IP = %q, want %q", got.IP, tt.wantIP)
+			}
+			if got.DeviceID != tt.wantDevice {
+				t.Errorf("DeviceID = %q, want %q", got.DeviceID, tt.wantDevice)
+			}
+			if got.RequestID != "req-1" {
+				t.Errorf("RequestID = %q, want the one set by RequestID", got.RequestID)
+			}
+		})
+	}
+}
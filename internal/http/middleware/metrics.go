@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+	"time"
+
+	"dekamond/internal/http/handlers"
+	"dekamond/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// Metrics observes every request in the HTTP latency histogram, labelled with
+// the chi route pattern. Requests that match no route share one label, as do
+// requests with a non-standard method.
+func Metrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		defer func() {
+			status := ww.Status()
+			if status == 0 {
+				status = http.StatusOK
+			}
+			route := ""
+			if rctx := chi.RouteContext(r.Context()); rctx != nil {
+				route = rctx.RoutePattern()
+			}
+			if route == "" {
+				route = "unmatched"
+			}
+			metrics.HTTPRequestDuration.WithLabelValues(methodLabel(r.Method), route, strconv.Itoa(status)).
+				Observe(time.Since(start).Seconds())
+		}()
+		next.ServeHTTP(ww, r)
+	})
+}
+
+// methodLabel keeps the method label bounded: clients can send any token as
+// the method.
+func methodLabel(method string) string {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch,
+		http.MethodDelete, http.MethodConnect, http.MethodOptions, http.MethodTrace:
+		return method
+	}
+	return "OTHER"
+}
+
+// ScrapeToken requires "Authorization: Bearer <token>" when token is set, so
+// the metrics endpoint can be reached by a scraper but not by anyone else.
+func ScrapeToken(token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if token != "" {
+				got, ok := handlers.BearerToken(r)
+				if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+					handlers.WriteJSON(w, http.StatusUnauthorized, handlers.ApiResponse{Error: "invalid_token"})
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
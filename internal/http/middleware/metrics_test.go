@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dekamond/internal/metrics"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	r := chi.NewRouter()
+	r.Use(Metrics)
+	r.Get("/test-metrics/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+
+	before := testutil.CollectAndCount(metrics.HTTPRequestDuration)
+	for _, path := range []string{"/test-metrics/1", "/test-metrics/2", "/no-such-route"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// Both IDs share the route pattern's series; the unknown path gets its own.
+	if n := testutil.CollectAndCount(metrics.HTTPRequestDuration) - before; n != 2 {
+		t.Errorf("new series = %d, want 2", n)
+	}
+
+	// Made-up methods share one series.
+	before = testutil.CollectAndCount(metrics.HTTPRequestDuration)
+	for _, method := range []string{"FOO", "BAR", "BAZ"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/test-metrics/1", nil))
+	}
+	if n := testutil.CollectAndCount(metrics.HTTPRequestDuration) - before; n != 1 {
+		t.Errorf("new series for unknown methods = %d, want 1", n)
+	}
+}
+
+func TestScrapeToken(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		header string
+		want   int
+	}{
+		{name: "no token configured", want: http.StatusOK},
+		{name: "right token", token: "s3cret", header: "Bearer s3cret", want: http.StatusOK},
+		{name: "wrong token", token: "s3cret", header: "Bearer guess", want: http.StatusUnauthorized},
+		{name: "missing token", token: "s3cret", want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := ScrapeToken(tt.token)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+			req := httptest.NewRequest("GET", "/metrics", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
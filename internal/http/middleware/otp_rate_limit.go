@@ -1,70 +1,228 @@
 package middleware
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strings"
 	"time"
 
+	authdomain "dekamond/internal/domain/auth"
 	"dekamond/internal/http/handlers"
+	"dekamond/internal/infra/ratelimit"
+	"dekamond/internal/phone"
+	authuc "dekamond/internal/usecase/auth"
 )
 
-func OTPRateLimit(limiter RateLimiter, maxRequests int, window time.Duration) func(http.Handler) http.Handler {
+// OTPQuota is one layer of the request-otp send limits. Key picks the bucket
+// for a request and the phone number it asks a code for; an empty key skips
+// the layer for that request.
+type OTPQuota struct {
+	Limiter RateLimiter
+	Limit   ratelimit.Limit
+	Key     func(r *http.Request, phone string) string
+}
+
+// maxOTPRequestBody bounds what OTPSendQuota reads before anything else has
+// looked at the request; a phone number and a channel fit in far less.
+const maxOTPRequestBody = 4 << 10
+
+// OTPSendQuota rejects requests for numbers that do not parse or whose region
+// countryAllowed refuses (nil allows all) before touching any quota. It then
+// peeks at every quota and only charges them once all have room, so a
+// request turned away by one layer costs nothing in the others. Quotas with a
+// zero rate are disabled. The X-RateLimit-* headers describe the tightest
+// quota.
+//
+// The phone number is normalized with defaultRegion so that "0912..." and
+// "+98912..." share buckets.
+func OTPSendQuota(defaultRegion string, countryAllowed func(region string) bool, quotas ...OTPQuota) func(http.Handler) http.Handler {
+	var active []OTPQuota
+	for _, q := range quotas {
+		if q.Limit.Rate > 0 {
+			active = append(active, q)
+		}
+	}
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			var body struct{ Phone string `json:"phone"` }
-			if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			raw, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxOTPRequestBody))
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				handlers.WriteJSON(w, http.StatusRequestEntityTooLarge, handlers.ApiResponse{Error: "request_too_large"})
+				return
+			}
+			if err != nil {
 				handlers.WriteJSON(w, http.StatusBadRequest, handlers.ApiResponse{Error: "invalid_phone"})
 				return
 			}
-
-			phone := strings.TrimSpace(body.Phone)
-			if phone == "" {
+			var body struct{ Phone string `json:"phone"` }
+			if err := json.Unmarshal(raw, &body); err != nil {
 				handlers.WriteJSON(w, http.StatusBadRequest, handlers.ApiResponse{Error: "invalid_phone"})
 				return
 			}
 
-			rlKey := fmt.Sprintf("otp:rl:%s", phone)
-			cnt, err := limiter.Increment(r.Context(), rlKey)
+			number, err := phone.Normalize(body.Phone, defaultRegion)
 			if err != nil {
-				next.ServeHTTP(w, r)
+				handlers.WriteJSON(w, http.StatusBadRequest, handlers.ApiResponse{Error: err.Error()})
 				return
 			}
+			if countryAllowed != nil {
+				region, _ := phone.Region(number)
+				if !countryAllowed(region) {
+					handlers.WriteJSON(w, http.StatusBadRequest, handlers.ApiResponse{Error: authuc.ErrCountryNotAllowed.Error()})
+					return
+				}
+			}
 
-			if cnt == 1 {
-				limiter.SetExpiry(r.Context(), rlKey, window)
+			r.Body = io.NopCloser(bytes.NewReader(raw))
+
+			type charge struct {
+				q   OTPQuota
+				key string
+			}
+			var charges []charge
+			for _, q := range active {
+				key := q.Key(r, number)
+				if key == "" {
+					continue
+				}
+				res, err := q.Limiter.Peek(r.Context(), key, q.Limit)
+				if err != nil {
+					handlers.WriteJSON(w, http.StatusServiceUnavailable, handlers.ApiResponse{Error: "rate_limit_unavailable"})
+					return
+				}
+				if !res.Allowed {
+					allow(w, res)
+					return
+				}
+				charges = append(charges, charge{q, key})
 			}
 
-			if cnt > int64(maxRequests) {
-				handlers.WriteJSON(w, http.StatusTooManyRequests, handlers.ApiResponse{
-					Error: "rate limit exceeded",
-				})
-				return
+			// A concurrent request can still take the last slot between the
+			// peek and the charge; Allow is atomic per key, so the limit holds
+			// and at worst the layers charged before it lose one request.
+			var tightest *ratelimit.Result
+			for _, c := range charges {
+				res, err := c.q.Limiter.Allow(r.Context(), c.key, c.q.Limit)
+				if err != nil {
+					handlers.WriteJSON(w, http.StatusServiceUnavailable, handlers.ApiResponse{Error: "rate_limit_unavailable"})
+					return
+				}
+				if !res.Allowed {
+					allow(w, res)
+					return
+				}
+				if tightest == nil || res.Remaining < tightest.Remaining {
+					tightest = &res
+				}
+			}
+			if tightest != nil {
+				allow(w, *tightest)
 			}
 
-			bodyBytes, _ := json.Marshal(body)
-			r.Body = &bodyReader{data: bodyBytes}
-			
 			next.ServeHTTP(w, r)
 		})
 	}
 }
 
-type bodyReader struct {
-	data []byte
-	pos  int
+func PhoneQuota(limiter RateLimiter, maxRequests int, window time.Duration) OTPQuota {
+	return OTPQuota{
+		Limiter: limiter,
+		Limit:   ratelimit.PerPeriod(maxRequests, window),
+		Key: func(r *http.Request, phone string) string {
+			return fmt.Sprintf("otp:rl:%s", phone)
+		},
+	}
+}
+
+// CallerPhoneQuota charges the authenticated caller's current number in the
+// same bucket as PhoneQuota, for routes that send a code there as well.
+func CallerPhoneQuota(limiter RateLimiter, maxRequests int, window time.Duration) OTPQuota {
+	q := PhoneQuota(limiter, maxRequests, window)
+	phoneKey := q.Key
+	q.Key = func(r *http.Request, _ string) string {
+		p, ok := authdomain.PrincipalFromContext(r.Context())
+		if !ok || p.Phone == "" {
+			return ""
+		}
+		return phoneKey(r, p.Phone)
+	}
+	return q
 }
 
-func (br *bodyReader) Read(p []byte) (n int, err error) {
-	if br.pos >= len(br.data) {
-		return 0, nil
+// IPQuota keys on the client IP found by the ClientInfo middleware.
+func IPQuota(limiter RateLimiter, maxRequests int, window time.Duration) OTPQuota {
+	return OTPQuota{
+		Limiter: limiter,
+		Limit:   ratelimit.PerPeriod(maxRequests, window),
+		Key: func(r *http.Request, phone string) string {
+			if ip := authdomain.ClientInfoFromContext(r.Context()).IP; ip != "" {
+				return fmt.Sprintf("otp:rl:ip:%s", ip)
+			}
+			return ""
+		},
 	}
-	n = copy(p, br.data[br.pos:])
-	br.pos += n
-	return n, nil
 }
 
-func (br *bodyReader) Close() error {
-	return nil
-}
\ No newline at end of file
+// DeviceQuota keys on the device fingerprint header. Requests without one
+// are only subject to the other quotas.
+func DeviceQuota(limiter RateLimiter, maxRequests int, window time.Duration) OTPQuota {
+	return OTPQuota{
+		Limiter: limiter,
+		Limit:   ratelimit.PerPeriod(maxRequests, window),
+		Key: func(r *http.Request, phone string) string {
+			id := authdomain.ClientInfoFromContext(r.Context()).DeviceID
+			if id == "" {
+				return ""
+			}
+			sum := sha256.Sum256([]byte(id))
+			return fmt.Sprintf("otp:rl:device:%s", hex.EncodeToString(sum[:8]))
+		},
+	}
+}
+
+// PrefixQuota keys on the first digits of the number, which catches pumping
+// through ranges of numbers on one premium-rate block.
+func PrefixQuota(limiter RateLimiter, digits, maxRequests int, window time.Duration) OTPQuota {
+	return OTPQuota{
+		Limiter: limiter,
+		Limit:   ratelimit.PerPeriod(maxRequests, window),
+		Key: func(r *http.Request, number string) string {
+			d := strings.TrimPrefix(number, "+")
+			if digits <= 0 || len(d) <= digits {
+				return ""
+			}
+			return fmt.Sprintf("otp:rl:prefix:%s", d[:digits])
+		},
+	}
+}
+
+func CountryQuota(limiter RateLimiter, maxRequests int, window time.Duration) OTPQuota {
+	return OTPQuota{
+		Limiter: limiter,
+		Limit:   ratelimit.PerPeriod(maxRequests, window),
+		Key: func(r *http.Request, number string) string {
+			region, ok := phone.Region(number)
+			if !ok {
+				region = "unknown"
+			}
+			return fmt.Sprintf("otp:rl:country:%s", region)
+		},
+	}
+}
+
+// GlobalQuota is a single budget shared by every request.
+func GlobalQuota(limiter RateLimiter, maxRequests int, window time.Duration) OTPQuota {
+	return OTPQuota{
+		Limiter: limiter,
+		Limit:   ratelimit.PerPeriod(maxRequests, window),
+		Key: func(r *http.Request, phone string) string {
+			return "otp:rl:global"
+		},
+	}
+}
@@ -3,59 +3,19 @@ package middleware
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
 
-	userdomain "dekamond/internal/domain/user"
+	authdomain "dekamond/internal/domain/auth"
+	"dekamond/internal/infra/ratelimit"
 )
 
-type mockCacheStore struct {
-	store map[string]string
-}
-
-func newMockCacheStore() *mockCacheStore {
-	return &mockCacheStore{
-		store: make(map[string]string),
-	}
-}
-
-func (m *mockCacheStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
-	m.store[key] = value
-	return nil
-}
-
-func (m *mockCacheStore) Get(ctx context.Context, key string) (string, error) {
-	value, exists := m.store[key]
-	if !exists {
-		return "", userdomain.ErrNotFound
-	}
-	return value, nil
-}
-
-func (m *mockCacheStore) Delete(ctx context.Context, key string) error {
-	delete(m.store, key)
-	return nil
-}
-
-func (m *mockCacheStore) Increment(ctx context.Context, key string) (int64, error) {
-	current, exists := m.store[key]
-	if !exists {
-		m.store[key] = "1"
-		return 1, nil
-	}
-	count := len(current) + 1
-	m.store[key] = string(make([]byte, count))
-	return int64(count), nil
-}
-
-func (m *mockCacheStore) SetExpiry(ctx context.Context, key string, ttl time.Duration) error {
-	return nil
-}
-
-func TestOTPRateLimit(t *testing.T) {
+func TestPhoneQuota(t *testing.T) {
 	tests := []struct {
 		name           string
 		phone          string
@@ -100,14 +60,14 @@ func TestOTPRateLimit(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			cache := newMockCacheStore()
+			limiter := ratelimit.NewMemory(ratelimit.SlidingLog)
 
 			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				w.WriteHeader(http.StatusOK)
 				w.Write([]byte("OK"))
 			})
 
-			middleware := OTPRateLimit(cache, tt.maxRequests, tt.windowDuration)(handler)
+			middleware := OTPSendQuota("", nil, PhoneQuota(limiter, tt.maxRequests, tt.windowDuration))(handler)
 
 			for i := 0; i < tt.requestCount; i++ {
 				body := fmt.Sprintf(`{"phone":"%s"}`, tt.phone)
@@ -131,6 +91,71 @@ func TestOTPRateLimit(t *testing.T) {
 	}
 }
 
+func TestPhoneQuotaHeaders(t *testing.T) {
+	var gotBody string
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody = string(b)
+		w.WriteHeader(http.StatusOK)
+	})
+	middleware := OTPSendQuota("", nil, PhoneQuota(ratelimit.NewMemory(ratelimit.SlidingLog), 2, time.Minute))(handler)
+
+	send := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/auth/request-otp", strings.NewReader(`{"phone":"+15551234567"}`))
+		rr := httptest.NewRecorder()
+		middleware.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("first request: status = %d, want %d", rr.Code, http.StatusOK)
+	}
+	if gotBody != `{"phone":"+15551234567"}` {
+		t.Errorf("handler body = %q, want the original request body", gotBody)
+	}
+	if got := rr.Header().Get("X-RateLimit-Limit"); got != "2" {
+		t.Errorf("X-RateLimit-Limit = %q, want 2", got)
+	}
+	if got := rr.Header().Get("X-RateLimit-Remaining"); got != "1" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 1", got)
+	}
+	if got := rr.Header().Get("X-RateLimit-Reset"); got != "60" {
+		t.Errorf("X-RateLimit-Reset = %q, want 60", got)
+	}
+
+	send()
+	rr = send()
+	if rr.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request: status = %d, want %d", rr.Code, http.StatusTooManyRequests)
+	}
+	if got := rr.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 0", got)
+	}
+	if secs, _ := strconv.Atoi(rr.Header().Get("Retry-After")); secs < 1 || secs > 60 {
+		t.Errorf("Retry-After = %q, want 1..60", rr.Header().Get("Retry-After"))
+	}
+}
+
+func TestOTPSendQuotaNormalizesPhone(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mw := OTPSendQuota("IR", nil, PhoneQuota(ratelimit.NewMemory(ratelimit.SlidingLog), 2, time.Hour))(handler)
+
+	for i, raw := range []string{"+989121234567", "0912 123 4567", "۰۹۱۲۱۲۳۴۵۶۷"} {
+		req := httptest.NewRequest("POST", "/api/auth/request-otp", strings.NewReader(fmt.Sprintf(`{"phone":"%s"}`, raw)))
+		rr := httptest.NewRecorder()
+		mw.ServeHTTP(rr, req)
+
+		want := http.StatusOK
+		if i == 2 {
+			want = http.StatusTooManyRequests
+		}
+		if rr.Code != want {
+			t.Errorf("request %d (%q): status = %d, want %d", i+1, raw, rr.Code, want)
+		}
+	}
+}
+
 func TestRateLimitKey(t *testing.T) {
 	phone := "+15551234567"
 	expected := "otp:rl:+15551234567"
@@ -140,3 +165,239 @@ func TestRateLimitKey(t *testing.T) {
 		t.Errorf("rateLimitKey() = %s, want %s", result, expected)
 	}
 }
+
+type failingLimiter struct{}
+
+func (failingLimiter) Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, ratelimit.ErrUnavailable
+}
+
+func (failingLimiter) Peek(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error) {
+	return ratelimit.Result{}, ratelimit.ErrUnavailable
+}
+
+func TestPhoneQuotaBackendDown(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+	req := httptest.NewRequest("POST", "/api/auth/request-otp", strings.NewReader(`{"phone":"+15551234567"}`))
+	rr := httptest.NewRecorder()
+
+	OTPSendQuota("", nil, PhoneQuota(failingLimiter{}, 3, time.Minute))(handler).ServeHTTP(rr, req)
+
+	if called {
+		t.Errorf("handler should not run when the limiter fails")
+	}
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestOTPSendQuota(t *testing.T) {
+	type request struct {
+		phone, ip, device string
+		wantStatus        int
+	}
+
+	tests := []struct {
+		name     string
+		quotas   func(l RateLimiter) []OTPQuota
+		requests []request
+	}{
+		{
+			name: "ip quota stops phone rotation",
+			quotas: func(l RateLimiter) []OTPQuota {
+				return []OTPQuota{PhoneQuota(l, 3, time.Hour), IPQuota(l, 2, time.Hour)}
+			},
+			requests: []request{
+				{phone: "+15551230001", ip: "203.0.113.7", wantStatus: http.StatusOK},
+				{phone: "+15551230002", ip: "203.0.113.7", wantStatus: http.StatusOK},
+				{phone: "+15551230003", ip: "203.0.113.7", wantStatus: http.StatusTooManyRequests},
+				{phone: "+15551230004", ip: "203.0.113.8", wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name: "device quota only applies with a device id",
+			quotas: func(l RateLimiter) []OTPQuota {
+				return []OTPQuota{DeviceQuota(l, 1, time.Hour)}
+			},
+			requests: []request{
+				{phone: "+15551230001", device: "dev-1", wantStatus: http.StatusOK},
+				{phone: "+15551230002", device: "dev-1", wantStatus: http.StatusTooManyRequests},
+				{phone: "+15551230003", wantStatus: http.StatusOK},
+				{phone: "+15551230004", wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name: "prefix quota",
+			quotas: func(l RateLimiter) []OTPQuota {
+				return []OTPQuota{PrefixQuota(l, 6, 2, time.Hour)}
+			},
+			requests: []request{
+				{phone: "+989120000001", wantStatus: http.StatusOK},
+				{phone: "+989120000002", wantStatus: http.StatusOK},
+				{phone: "+989120000003", wantStatus: http.StatusTooManyRequests},
+				{phone: "+989130000001", wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name: "country quota",
+			quotas: func(l RateLimiter) []OTPQuota {
+				return []OTPQuota{CountryQuota(l, 1, time.Hour)}
+			},
+			requests: []request{
+				{phone: "+447911000001", wantStatus: http.StatusOK},
+				{phone: "+447700000002", wantStatus: http.StatusTooManyRequests},
+				{phone: "+989120000001", wantStatus: http.StatusOK},
+			},
+		},
+		{
+			name: "global budget",
+			quotas: func(l RateLimiter) []OTPQuota {
+				return []OTPQuota{GlobalQuota(l, 2, 24*time.Hour)}
+			},
+			requests: []request{
+				{phone: "+15551230001", ip: "203.0.113.1", wantStatus: http.StatusOK},
+				{phone: "+447911000001", ip: "203.0.113.2", wantStatus: http.StatusOK},
+				{phone: "+989120000001", ip: "203.0.113.3", wantStatus: http.StatusTooManyRequests},
+			},
+		},
+		{
+			name: "zero rate disables a quota",
+			quotas: func(l RateLimiter) []OTPQuota {
+				return []OTPQuota{GlobalQuota(l, 0, time.Hour)}
+			},
+			requests: []request{
+				{phone: "+15551230001", wantStatus: http.StatusOK},
+				{phone: "+15551230001", wantStatus: http.StatusOK},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+			mw := ClientInfo(nil, "X-Device-ID")(OTPSendQuota("", nil, tt.quotas(ratelimit.NewMemory(ratelimit.SlidingLog))...)(handler))
+
+			for i, rq := range tt.requests {
+				req := httptest.NewRequest("POST", "/api/auth/request-otp", strings.NewReader(fmt.Sprintf(`{"phone":"%s"}`, rq.phone)))
+				req.RemoteAddr = "192.0.2.1:1234"
+				if rq.ip != "" {
+					req.RemoteAddr = rq.ip + ":1234"
+				}
+				if rq.device != "" {
+					req.Header.Set("X-Device-ID", rq.device)
+				}
+				rr := httptest.NewRecorder()
+				mw.ServeHTTP(rr, req)
+				if rr.Code != rq.wantStatus {
+					t.Errorf("request %d: status = %d, want %d", i+1, rr.Code, rq.wantStatus)
+				}
+			}
+		})
+	}
+}
+
+func TestOTPSendQuotaReportsTightestLimit(t *testing.T) {
+	l := ratelimit.NewMemory(ratelimit.SlidingLog)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	mw := OTPSendQuota("", nil, PhoneQuota(l, 5, time.Hour), GlobalQuota(l, 2, time.Hour))(handler)
+
+	req := httptest.NewRequest("POST", "/api/auth/request-otp", strings.NewReader(`{"phone":"+15551234567"}`))
+	rr := httptest.NewRecorder()
+	mw.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("X-RateLimit-Limit"); got != "2" {
+		t.Errorf("X-RateLimit-Limit = %q, want 2", got)
+	}
+	if got := rr.Header().Get("X-RateLimit-Remaining"); got != "1" {
+		t.Errorf("X-RateLimit-Remaining = %q, want 1", got)
+	}
+}
+
+// Requests rejected by validation or by one quota must not use up the
+// others, or anyone could drain the global budget for everybody.
+func TestOTPSendQuotaChargesOnlyWhenAllPass(t *testing.T) {
+	l := ratelimit.NewMemory(ratelimit.SlidingLog)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	notGB := func(region string) bool { return region != "GB" }
+	mw := ClientInfo(nil, "")(OTPSendQuota("", notGB,
+		PhoneQuota(l, 1, time.Hour),
+		IPQuota(l, 3, time.Hour),
+		GlobalQuota(l, 3, time.Hour),
+	)(handler))
+
+	send := func(phone, ip string) int {
+		req := httptest.NewRequest("POST", "/api/auth/request-otp", strings.NewReader(fmt.Sprintf(`{"phone":"%s"}`, phone)))
+		req.RemoteAddr = ip + ":1234"
+		rr := httptest.NewRecorder()
+		mw.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	steps := []struct {
+		phone, ip  string
+		wantStatus int
+	}{
+		{phone: "not a number", ip: "203.0.113.1", wantStatus: http.StatusBadRequest},
+		{phone: "+448001234567", ip: "203.0.113.1", wantStatus: http.StatusBadRequest}, // toll free
+		{phone: "+447400123456", ip: "203.0.113.1", wantStatus: http.StatusBadRequest}, // denied region
+		{phone: "+15551230001", ip: "203.0.113.1", wantStatus: http.StatusOK},
+		{phone: "+15551230001", ip: "203.0.113.1", wantStatus: http.StatusTooManyRequests},
+		{phone: "+15551230001", ip: "203.0.113.1", wantStatus: http.StatusTooManyRequests},
+		{phone: "+15551230002", ip: "203.0.113.1", wantStatus: http.StatusOK},
+		{phone: "+15551230003", ip: "203.0.113.2", wantStatus: http.StatusOK},
+		{phone: "+15551230004", ip: "203.0.113.3", wantStatus: http.StatusTooManyRequests},
+	}
+	for i, st := range steps {
+		if got := send(st.phone, st.ip); got != st.wantStatus {
+			t.Errorf("request %d (%s): status = %d, want %d", i+1, st.phone, got, st.wantStatus)
+		}
+	}
+}
+
+func TestOTPSendQuotaRejectsLargeBodies(t *testing.T) {
+	called := false
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { called = true })
+	body := `{"phone":"+15551234567","padding":"` + strings.Repeat("x", maxOTPRequestBody) + `"}`
+	req := httptest.NewRequest("POST", "/api/auth/request-otp", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+
+	OTPSendQuota("", nil, PhoneQuota(ratelimit.NewMemory(ratelimit.SlidingLog), 3, time.Minute))(handler).ServeHTTP(rr, req)
+
+	if called {
+		t.Errorf("handler should not run for an oversized body")
+	}
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Errorf("status = %d, want %d", rr.Code, http.StatusRequestEntityTooLarge)
+	}
+}
+
+func TestCallerPhoneQuota(t *testing.T) {
+	l := ratelimit.NewMemory(ratelimit.SlidingLog)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	requestOTP := OTPSendQuota("", nil, PhoneQuota(l, 2, time.Hour))(handler)
+	changePhone := OTPSendQuota("", nil, PhoneQuota(l, 2, time.Hour), CallerPhoneQuota(l, 2, time.Hour))(handler)
+
+	send := func(h http.Handler, phone string, principal *authdomain.Principal) int {
+		req := httptest.NewRequest("POST", "/", strings.NewReader(fmt.Sprintf(`{"phone":%q}`, phone)))
+		if principal != nil {
+			req = req.WithContext(authdomain.WithPrincipal(req.Context(), principal))
+		}
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	caller := &authdomain.Principal{UserID: "user-1", Phone: "+15551234567"}
+
+	// Phone changes to different numbers share the caller's own budget with
+	// codes requested for that number.
+	if got := send(requestOTP, "+15551234567", nil); got != http.StatusOK {
+		t.Fatalf("request-otp status = %d, want 200", got)
+	}
+	if got := send(changePhone, "+15550000001", caller); got != http.StatusOK {
+		t.Fatalf("first phone change status = %d, want 200", got)
+	}
+	if got := send(changePhone, "+15550000002", caller); got != http.StatusTooManyRequests {
+		t.Errorf("second phone change status = %d, want 429", got)
+	}
+}
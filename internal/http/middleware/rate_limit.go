@@ -1,41 +1,33 @@
 package middleware
 
 import (
-	"fmt"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
 	"dekamond/internal/http/handlers"
+	"dekamond/internal/infra/ratelimit"
 )
 
-func RateLimit(limiter RateLimiter, maxRequests int, window time.Duration, keyFunc func(r *http.Request) string) func(http.Handler) http.Handler {
-	return func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			key := keyFunc(r)
-			if key == "" {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			rlKey := fmt.Sprintf("rate_limit:%s", key)
-			cnt, err := limiter.Increment(r.Context(), rlKey)
-			if err != nil {
-				next.ServeHTTP(w, r)
-				return
-			}
-
-			if cnt == 1 {
-				limiter.SetExpiry(r.Context(), rlKey, window)
-			}
+// allow sets the X-RateLimit-* headers and, when res rejects the request,
+// writes the 429 response. It reports whether the request may proceed.
+func allow(w http.ResponseWriter, res ratelimit.Result) bool {
+	h := w.Header()
+	h.Set("X-RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("X-RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("X-RateLimit-Reset", strconv.Itoa(seconds(res.ResetAfter)))
+	if res.Allowed {
+		return true
+	}
 
-			if cnt > int64(maxRequests) {
-				handlers.WriteJSON(w, http.StatusTooManyRequests, handlers.ApiResponse{
-					Error: "rate limit exceeded",
-				})
-				return
-			}
+	h.Set("Retry-After", strconv.Itoa(max(seconds(res.RetryAfter), 1)))
+	handlers.WriteJSON(w, http.StatusTooManyRequests, handlers.ApiResponse{
+		Error: "rate limit exceeded",
+	})
+	return false
+}
 
-			next.ServeHTTP(w, r)
-		})
-	}
-}
\ No newline at end of file
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
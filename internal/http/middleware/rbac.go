@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	authdomain "dekamond/internal/domain/auth"
+	"dekamond/internal/http/handlers"
+)
+
+// RequireRole lets the request through if the principal has any of roles.
+// It must run after JwtAuth.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return require(func(p *authdomain.Principal) bool {
+		for _, role := range roles {
+			if p.HasRole(role) {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// RequirePermission lets the request through if one of the principal's roles
+// grants perm. It must run after JwtAuth.
+func RequirePermission(perm authdomain.Permission) func(http.Handler) http.Handler {
+	return require(func(p *authdomain.Principal) bool {
+		return p.HasPermission(perm)
+	})
+}
+
+func require(allowed func(*authdomain.Principal) bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := authdomain.PrincipalFromContext(r.Context())
+			if !ok {
+				handlers.WriteJSON(w, http.StatusUnauthorized, handlers.ApiResponse{Error: "missing_token"})
+				return
+			}
+			if !allowed(principal) {
+				handlers.WriteJSON(w, http.StatusForbidden, handlers.ApiResponse{Error: "forbidden"})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
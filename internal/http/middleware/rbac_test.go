@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	authdomain "dekamond/internal/domain/auth"
+)
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name       string
+		principal  *authdomain.Principal
+		perm       authdomain.Permission
+		wantStatus int
+	}{
+		{
+			name:       "no principal",
+			perm:       authdomain.PermUsersList,
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "user cannot list",
+			principal:  &authdomain.Principal{UserID: "u1", Roles: []string{authdomain.RoleUser}},
+			perm:       authdomain.PermUsersList,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "support cannot list",
+			principal:  &authdomain.Principal{UserID: "u1", Roles: []string{authdomain.RoleUser, authdomain.RoleSupport}},
+			perm:       authdomain.PermUsersList,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "support can read others",
+			principal:  &authdomain.Principal{UserID: "u1", Roles: []string{authdomain.RoleSupport}},
+			perm:       authdomain.PermUsersRead,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "support can suspend",
+			principal:  &authdomain.Principal{UserID: "u1", Roles: []string{authdomain.RoleSupport}},
+			perm:       authdomain.PermUsersModerate,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "support cannot ban",
+			principal:  &authdomain.Principal{UserID: "u1", Roles: []string{authdomain.RoleSupport}},
+			perm:       authdomain.PermUsersBan,
+			wantStatus: http.StatusForbidden,
+		},
+		{
+			name:       "admin can ban",
+			principal:  &authdomain.Principal{UserID: "u1", Roles: []string{authdomain.RoleAdmin}},
+			perm:       authdomain.PermUsersBan,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "admin can list",
+			principal:  &authdomain.Principal{UserID: "u1", Roles: []string{authdomain.RoleUser, authdomain.RoleAdmin}},
+			perm:       authdomain.PermUsersList,
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+			req := httptest.NewRequest("GET", "/api/users", nil)
+			if tt.principal != nil {
+				req = req.WithContext(authdomain.WithPrincipal(req.Context(), tt.principal))
+			}
+			rr := httptest.NewRecorder()
+			RequirePermission(tt.perm)(handler).ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mw := RequireRole(authdomain.RoleSupport, authdomain.RoleAdmin)(handler)
+
+	for roles, want := range map[string]int{
+		authdomain.RoleUser:    http.StatusForbidden,
+		authdomain.RoleSupport: http.StatusOK,
+		authdomain.RoleAdmin:   http.StatusOK,
+	} {
+		req := httptest.NewRequest("GET", "/", nil)
+		req = req.WithContext(authdomain.WithPrincipal(req.Context(), &authdomain.Principal{Roles: []string{roles}}))
+		rr := httptest.NewRecorder()
+		mw.ServeHTTP(rr, req)
+		if rr.Code != want {
+			t.Errorf("role %s: status = %d, want %d", roles, rr.Code, want)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dekamond/internal/logging"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+func TestRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantEcho bool
+	}{
+		{name: "missing header", header: ""},
+		{name: "client id is kept", header: "abc-123_x.y:z", wantEcho: true},
+		{name: "unsafe characters", header: "abc\n{\"admin\":true}"},
+		{name: "too long", header: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			var gotID string
+			handler := RequestID(logging.New(&buf, "info"))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotID = logging.RequestID(r.Context())
+				logging.FromContext(r.Context()).Info("inside")
+			}))
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				req.Header.Set(RequestIDHeader, tt.header)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if echoed := rec.Header().Get(RequestIDHeader); echoed != gotID {
+				t.Fatalf("response header = %q, context = %q", echoed, gotID)
+			}
+			if tt.wantEcho {
+				if gotID != tt.header {
+					t.Errorf("request ID = %q, want %q", gotID, tt.header)
+				}
+			} else if _, err := uuid.Parse(gotID); err != nil {
+				t.Errorf("request ID = %q, want a generated uuid", gotID)
+			}
+			var line map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil || line["request_id"] != gotID {
+				t.Errorf("context logger does not carry the request ID: %s", buf.String())
+			}
+		})
+	}
+}
+
+func TestAccessLog(t *testing.T) {
+	var buf bytes.Buffer
+	r := chi.NewRouter()
+	r.Use(RequestID(logging.New(&buf, "info")), AccessLog)
+	r.Get("/api/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte("nope"))
+	})
+	r.Get("/boom", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	tests := []struct {
+		path       string
+		wantRoute  string
+		wantStatus float64
+		wantLevel  string
+	}{
+		{path: "/api/users/42?phone=%2B989121234567", wantRoute: "/api/users/{id}", wantStatus: 404, wantLevel: "INFO"},
+		{path: "/boom", wantRoute: "/boom", wantStatus: 500, wantLevel: "ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			buf.Reset()
+			req := httptest.NewRequest("GET", tt.path, nil)
+			req.Header.Set(RequestIDHeader, "req-1")
+			r.ServeHTTP(httptest.NewRecorder(), req)
+
+			var line map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("access log is not one JSON line: %s", buf.String())
+			}
+			if line["route"] != tt.wantRoute || line["status"] != tt.wantStatus || line["level"] != tt.wantLevel {
+				t.Errorf("route, status, level = %v, %v, %v, want %v, %v, %v",
+					line["route"], line["status"], line["level"], tt.wantRoute, tt.wantStatus, tt.wantLevel)
+			}
+			if line["request_id"] != "req-1" {
+				t.Errorf("request_id = %v, want req-1", line["request_id"])
+			}
+			if _, ok := line["duration_ms"].(float64); !ok {
+				t.Errorf("duration_ms missing: %s", buf.String())
+			}
+			if strings.Contains(buf.String(), "9121234567") {
+				t.Errorf("access log leaks the query string: %s", buf.String())
+			}
+		})
+	}
+}
@@ -2,10 +2,16 @@ package middleware
 
 import (
 	"context"
-	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+	"dekamond/internal/infra/ratelimit"
 )
 
 type RateLimiter interface {
-	Increment(ctx context.Context, key string) (int64, error)
-	SetExpiry(ctx context.Context, key string, ttl time.Duration) error
-}
\ No newline at end of file
+	Allow(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
+	Peek(ctx context.Context, key string, limit ratelimit.Limit) (ratelimit.Result, error)
+}
+
+type TokenVerifier interface {
+	VerifyAccessToken(ctx context.Context, token string) (*authdomain.Principal, error)
+}
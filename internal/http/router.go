@@ -1,18 +1,27 @@
 package http
 
 import (
+	"log/slog"
 	"net/http"
 	"os"
-	"time"
 
 	"dekamond/internal/config"
+	auditdomain "dekamond/internal/domain/audit"
+	authdomain "dekamond/internal/domain/auth"
 	userdomain "dekamond/internal/domain/user"
+	webhookdomain "dekamond/internal/domain/webhook"
 	"dekamond/internal/http/handlers"
 	"dekamond/internal/http/middleware"
 	"dekamond/internal/infra/cache"
+	"dekamond/internal/infra/jwtkeys"
+	"dekamond/internal/infra/ratelimit"
+	"dekamond/internal/metrics"
+	webhookinfra "dekamond/internal/infra/webhook"
 	postgresrepositories "dekamond/internal/infra/db/postgres/repositories"
+	auditusecase "dekamond/internal/usecase/audit"
 	authusecase "dekamond/internal/usecase/auth"
 	userusecase "dekamond/internal/usecase/user"
+	webhookusecase "dekamond/internal/usecase/webhook"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/cors"
@@ -21,8 +30,12 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 )
 
-func NewRouter(conf config.Config, pg *pgxpool.Pool, redis *redis.Client) http.Handler {
+func NewRouter(conf config.Config, logger *slog.Logger, pg *pgxpool.Pool, redis *redis.Client, otpSender authdomain.OTPSender, keys *jwtkeys.KeySet, limiter ratelimit.Limiter, auditLog auditdomain.Recorder) http.Handler {
 	r := chi.NewRouter()
+	r.Use(middleware.RequestID(logger))
+	r.Use(middleware.ClientInfo(conf.TrustedProxies, conf.OTPQuota.DeviceHeader))
+	r.Use(middleware.AccessLog)
+	r.Use(middleware.Metrics)
 	r.Use(cors.AllowAll().Handler)
 
 	userRepo := postgresrepositories.NewPostgresUserRepository(pg)
@@ -31,22 +44,98 @@ func NewRouter(conf config.Config, pg *pgxpool.Pool, redis *redis.Client) http.H
 	cacheStore := cache.NewRedisStore(redis)
 	var _ userdomain.CacheStore = cacheStore
 
-	authUsecase := authusecase.New(userRepo, cacheStore, conf)
-	authHandler := handlers.NewAuthHandler(authUsecase)
+	refreshTokenRepo := postgresrepositories.NewPostgresRefreshTokenRepository(pg)
+	var _ authdomain.RefreshTokenRepository = refreshTokenRepo
 
-	userUsecase := userusecase.New(userRepo)
-	userHandler := handlers.NewUserHandler(userUsecase)
+	sessionRepo := postgresrepositories.NewPostgresSessionRepository(pg)
+	var _ authdomain.SessionRepository = sessionRepo
+
+	auditRepo := postgresrepositories.NewPostgresAuditRepository(pg)
+	var _ auditdomain.Repository = auditRepo
+
+	var _ authdomain.TokenKeys = keys
+	authUsecase := authusecase.New(userRepo, refreshTokenRepo, sessionRepo, cacheStore, otpSender, keys, conf, auditLog)
+	authHandler := handlers.NewAuthHandler(authUsecase, conf.PhoneDefaultRegion)
+
+	adminHandler := handlers.NewAdminHandler(authUsecase)
+
+	userUsecase := userusecase.New(userRepo, conf.AccountDeletionGrace, conf.CursorSecret, conf.AuditPhoneKey, auditLog)
+	userHandler := handlers.NewUserHandler(userUsecase, conf.PhoneDefaultRegion)
+
+	auditHandler := handlers.NewAuditHandler(auditusecase.New(auditRepo, conf.AuditPhoneKey))
+
+	webhookRepo := postgresrepositories.NewPostgresWebhookRepository(pg)
+	var _ webhookdomain.Repository = webhookRepo
+	webhookSender := webhookinfra.NewHTTPSender(conf.Webhook.Timeout, webhookdomain.NewAddressPolicy(conf.Webhook.AllowedNetworks))
+	webhookUsecase := webhookusecase.New(webhookRepo, webhookSender, conf.Webhook)
+	webhookHandler := handlers.NewWebhookHandler(webhookUsecase)
+
+	guard := func(name string) middleware.RateLimiter {
+		return ratelimit.WithFailurePolicy(conf.RateLimit, name, limiter)
+	}
+	q := conf.OTPQuota
+	otpQuotas := []middleware.OTPQuota{
+		middleware.PhoneQuota(guard("otp_send"), conf.OTPPolicy.MaxSends, conf.OTPPolicy.SendWindow),
+		middleware.DeviceQuota(guard("otp_send_device"), q.Device.Max, q.Device.Window),
+		middleware.IPQuota(guard("otp_send_ip"), q.IP.Max, q.IP.Window),
+		middleware.PrefixQuota(guard("otp_send_prefix"), q.PrefixDigits, q.Prefix.Max, q.Prefix.Window),
+		middleware.CountryQuota(guard("otp_send_country"), q.Country.Max, q.Country.Window),
+		middleware.GlobalQuota(guard("otp_send_global"), q.Global.Max, q.Global.Window),
+	}
+	otpSendQuota := middleware.OTPSendQuota(conf.PhoneDefaultRegion, conf.OTPPolicy.CountryAllowed, otpQuotas...)
+	// A phone change can also text the current number, which then pays into
+	// its own per-phone quota.
+	phoneChangeQuota := otpSendQuota
+	if conf.PhoneChangeConfirmOld {
+		phoneChangeQuota = middleware.OTPSendQuota(conf.PhoneDefaultRegion, conf.OTPPolicy.CountryAllowed,
+			append(otpQuotas, middleware.CallerPhoneQuota(guard("otp_send"), conf.OTPPolicy.MaxSends, conf.OTPPolicy.SendWindow))...)
+	}
+
+	jwksHandler := handlers.NewJWKSHandler(keys)
+	r.Get("/.well-known/jwks.json", jwksHandler.Get)
+
+	r.With(middleware.ScrapeToken(conf.MetricsToken)).Get("/metrics", metrics.Handler().ServeHTTP)
 
 	r.Route("/api", func(api chi.Router) {
 		api.Route("/auth", func(auth chi.Router) {
-			auth.With(middleware.OTPRateLimit(cacheStore, 3, 10*time.Minute)).Post("/request-otp", authHandler.RequestOTP)
+			auth.With(otpSendQuota).Post("/request-otp", authHandler.RequestOTP)
 			auth.Post("/verify-otp", authHandler.VerifyOTP)
+			auth.Post("/refresh", authHandler.Refresh)
+			auth.With(middleware.JwtAuth(authUsecase)).Post("/logout", authHandler.Logout)
+			auth.With(middleware.JwtAuth(authUsecase)).Post("/logout-all", authHandler.LogoutAll)
 		})
 		api.Route("/users", func(users chi.Router) {
-			users.Use(middleware.JwtAuth(conf.JWTSecret))
-			users.Get("/", userHandler.List)
+			users.Use(middleware.JwtAuth(authUsecase))
+			users.With(middleware.RequirePermission(authdomain.PermUsersList)).Get("/", userHandler.List)
+			users.Get("/me", userHandler.Me)
+			users.Patch("/me", userHandler.UpdateMe)
+			users.Delete("/me", authHandler.DeleteAccount)
+			users.Get("/me/export", userHandler.Export)
+			users.With(phoneChangeQuota).Post("/me/phone", authHandler.StartPhoneChange)
+			users.Post("/me/phone/verify", authHandler.ConfirmPhoneChange)
+			users.Get("/me/sessions", authHandler.ListSessions)
+			users.Delete("/me/sessions/{id}", authHandler.RevokeSession)
 			users.Get("/{id}", userHandler.GetByID)
 		})
+		api.Route("/admin/users", func(admin chi.Router) {
+			admin.Use(middleware.JwtAuth(authUsecase))
+			admin.With(middleware.RequirePermission(authdomain.PermUsersExport)).Get("/export", userHandler.ExportUsers)
+			admin.Route("/{id}", func(user chi.Router) {
+				user.With(middleware.RequirePermission(authdomain.PermUsersModerate)).Post("/suspend", adminHandler.Suspend)
+				user.With(middleware.RequirePermission(authdomain.PermUsersModerate)).Post("/logout", adminHandler.ForceLogout)
+				user.With(middleware.RequirePermission(authdomain.PermUsersBan)).Post("/ban", adminHandler.Ban)
+				user.With(middleware.RequirePermission(authdomain.PermUsersBan)).Post("/unban", adminHandler.Unban)
+			})
+		})
+		api.With(middleware.JwtAuth(authUsecase), middleware.RequirePermission(authdomain.PermAuditRead)).Get("/admin/audit-events", auditHandler.List)
+		api.Route("/admin/webhooks", func(webhooks chi.Router) {
+			webhooks.Use(middleware.JwtAuth(authUsecase), middleware.RequirePermission(authdomain.PermWebhooksManage))
+			webhooks.Get("/", webhookHandler.List)
+			webhooks.Post("/", webhookHandler.Create)
+			webhooks.Delete("/{id}", webhookHandler.Delete)
+			webhooks.Get("/{id}/deliveries", webhookHandler.ListDeliveries)
+			webhooks.Post("/{id}/deliveries/{deliveryID}/redeliver", webhookHandler.Redeliver)
+		})
 	})
 
 	r.Get("/openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
@@ -0,0 +1,120 @@
+package auditlog
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"dekamond/internal/config"
+	auditdomain "dekamond/internal/domain/audit"
+	"dekamond/internal/metrics"
+)
+
+// insertTimeout bounds one batch insert so a stuck database can't hold the
+// buffer forever.
+const insertTimeout = 5 * time.Second
+
+// Writer is an audit.Recorder that queues events in memory and inserts them
+// in batches from a background goroutine. Record never waits: when the
+// buffer is full the event is dropped.
+type Writer struct {
+	repo       auditdomain.Repository
+	events     chan auditdomain.Event
+	batchSize  int
+	flushEvery time.Duration
+	stop       chan struct{}
+	done       chan struct{}
+	stopOnce   sync.Once
+}
+
+func NewWriter(repo auditdomain.Repository, conf config.AuditConfig) *Writer {
+	w := &Writer{
+		repo:       repo,
+		events:     make(chan auditdomain.Event, max(conf.BufferSize, 1)),
+		batchSize:  max(conf.BatchSize, 1),
+		flushEvery: conf.FlushInterval,
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+	if w.flushEvery <= 0 {
+		w.flushEvery = time.Second
+	}
+	go w.run()
+	return w
+}
+
+func (w *Writer) Record(ctx context.Context, e auditdomain.Event) {
+	e = auditdomain.FromContext(ctx, e)
+	select {
+	case <-w.stop:
+		metrics.AuditEvents.WithLabelValues(metrics.AuditDropped).Inc()
+		return
+	default:
+	}
+	select {
+	case w.events <- e:
+	default:
+		metrics.AuditEvents.WithLabelValues(metrics.AuditDropped).Inc()
+	}
+}
+
+// Close writes out the queued events and stops the writer. Events recorded
+// afterwards are dropped.
+func (w *Writer) Close(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stop) })
+	select {
+	case <-w.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (w *Writer) run() {
+	defer close(w.done)
+	ticker := time.NewTicker(w.flushEvery)
+	defer ticker.Stop()
+
+	batch := make([]auditdomain.Event, 0, w.batchSize)
+	add := func(e auditdomain.Event) {
+		batch = append(batch, e)
+		if len(batch) >= w.batchSize {
+			w.flush(batch)
+			batch = batch[:0]
+		}
+	}
+	for {
+		select {
+		case e := <-w.events:
+			add(e)
+		case <-ticker.C:
+			w.flush(batch)
+			batch = batch[:0]
+		case <-w.stop:
+			for {
+				select {
+				case e := <-w.events:
+					add(e)
+				default:
+					w.flush(batch)
+					return
+				}
+			}
+		}
+	}
+}
+
+func (w *Writer) flush(batch []auditdomain.Event) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), insertTimeout)
+	defer cancel()
+	if err := w.repo.Insert(ctx, batch); err != nil {
+		metrics.AuditEvents.WithLabelValues(metrics.AuditFailed).Add(float64(len(batch)))
+		slog.Error("audit log write failed", "events", len(batch), "err", err)
+		return
+	}
+	metrics.AuditEvents.WithLabelValues(metrics.AuditWritten).Add(float64(len(batch)))
+}
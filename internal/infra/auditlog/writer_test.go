@@ -0,0 +1,176 @@
+package auditlog
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"dekamond/internal/config"
+	auditdomain "dekamond/internal/domain/audit"
+	authdomain "dekamond/internal/domain/auth"
+	"dekamond/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type mockAuditRepository struct {
+	mu      sync.Mutex
+	batches [][]auditdomain.Event
+	block   chan struct{}
+	err     error
+}
+
+func (m *mockAuditRepository) Insert(ctx context.Context, events []auditdomain.Event) error {
+	if m.block != nil {
+		<-m.block
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.err != nil {
+		return m.err
+	}
+	m.batches = append(m.batches, append([]auditdomain.Event(nil), events...))
+	return nil
+}
+
+func (m *mockAuditRepository) List(ctx context.Context, f auditdomain.Filter) ([]auditdomain.Event, error) {
+	return nil, nil
+}
+
+func (m *mockAuditRepository) events() []auditdomain.Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []auditdomain.Event
+	for _, b := range m.batches {
+		out = append(out, b...)
+	}
+	return out
+}
+
+func stat(result string) int64 {
+	return int64(testutil.ToFloat64(metrics.AuditEvents.WithLabelValues(result)))
+}
+
+func TestWriterRecordsRequestDetails(t *testing.T) {
+	repo := &mockAuditRepository{}
+	w := NewWriter(repo, config.AuditConfig{BufferSize: 10, BatchSize: 10, FlushInterval: time.Hour})
+
+	ctx := authdomain.WithClientInfo(context.Background(), authdomain.ClientInfo{IP: "203.0.113.7", UserAgent: "curl/8", RequestID: "req-1"})
+	ctx = authdomain.WithPrincipal(ctx, &authdomain.Principal{UserID: "admin-1"})
+	w.Record(ctx, auditdomain.Event{Action: auditdomain.ActionUserViewed, Target: "user-1", Outcome: auditdomain.OutcomeSuccess})
+	w.Record(context.Background(), auditdomain.Event{ActorID: "user-2", Action: auditdomain.ActionLogin, Outcome: auditdomain.OutcomeFailure})
+
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	got := repo.events()
+	if len(got) != 2 {
+		t.Fatalf("wrote %d events, want 2", len(got))
+	}
+	e := got[0]
+	if e.ActorID != "admin-1" || e.IP != "203.0.113.7" || e.UserAgent != "curl/8" || e.RequestID != "req-1" {
+		t.Errorf("request details not recorded: %+v", e)
+	}
+	if e.OccurredAt.IsZero() {
+		t.Error("OccurredAt not set")
+	}
+	if got[1].ActorID != "user-2" {
+		t.Errorf("ActorID = %q, want the one given", got[1].ActorID)
+	}
+}
+
+func TestWriterBatches(t *testing.T) {
+	repo := &mockAuditRepository{}
+	w := NewWriter(repo, config.AuditConfig{BufferSize: 10, BatchSize: 2, FlushInterval: time.Hour})
+	for i := 0; i < 5; i++ {
+		w.Record(context.Background(), auditdomain.Event{Action: auditdomain.ActionOTPRequested})
+	}
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if len(repo.batches) != 3 {
+		t.Fatalf("wrote %d batches, want 3", len(repo.batches))
+	}
+	for _, b := range repo.batches {
+		if len(b) > 2 {
+			t.Errorf("batch of %d exceeds batch size", len(b))
+		}
+	}
+}
+
+func TestWriterFlushesOnInterval(t *testing.T) {
+	repo := &mockAuditRepository{}
+	w := NewWriter(repo, config.AuditConfig{BufferSize: 10, BatchSize: 100, FlushInterval: 10 * time.Millisecond})
+	defer w.Close(context.Background())
+
+	w.Record(context.Background(), auditdomain.Event{Action: auditdomain.ActionOTPRequested})
+	deadline := time.Now().Add(time.Second)
+	for len(repo.events()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("event not written before Close")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWriterDropsWhenFull(t *testing.T) {
+	repo := &mockAuditRepository{block: make(chan struct{})}
+	w := NewWriter(repo, config.AuditConfig{BufferSize: 2, BatchSize: 1, FlushInterval: time.Hour})
+	dropped := stat("dropped")
+
+	// The first event is taken by the writer and blocks in Insert; two more
+	// fill the buffer.
+	w.Record(context.Background(), auditdomain.Event{Action: auditdomain.ActionOTPRequested})
+	deadline := time.Now().Add(time.Second)
+	for len(w.events) != 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("writer did not pick up the first event")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 5; i++ {
+			w.Record(context.Background(), auditdomain.Event{Action: auditdomain.ActionOTPRequested})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Record blocked on a full buffer")
+	}
+	if n := stat("dropped") - dropped; n != 3 {
+		t.Errorf("dropped %d events, want 3", n)
+	}
+
+	close(repo.block)
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if n := len(repo.events()); n != 3 {
+		t.Errorf("wrote %d events, want 3", n)
+	}
+	w.Record(context.Background(), auditdomain.Event{Action: auditdomain.ActionOTPRequested})
+	if n := stat("dropped") - dropped; n != 4 {
+		t.Errorf("event recorded after Close was not dropped")
+	}
+}
+
+func TestWriterCountsFailedInserts(t *testing.T) {
+	repo := &mockAuditRepository{err: errors.New("db down")}
+	w := NewWriter(repo, config.AuditConfig{BufferSize: 10, BatchSize: 10, FlushInterval: time.Hour})
+	failed := stat("failed")
+
+	w.Record(context.Background(), auditdomain.Event{Action: auditdomain.ActionOTPRequested})
+	w.Record(context.Background(), auditdomain.Event{Action: auditdomain.ActionOTPRequested})
+	if err := w.Close(context.Background()); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if n := stat("failed") - failed; n != 2 {
+		t.Errorf("failed = %d, want 2", n)
+	}
+}
@@ -0,0 +1,38 @@
+package cache
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+var (
+	poolHits       = prometheus.NewDesc("redis_pool_hits_total", "Times a free connection was found in the pool.", nil, nil)
+	poolMisses     = prometheus.NewDesc("redis_pool_misses_total", "Times no free connection was found in the pool.", nil, nil)
+	poolTimeouts   = prometheus.NewDesc("redis_pool_timeouts_total", "Times waiting for a connection timed out.", nil, nil)
+	poolTotalConns = prometheus.NewDesc("redis_pool_total_conns", "Connections in the pool.", nil, nil)
+	poolIdleConns  = prometheus.NewDesc("redis_pool_idle_conns", "Idle connections in the pool.", nil, nil)
+	poolStaleConns = prometheus.NewDesc("redis_pool_stale_conns_total", "Stale connections removed from the pool.", nil, nil)
+)
+
+// PoolCollector exports go-redis pool statistics, read at scrape time.
+type PoolCollector struct {
+	client *redis.Client
+}
+
+func NewPoolCollector(client *redis.Client) *PoolCollector {
+	return &PoolCollector{client: client}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(poolHits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(poolMisses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(poolTimeouts, prometheus.CounterValue, float64(s.Timeouts))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(poolStaleConns, prometheus.CounterValue, float64(s.StaleConns))
+}
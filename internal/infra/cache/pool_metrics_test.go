@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestPoolCollector(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	if err := client.Ping(context.Background()).Err(); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+
+	want := `
+# HELP redis_pool_total_conns Connections in the pool.
+# TYPE redis_pool_total_conns gauge
+redis_pool_total_conns 1
+# HELP redis_pool_idle_conns Idle connections in the pool.
+# TYPE redis_pool_idle_conns gauge
+redis_pool_idle_conns 1
+`
+	if err := testutil.CollectAndCompare(NewPoolCollector(client), strings.NewReader(want),
+		"redis_pool_total_conns", "redis_pool_idle_conns"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(NewPoolCollector(client)); n != 6 {
+		t.Errorf("metrics = %d, want 6", n)
+	}
+}
@@ -2,8 +2,11 @@ package cache
 
 import (
 	"context"
+	"errors"
 	"time"
 
+	userdomain "dekamond/internal/domain/user"
+
 	"github.com/redis/go-redis/v9"
 )
 
@@ -20,17 +23,52 @@ func (s *RedisStore) Set(ctx context.Context, key string, value string, ttl time
 }
 
 func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
-	return s.redis.Get(ctx, key).Result()
+	val, err := s.redis.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", userdomain.ErrNotFound
+	}
+	return val, err
 }
 
 func (s *RedisStore) Delete(ctx context.Context, key string) error {
 	return s.redis.Del(ctx, key).Err()
 }
 
-func (s *RedisStore) Increment(ctx context.Context, key string) (int64, error) {
-	return s.redis.Incr(ctx, key).Result()
+var increment = redis.NewScript(`
+local n = redis.call('INCR', KEYS[1])
+if redis.call('PTTL', KEYS[1]) == -1 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return n
+`)
+
+func (s *RedisStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	return increment.Run(ctx, s.redis, []string{key}, ttl.Milliseconds()).Int64()
+}
+
+var incrementIfExists = redis.NewScript(`
+local ttl = redis.call('PTTL', KEYS[1])
+if ttl == -2 then
+	return -1
+end
+local n = redis.call('INCR', KEYS[2])
+if ttl > 0 then
+	redis.call('PEXPIRE', KEYS[2], ttl)
+end
+return n
+`)
+
+func (s *RedisStore) IncrementIfExists(ctx context.Context, key, counterKey string) (int64, error) {
+	n, err := incrementIfExists.Run(ctx, s.redis, []string{key, counterKey}).Int64()
+	if err != nil {
+		return 0, err
+	}
+	if n < 0 {
+		return 0, userdomain.ErrNotFound
+	}
+	return n, nil
 }
 
 func (s *RedisStore) SetExpiry(ctx context.Context, key string, ttl time.Duration) error {
 	return s.redis.Expire(ctx, key, ttl).Err()
-}
\ No newline at end of file
+}
@@ -0,0 +1,71 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestIncrementIfExists(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := NewRedisStore(client)
+
+	if _, err := store.IncrementIfExists(ctx, "otp:x", "otp:attempts:x"); !errors.Is(err, userdomain.ErrNotFound) {
+		t.Fatalf("missing key: err = %v, want %v", err, userdomain.ErrNotFound)
+	}
+	if mr.Exists("otp:attempts:x") {
+		t.Fatal("counter created for a missing key")
+	}
+
+	if err := store.Set(ctx, "otp:x", "record", time.Minute); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	for want := int64(1); want <= 3; want++ {
+		n, err := store.IncrementIfExists(ctx, "otp:x", "otp:attempts:x")
+		if err != nil || n != want {
+			t.Fatalf("IncrementIfExists = %d, %v, want %d", n, err, want)
+		}
+	}
+	if ttl := mr.TTL("otp:attempts:x"); ttl <= 0 || ttl > time.Minute {
+		t.Errorf("counter TTL = %s, want the key's", ttl)
+	}
+	if got, _ := mr.Get("otp:x"); got != "record" {
+		t.Errorf("key = %q, want it untouched", got)
+	}
+}
+
+func TestIncrement(t *testing.T) {
+	ctx := context.Background()
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+	store := NewRedisStore(client)
+
+	for want := int64(1); want <= 2; want++ {
+		n, err := store.Increment(ctx, "otp:ipfail:x", time.Minute)
+		if err != nil || n != want {
+			t.Fatalf("Increment = %d, %v, want %d", n, err, want)
+		}
+	}
+	if ttl := mr.TTL("otp:ipfail:x"); ttl != time.Minute {
+		t.Errorf("TTL = %s, want 1m from the first increment", ttl)
+	}
+
+	// A counter left without an expiry gets one on its next increment.
+	mr.Set("otp:lockcount:x", "4")
+	if n, err := store.Increment(ctx, "otp:lockcount:x", time.Hour); err != nil || n != 5 {
+		t.Fatalf("Increment = %d, %v, want 5", n, err)
+	}
+	if ttl := mr.TTL("otp:lockcount:x"); ttl != time.Hour {
+		t.Errorf("TTL = %s, want 1h", ttl)
+	}
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS user_roles (
+  user_id     uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  role        varchar(32) NOT NULL CHECK (role IN ('user', 'support', 'admin')),
+  granted_at  timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (user_id, role)
+);
+
+INSERT INTO user_roles(user_id, role)
+SELECT id, 'user' FROM users
+ON CONFLICT DO NOTHING;
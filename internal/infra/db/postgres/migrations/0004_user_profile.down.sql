@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_users_email;
+
+ALTER TABLE users
+  DROP COLUMN IF EXISTS display_name,
+  DROP COLUMN IF EXISTS email,
+  DROP COLUMN IF EXISTS avatar_url,
+  DROP COLUMN IF EXISTS locale,
+  DROP COLUMN IF EXISTS timezone,
+  DROP COLUMN IF EXISTS updated_at,
+  DROP COLUMN IF EXISTS version;
@@ -0,0 +1,12 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS display_name  varchar(64),
+  ADD COLUMN IF NOT EXISTS email         varchar(254),
+  ADD COLUMN IF NOT EXISTS avatar_url    varchar(2048),
+  ADD COLUMN IF NOT EXISTS locale        varchar(35),
+  ADD COLUMN IF NOT EXISTS timezone      varchar(64),
+  ADD COLUMN IF NOT EXISTS updated_at    timestamptz NOT NULL DEFAULT now(),
+  ADD COLUMN IF NOT EXISTS version       bigint NOT NULL DEFAULT 1;
+
+UPDATE users SET updated_at = created_at;
+
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(lower(email)) WHERE email IS NOT NULL;
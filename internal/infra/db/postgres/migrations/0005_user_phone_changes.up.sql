@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS user_phone_changes (
+  id          uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+  user_id     uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  old_phone   varchar(32) NOT NULL,
+  new_phone   varchar(32) NOT NULL,
+  ip          varchar(45),
+  user_agent  varchar(512),
+  changed_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_phone_changes_user ON user_phone_changes(user_id, changed_at);
@@ -0,0 +1,7 @@
+DELETE FROM users WHERE deleted_at IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_users_phone_live;
+ALTER TABLE users ADD CONSTRAINT users_phone_key UNIQUE (phone);
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
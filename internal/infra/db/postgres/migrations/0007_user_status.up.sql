@@ -0,0 +1,9 @@
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS status             varchar(16) NOT NULL DEFAULT 'active'
+                                              CHECK (status IN ('active', 'suspended', 'banned')),
+  ADD COLUMN IF NOT EXISTS status_reason      varchar(500),
+  ADD COLUMN IF NOT EXISTS status_until       timestamptz,
+  ADD COLUMN IF NOT EXISTS status_changed_at  timestamptz,
+  ADD COLUMN IF NOT EXISTS status_changed_by  uuid REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_users_status ON users(status) WHERE status <> 'active';
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_user_roles_role;
+DROP INDEX IF EXISTS idx_users_created_at;
+DROP INDEX IF EXISTS idx_users_phone_prefix;
+DROP INDEX IF EXISTS idx_users_phone_trgm;
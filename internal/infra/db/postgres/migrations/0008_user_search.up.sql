@@ -0,0 +1,9 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Substring search on phone (LIKE '%...%').
+CREATE INDEX IF NOT EXISTS idx_users_phone_trgm ON users USING gin (phone gin_trgm_ops) WHERE deleted_at IS NULL;
+-- Prefix search on phone (LIKE '...%').
+CREATE INDEX IF NOT EXISTS idx_users_phone_prefix ON users (phone text_pattern_ops) WHERE deleted_at IS NULL;
+-- Default listing order and created_after/created_before ranges.
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users (created_at, id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_user_roles_role ON user_roles (role, user_id);
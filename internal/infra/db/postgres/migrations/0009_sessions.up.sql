@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS sessions (
+  id            uuid PRIMARY KEY,
+  user_id       uuid NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+  device_name   varchar(64),
+  user_agent    varchar(512),
+  ip            varchar(45),
+  created_at    timestamptz NOT NULL DEFAULT now(),
+  last_seen_at  timestamptz NOT NULL DEFAULT now(),
+  expires_at    timestamptz NOT NULL,
+  revoked_at    timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_active ON sessions(user_id, last_seen_at DESC) WHERE revoked_at IS NULL;
+
+-- Logins from before sessions existed become sessions without device details.
+INSERT INTO sessions(id, user_id, created_at, last_seen_at, expires_at, revoked_at)
+SELECT family_id, user_id, min(created_at), max(created_at), max(expires_at),
+       CASE WHEN bool_and(revoked_at IS NOT NULL) THEN max(revoked_at) END
+FROM refresh_tokens
+GROUP BY family_id, user_id
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE refresh_tokens
+  ADD CONSTRAINT refresh_tokens_family_id_fkey FOREIGN KEY (family_id) REFERENCES sessions(id) ON DELETE CASCADE;
@@ -0,0 +1,27 @@
+-- actor_id and target keep no foreign keys so events outlive purged users.
+CREATE TABLE IF NOT EXISTS audit_events (
+  id           bigserial PRIMARY KEY,
+  occurred_at  timestamptz NOT NULL,
+  actor_id     uuid,
+  action       varchar(64) NOT NULL,
+  target       varchar(64),
+  ip           varchar(45),
+  user_agent   varchar(512),
+  request_id   varchar(64),
+  outcome      varchar(16) NOT NULL,
+  details      jsonb
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_occurred_at ON audit_events(occurred_at);
+CREATE INDEX IF NOT EXISTS idx_audit_events_actor ON audit_events(actor_id, id) WHERE actor_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_audit_events_target ON audit_events(target, id) WHERE target IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+  RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_append_only
+  BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_events
+  FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
@@ -0,0 +1,37 @@
+-- Written in the same transaction as the user change it describes. user_id
+-- is not a foreign key so events outlive purged users.
+CREATE TABLE IF NOT EXISTS outbox_events (
+  id             uuid PRIMARY KEY,
+  event_type     varchar(64) NOT NULL,
+  user_id        uuid NOT NULL,
+  data           jsonb NOT NULL,
+  occurred_at    timestamptz NOT NULL DEFAULT now(),
+  dispatched_at  timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_pending ON outbox_events(occurred_at) WHERE dispatched_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+  id          uuid PRIMARY KEY,
+  url         varchar(2048) NOT NULL,
+  secret      varchar(128) NOT NULL,
+  events      text[] NOT NULL DEFAULT '{}',
+  created_at  timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+  id               bigserial PRIMARY KEY,
+  endpoint_id      uuid NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+  event_id         uuid NOT NULL REFERENCES outbox_events(id) ON DELETE CASCADE,
+  status           varchar(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'delivered', 'dead')),
+  attempts         int NOT NULL DEFAULT 0,
+  next_attempt_at  timestamptz NOT NULL DEFAULT now(),
+  last_status      int,
+  last_error       varchar(500),
+  created_at       timestamptz NOT NULL DEFAULT now(),
+  delivered_at     timestamptz,
+  UNIQUE (endpoint_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint ON webhook_deliveries(endpoint_id, id);
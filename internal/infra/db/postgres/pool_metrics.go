@@ -0,0 +1,42 @@
+package postgres
+
+import (
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	poolAcquiredConns   = prometheus.NewDesc("pgxpool_acquired_conns", "Connections currently checked out of the pool.", nil, nil)
+	poolIdleConns       = prometheus.NewDesc("pgxpool_idle_conns", "Idle connections in the pool.", nil, nil)
+	poolTotalConns      = prometheus.NewDesc("pgxpool_total_conns", "Connections in the pool, including ones being opened.", nil, nil)
+	poolMaxConns        = prometheus.NewDesc("pgxpool_max_conns", "Maximum size of the pool.", nil, nil)
+	poolAcquires        = prometheus.NewDesc("pgxpool_acquires_total", "Successful connection acquires.", nil, nil)
+	poolAcquireDuration = prometheus.NewDesc("pgxpool_acquire_duration_seconds_total", "Time spent waiting for connections.", nil, nil)
+	poolEmptyAcquires   = prometheus.NewDesc("pgxpool_empty_acquires_total", "Acquires that had to wait because no idle connection was free.", nil, nil)
+	poolCanceledAcquire = prometheus.NewDesc("pgxpool_canceled_acquires_total", "Acquires given up because their context was canceled.", nil, nil)
+)
+
+// PoolCollector exports pgxpool statistics, read from the pool at scrape time.
+type PoolCollector struct {
+	pool *pgxpool.Pool
+}
+
+func NewPoolCollector(pool *pgxpool.Pool) *PoolCollector {
+	return &PoolCollector{pool: pool}
+}
+
+func (c *PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	prometheus.DescribeByCollect(c, ch)
+}
+
+func (c *PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(poolAcquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(poolIdleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(poolTotalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(poolMaxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquire, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
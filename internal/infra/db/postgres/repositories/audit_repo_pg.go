@@ -0,0 +1,87 @@
+package postgresrepositories
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	auditdomain "dekamond/internal/domain/audit"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresAuditRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresAuditRepository(pool *pgxpool.Pool) *PostgresAuditRepository {
+	return &PostgresAuditRepository{pool: pool}
+}
+
+func (r *PostgresAuditRepository) Insert(ctx context.Context, events []auditdomain.Event) error {
+	batch := &pgx.Batch{}
+	for _, e := range events {
+		var details map[string]string
+		if len(e.Details) > 0 {
+			details = e.Details
+		}
+		batch.Queue(`
+			INSERT INTO audit_events(occurred_at, actor_id, action, target, ip, user_agent, request_id, outcome, details)
+			VALUES($1, NULLIF($2, '')::uuid, $3, NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), $8, $9)`,
+			e.OccurredAt, e.ActorID, string(e.Action), truncate(e.Target, 64), truncate(e.IP, 45),
+			truncate(e.UserAgent, maxUserAgentLen), truncate(e.RequestID, 64), string(e.Outcome), details)
+	}
+	return r.pool.SendBatch(ctx, batch).Close()
+}
+
+func (r *PostgresAuditRepository) List(ctx context.Context, f auditdomain.Filter) ([]auditdomain.Event, error) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.ActorID != "" {
+		add("actor_id = $%d", f.ActorID)
+	}
+	if f.Action != "" {
+		add("action = $%d", string(f.Action))
+	}
+	if f.Target != "" {
+		add("target = $%d", f.Target)
+	}
+	if f.Outcome != "" {
+		add("outcome = $%d", string(f.Outcome))
+	}
+	if f.From != nil {
+		add("occurred_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("occurred_at < $%d", *f.To)
+	}
+	if f.BeforeID > 0 {
+		add("id < $%d", f.BeforeID)
+	}
+	where := ""
+	if len(conds) > 0 {
+		where = "WHERE " + strings.Join(conds, " AND ")
+	}
+	args = append(args, f.Limit)
+	rows, err := r.pool.Query(ctx, fmt.Sprintf(`
+		SELECT id, occurred_at, COALESCE(actor_id::text, ''), action, COALESCE(target, ''),
+			COALESCE(ip, ''), COALESCE(user_agent, ''), COALESCE(request_id, ''), outcome, details
+		FROM audit_events
+		%s
+		ORDER BY id DESC
+		LIMIT $%d`, where, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (auditdomain.Event, error) {
+		var e auditdomain.Event
+		err := row.Scan(&e.ID, &e.OccurredAt, &e.ActorID, &e.Action, &e.Target,
+			&e.IP, &e.UserAgent, &e.RequestID, &e.Outcome, &e.Details)
+		return e, err
+	})
+}
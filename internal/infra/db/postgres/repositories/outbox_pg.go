@@ -0,0 +1,24 @@
+package postgresrepositories
+
+import (
+	"context"
+
+	userdomain "dekamond/internal/domain/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// insertEvent adds a user event to the outbox as part of tx. The webhook
+// dispatcher picks it up once tx commits.
+func insertEvent(ctx context.Context, tx pgx.Tx, eventType userdomain.EventType, userID string, data map[string]any) error {
+	data["user_id"] = userID
+	_, err := tx.Exec(ctx, `
+		INSERT INTO outbox_events(id, event_type, user_id, data) VALUES($1, $2, $3, $4)`,
+		uuid.New().String(), string(eventType), userID, data)
+	return err
+}
+
+// outboxPhoneFields are the event data keys holding phone numbers, which
+// PurgeDeleted strips from a purged user's events.
+var outboxPhoneFields = []string{"phone", "old_phone", "new_phone"}
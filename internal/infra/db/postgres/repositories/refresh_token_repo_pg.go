@@ -0,0 +1,60 @@
+package postgresrepositories
+
+import (
+	"context"
+
+	authdomain "dekamond/internal/domain/auth"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresRefreshTokenRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresRefreshTokenRepository(pool *pgxpool.Pool) *PostgresRefreshTokenRepository {
+	return &PostgresRefreshTokenRepository{pool: pool}
+}
+
+func (r *PostgresRefreshTokenRepository) Create(ctx context.Context, t *authdomain.RefreshToken) error {
+	if t.ID == "" {
+		t.ID = uuid.New().String()
+	}
+	row := r.pool.QueryRow(ctx,
+		`INSERT INTO refresh_tokens(id, user_id, family_id, token_hash, expires_at) VALUES($1,$2,$3,$4,$5) RETURNING created_at`,
+		t.ID, t.UserID, t.FamilyID, t.TokenHash, t.ExpiresAt)
+	return row.Scan(&t.CreatedAt)
+}
+
+func (r *PostgresRefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*authdomain.RefreshToken, error) {
+	row := r.pool.QueryRow(ctx,
+		`SELECT id, user_id, family_id, token_hash, expires_at, created_at, used_at, revoked_at FROM refresh_tokens WHERE token_hash=$1`,
+		hash)
+	var t authdomain.RefreshToken
+	if err := row.Scan(&t.ID, &t.UserID, &t.FamilyID, &t.TokenHash, &t.ExpiresAt, &t.CreatedAt, &t.UsedAt, &t.RevokedAt); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (r *PostgresRefreshTokenRepository) MarkUsed(ctx context.Context, id string) (bool, error) {
+	tag, err := r.pool.Exec(ctx,
+		`UPDATE refresh_tokens SET used_at = now() WHERE id=$1 AND used_at IS NULL AND revoked_at IS NULL`, id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+func (r *PostgresRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE refresh_tokens SET revoked_at = now() WHERE family_id=$1 AND revoked_at IS NULL`, familyID)
+	return err
+}
+
+func (r *PostgresRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE refresh_tokens SET revoked_at = now() WHERE user_id=$1 AND revoked_at IS NULL`, userID)
+	return err
+}
@@ -0,0 +1,99 @@
+package postgresrepositories
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type PostgresSessionRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresSessionRepository(pool *pgxpool.Pool) *PostgresSessionRepository {
+	return &PostgresSessionRepository{pool: pool}
+}
+
+func (r *PostgresSessionRepository) Create(ctx context.Context, s *authdomain.Session) error {
+	if s.ID == "" {
+		s.ID = uuid.New().String()
+	}
+	s.UserAgent = truncate(s.UserAgent, maxUserAgentLen)
+	// A new session is a login, so it is published as one.
+	return pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			INSERT INTO sessions(id, user_id, device_name, user_agent, ip, expires_at)
+			VALUES($1, $2, NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), $6)
+			RETURNING created_at, last_seen_at`,
+			s.ID, s.UserID, s.DeviceName, s.UserAgent, s.IP, s.ExpiresAt).Scan(&s.CreatedAt, &s.LastSeenAt)
+		if err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, userdomain.EventLoggedIn, s.UserID, map[string]any{
+			"session_id":  s.ID,
+			"device_name": s.DeviceName,
+		})
+	})
+}
+
+func (r *PostgresSessionRepository) Touch(ctx context.Context, id, ip, userAgent string, expiresAt time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE sessions SET
+			last_seen_at = now(), expires_at = $2,
+			ip = COALESCE(NULLIF($3, ''), ip), user_agent = COALESCE(NULLIF($4, ''), user_agent)
+		WHERE id = $1 AND revoked_at IS NULL`,
+		id, expiresAt, ip, truncate(userAgent, maxUserAgentLen))
+	return err
+}
+
+func (r *PostgresSessionRepository) ListActive(ctx context.Context, userID string) ([]authdomain.Session, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT id, user_id, COALESCE(device_name, ''), COALESCE(user_agent, ''), COALESCE(ip, ''),
+			created_at, last_seen_at, expires_at
+		FROM sessions
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > now()
+		ORDER BY last_seen_at DESC`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (authdomain.Session, error) {
+		var s authdomain.Session
+		err := row.Scan(&s.ID, &s.UserID, &s.DeviceName, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt)
+		return s, err
+	})
+}
+
+func (r *PostgresSessionRepository) Revoke(ctx context.Context, userID, id string) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE sessions SET revoked_at = now()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL AND expires_at > now()`, id, userID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+func (r *PostgresSessionRepository) RevokeAllForUser(ctx context.Context, userID string) error {
+	_, err := r.pool.Exec(ctx,
+		`UPDATE sessions SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	return err
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence,
+// which Postgres would reject.
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return strings.ToValidUTF8(s[:n], "")
+}
@@ -2,10 +2,18 @@ package postgresrepositories
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	userdomain "dekamond/internal/domain/user"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
@@ -17,52 +25,400 @@ func NewPostgresUserRepository(pool *pgxpool.Pool) *PostgresUserRepository {
 	return &PostgresUserRepository{pool: pool}
 }
 
-func (r *PostgresUserRepository) GetByPhone(ctx context.Context, phone string) (*userdomain.User, error) {
-	row := r.pool.QueryRow(ctx, `SELECT id, phone, created_at FROM users WHERE phone=$1`, phone)
+const userColumns = `id, phone, created_at, updated_at, version,
+	COALESCE(display_name, ''), COALESCE(email, ''), COALESCE(avatar_url, ''),
+	COALESCE(locale, ''), COALESCE(timezone, ''),
+	status, COALESCE(status_reason, ''), status_until,
+	ARRAY(SELECT role FROM user_roles ur WHERE ur.user_id = users.id ORDER BY role)`
+
+func scanUser(row pgx.Row) (*userdomain.User, error) {
 	var u userdomain.User
-	if err := row.Scan(&u.ID, &u.Phone, &u.CreatedAt); err != nil {
+	if err := row.Scan(&u.ID, &u.Phone, &u.CreatedAt, &u.UpdatedAt, &u.Version,
+		&u.DisplayName, &u.Email, &u.AvatarURL, &u.Locale, &u.Timezone,
+		&u.Status, &u.StatusReason, &u.StatusUntil, &u.Roles); err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
+func (r *PostgresUserRepository) GetByPhone(ctx context.Context, phone string) (*userdomain.User, error) {
+	return scanUser(r.pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE phone=$1 AND deleted_at IS NULL`, phone))
+}
+
+// Create inserts the user together with the default "user" role.
 func (r *PostgresUserRepository) Create(ctx context.Context, phone string) (*userdomain.User, error) {
 	id := uuid.New().String()
-	row := r.pool.QueryRow(ctx, `INSERT INTO users(id, phone) VALUES($1,$2) RETURNING id, phone, created_at`, id, phone)
-	var u userdomain.User
-	if err := row.Scan(&u.ID, &u.Phone, &u.CreatedAt); err != nil {
+	var u *userdomain.User
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		u, err = scanUser(tx.QueryRow(ctx, `
+			WITH u AS (
+				INSERT INTO users(id, phone) VALUES($1,$2) RETURNING id, phone, created_at, updated_at, version, status
+			), r AS (
+				INSERT INTO user_roles(user_id, role) SELECT id, 'user' FROM u RETURNING role
+			)
+			SELECT u.id, u.phone, u.created_at, u.updated_at, u.version, '', '', '', '', '',
+				u.status, '', NULL::timestamptz, ARRAY(SELECT role FROM r) FROM u`, id, phone))
+		if err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, userdomain.EventCreated, id, map[string]any{"phone": phone})
+	})
+	if err != nil {
 		return nil, err
 	}
-	return &u, nil
+	return u, nil
 }
 
 func (r *PostgresUserRepository) GetByID(ctx context.Context, id string) (*userdomain.User, error) {
-	row := r.pool.QueryRow(ctx, `SELECT id, phone, created_at FROM users WHERE id=$1`, id)
-	var u userdomain.User
-	if err := row.Scan(&u.ID, &u.Phone, &u.CreatedAt); err != nil {
-		return nil, err
+	return scanUser(r.pool.QueryRow(ctx, `SELECT `+userColumns+` FROM users WHERE id=$1 AND deleted_at IS NULL`, id))
+}
+
+// sortColumns maps each sort field to its SQL expression and the type its
+// keyset value is cast to.
+var sortColumns = map[userdomain.SortField]struct{ expr, typ string }{
+	userdomain.SortCreatedAt:   {"created_at", "timestamptz"},
+	userdomain.SortUpdatedAt:   {"updated_at", "timestamptz"},
+	userdomain.SortPhone:       {"phone", "text"},
+	userdomain.SortDisplayName: {"COALESCE(display_name, '')", "text"},
+}
+
+// listWhere builds the WHERE clause for f. Values are always passed as
+// arguments; only fixed SQL fragments are concatenated.
+func listWhere(f userdomain.ListFilter) (string, []any) {
+	conds := []string{"deleted_at IS NULL"}
+	var args []any
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
 	}
-	return &u, nil
+	if f.Phone != "" {
+		conds = append(conds, "phone = "+arg(f.Phone))
+	}
+	if f.PhonePrefix != "" {
+		conds = append(conds, "phone LIKE "+arg(likeEscape(f.PhonePrefix)+"%"))
+	}
+	if f.PhoneContains != "" {
+		conds = append(conds, "phone LIKE "+arg("%"+likeEscape(f.PhoneContains)+"%"))
+	}
+	if f.CreatedAfter != nil {
+		conds = append(conds, "created_at >= "+arg(*f.CreatedAfter))
+	}
+	if f.CreatedBefore != nil {
+		conds = append(conds, "created_at < "+arg(*f.CreatedBefore))
+	}
+	switch f.Status {
+	case "":
+	case userdomain.StatusActive:
+		conds = append(conds, "(status = 'active' OR status_until <= now())")
+	default:
+		conds = append(conds, "status = "+arg(string(f.Status))+" AND (status_until IS NULL OR status_until > now())")
+	}
+	if f.Role != "" {
+		conds = append(conds, "EXISTS (SELECT 1 FROM user_roles ur WHERE ur.user_id = users.id AND ur.role = "+arg(f.Role)+")")
+	}
+	return strings.Join(conds, " AND "), args
+}
+
+func likeEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
 }
 
-func (r *PostgresUserRepository) List(ctx context.Context, phone string, limit, offset int) ([]userdomain.User, int, error) {
-	q := `SELECT id, phone, created_at FROM users WHERE ($1 = '' OR phone = $1) ORDER BY created_at DESC LIMIT $2 OFFSET $3`
-	rows, err := r.pool.Query(ctx, q, phone, limit, offset)
+// listQuery builds the SELECT for f. With f.Before it reads backwards from
+// the keyset, so callers must reverse the rows. A zero Limit reads every row.
+func listQuery(f userdomain.ListFilter) (string, []any) {
+	where, args := listWhere(f)
+	col, ok := sortColumns[f.Sort]
+	if !ok {
+		col = sortColumns[userdomain.SortCreatedAt]
+	}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+	keyset := func(k *userdomain.Keyset, op string) string {
+		return fmt.Sprintf(" AND (%s, id) %s (%s::%s, %s::uuid)", col.expr, op, arg(k.Value), col.typ, arg(k.ID))
+	}
+	later, earlier := ">", "<"
+	if f.Desc {
+		later, earlier = "<", ">"
+	}
+	desc := f.Desc
+	if f.After != nil {
+		where += keyset(f.After, later)
+	}
+	if f.Before != nil {
+		where += keyset(f.Before, earlier)
+		desc = !desc
+	}
+	dir := "ASC"
+	if desc {
+		dir = "DESC"
+	}
+	q := fmt.Sprintf(`SELECT %s FROM users WHERE %s ORDER BY %s %s, id %s`, userColumns, where, col.expr, dir, dir)
+	if f.Limit > 0 {
+		q += fmt.Sprintf(` LIMIT %s OFFSET %s`, arg(f.Limit), arg(f.Offset))
+	}
+	return q, args
+}
+
+// List returns users in f's order. With f.Before the page nearest the keyset
+// is returned.
+func (r *PostgresUserRepository) List(ctx context.Context, f userdomain.ListFilter) ([]userdomain.User, error) {
+	q, args := listQuery(f)
+	rows, err := r.pool.Query(ctx, q, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 	defer rows.Close()
 	users := make([]userdomain.User, 0)
 	for rows.Next() {
-		var u userdomain.User
-		if err := rows.Scan(&u.ID, &u.Phone, &u.CreatedAt); err != nil {
-			return nil, 0, err
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *u)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if f.Before != nil {
+		slices.Reverse(users)
+	}
+	return users, nil
+}
+
+const streamBatchSize = 500
+
+// Stream reads the users through a server-side cursor in a read-only snapshot,
+// so memory stays bounded and the next batch is only fetched once fn has
+// consumed the previous one.
+func (r *PostgresUserRepository) Stream(ctx context.Context, f userdomain.ListFilter, fn func(userdomain.User) error) error {
+	f.After, f.Before, f.Limit, f.Offset = nil, nil, 0, 0
+	q, args := listQuery(f)
+	opts := pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly}
+	return pgx.BeginTxFunc(ctx, r.pool, opts, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, `DECLARE users_stream NO SCROLL CURSOR FOR `+q, args...); err != nil {
+			return err
+		}
+		fetch := fmt.Sprintf(`FETCH FORWARD %d FROM users_stream`, streamBatchSize)
+		for {
+			rows, err := tx.Query(ctx, fetch)
+			if err != nil {
+				return err
+			}
+			batch, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (userdomain.User, error) {
+				u, err := scanUser(row)
+				if err != nil {
+					return userdomain.User{}, err
+				}
+				return *u, nil
+			})
+			if err != nil {
+				return err
+			}
+			for _, u := range batch {
+				if err := fn(u); err != nil {
+					return err
+				}
+			}
+			if len(batch) < streamBatchSize {
+				return nil
+			}
+		}
+	})
+}
+
+// Count estimates from the planner's row estimate for the filtered query,
+// which leaves out soft-deleted rows, falling back to an exact count while
+// the table has never been analyzed.
+func (r *PostgresUserRepository) Count(ctx context.Context, f userdomain.ListFilter, exact bool) (int, error) {
+	f.After, f.Before = nil, nil
+	where, args := listWhere(f)
+	if !exact {
+		n, err := r.estimateCount(ctx, where, args)
+		if err != nil || n >= 0 {
+			return n, err
 		}
-		users = append(users, u)
 	}
 	var total int
-	if err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE ($1 = '' OR phone = $1)`, phone).Scan(&total); err != nil {
-		return nil, 0, err
+	err := r.pool.QueryRow(ctx, `SELECT COUNT(*) FROM users WHERE `+where, args...).Scan(&total)
+	return total, err
+}
+
+// estimateCount returns -1 when no estimate is available.
+func (r *PostgresUserRepository) estimateCount(ctx context.Context, where string, args []any) (int, error) {
+	// reltuples counts soft-deleted rows too, so it only tells whether the
+	// table has statistics the planner can use.
+	var n float64
+	err := r.pool.QueryRow(ctx, `SELECT reltuples FROM pg_class WHERE oid = 'users'::regclass`).Scan(&n)
+	if err != nil || n < 0 {
+		return -1, err
+	}
+	var plan []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	var raw []byte
+	if err := r.pool.QueryRow(ctx, `EXPLAIN (FORMAT JSON) SELECT 1 FROM users WHERE `+where, args...).Scan(&raw); err != nil {
+		return -1, err
+	}
+	if err := json.Unmarshal(raw, &plan); err != nil || len(plan) == 0 {
+		return -1, err
+	}
+	return int(plan[0].Plan.Rows), nil
+}
+
+// UpdateProfile overwrites the profile if the row is still at version and
+// returns the updated user. Empty fields are stored as NULL.
+func (r *PostgresUserRepository) UpdateProfile(ctx context.Context, id string, p userdomain.Profile, version int64) (*userdomain.User, error) {
+	u, err := scanUser(r.pool.QueryRow(ctx, `
+		UPDATE users SET
+			display_name = NULLIF($3, ''), email = NULLIF($4, ''), avatar_url = NULLIF($5, ''),
+			locale = NULLIF($6, ''), timezone = NULLIF($7, ''),
+			updated_at = now(), version = version + 1
+		WHERE id = $1 AND version = $2 AND deleted_at IS NULL
+		RETURNING `+userColumns, id, version, p.DisplayName, p.Email, p.AvatarURL, p.Locale, p.Timezone))
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return u, err
 	}
-	return users, total, nil
-}
\ No newline at end of file
+	var exists bool
+	if err := r.pool.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if exists {
+		return nil, userdomain.ErrVersionConflict
+	}
+	return nil, pgx.ErrNoRows
+}
+
+const maxUserAgentLen = 512
+
+func (r *PostgresUserRepository) ChangePhone(ctx context.Context, c userdomain.PhoneChange) (*userdomain.User, error) {
+	c.UserAgent = truncate(c.UserAgent, maxUserAgentLen)
+	if c.ChangedAt.IsZero() {
+		c.ChangedAt = time.Now()
+	}
+	var u *userdomain.User
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		var err error
+		u, err = scanUser(tx.QueryRow(ctx, `
+			UPDATE users SET phone = $3, updated_at = now(), version = version + 1
+			WHERE id = $1 AND phone = $2 AND deleted_at IS NULL
+			RETURNING `+userColumns, c.UserID, c.OldPhone, c.NewPhone))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx, `
+			INSERT INTO user_phone_changes(user_id, old_phone, new_phone, ip, user_agent, changed_at)
+			VALUES($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''), $6)`,
+			c.UserID, c.OldPhone, c.NewPhone, c.IP, c.UserAgent, c.ChangedAt)
+		if err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, userdomain.EventPhoneChanged, c.UserID, map[string]any{
+			"old_phone": c.OldPhone,
+			"new_phone": c.NewPhone,
+		})
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return nil, userdomain.ErrPhoneTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+func (r *PostgresUserRepository) SoftDelete(ctx context.Context, id string) (time.Time, error) {
+	var deletedAt time.Time
+	err := pgx.BeginFunc(ctx, r.pool, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			UPDATE users SET deleted_at = now(), updated_at = now(), version = version + 1
+			WHERE id = $1 AND deleted_at IS NULL
+			RETURNING deleted_at`, id).Scan(&deletedAt)
+		if err != nil {
+			return err
+		}
+		return insertEvent(ctx, tx, userdomain.EventDeleted, id, map[string]any{"deleted_at": deletedAt})
+	})
+	return deletedAt, err
+}
+
+func (r *PostgresUserRepository) Restore(ctx context.Context, phone string) (*userdomain.User, error) {
+	return scanUser(r.pool.QueryRow(ctx, `
+		UPDATE users SET deleted_at = NULL, updated_at = now(), version = version + 1
+		WHERE id = (
+			SELECT id FROM users WHERE phone = $1 AND deleted_at IS NOT NULL
+			ORDER BY deleted_at DESC LIMIT 1
+		) AND NOT EXISTS (SELECT 1 FROM users WHERE phone = $1 AND deleted_at IS NULL)
+		RETURNING `+userColumns, phone))
+}
+
+// PurgeDeleted relies on ON DELETE CASCADE to remove roles, refresh tokens and
+// phone change records along with the user. Outbox events are kept for the
+// delivery history, but the phone numbers in them are removed.
+func (r *PostgresUserRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := r.pool.QueryRow(ctx, `
+		WITH purged AS (
+			DELETE FROM users WHERE deleted_at < $1 RETURNING id
+		), scrubbed AS (
+			UPDATE outbox_events o SET data = o.data - $2::text[]
+			FROM purged WHERE o.user_id = purged.id
+		)
+		SELECT count(*) FROM purged`, before, outboxPhoneFields).Scan(&n)
+	return n, err
+}
+
+func (r *PostgresUserRepository) Export(ctx context.Context, id string) (*userdomain.Export, error) {
+	u, err := r.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	export := &userdomain.Export{ExportedAt: time.Now().UTC(), User: *u}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT COALESCE(device_name, ''), COALESCE(user_agent, ''), COALESCE(ip, ''),
+			created_at, last_seen_at, expires_at, revoked_at
+		FROM sessions WHERE user_id = $1 ORDER BY created_at`, id)
+	if err != nil {
+		return nil, err
+	}
+	export.Sessions, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (userdomain.SessionRecord, error) {
+		var s userdomain.SessionRecord
+		err := row.Scan(&s.DeviceName, &s.UserAgent, &s.IP, &s.CreatedAt, &s.LastSeenAt, &s.ExpiresAt, &s.RevokedAt)
+		return s, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err = r.pool.Query(ctx, `
+		SELECT old_phone, new_phone, COALESCE(ip, ''), COALESCE(user_agent, ''), changed_at FROM user_phone_changes
+		WHERE user_id = $1 ORDER BY changed_at`, id)
+	if err != nil {
+		return nil, err
+	}
+	export.PhoneChanges, err = pgx.CollectRows(rows, func(row pgx.CollectableRow) (userdomain.PhoneChange, error) {
+		c := userdomain.PhoneChange{UserID: id}
+		err := row.Scan(&c.OldPhone, &c.NewPhone, &c.IP, &c.UserAgent, &c.ChangedAt)
+		return c, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+func (r *PostgresUserRepository) SetStatus(ctx context.Context, id string, c userdomain.StatusChange) (*userdomain.User, error) {
+	return scanUser(r.pool.QueryRow(ctx, `
+		UPDATE users SET
+			status = $2, status_reason = NULLIF($3, ''), status_until = $4,
+			status_changed_at = now(), status_changed_by = NULLIF($5, '')::uuid,
+			updated_at = now(), version = version + 1
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING `+userColumns, id, c.Status, c.StatusReason, c.StatusUntil, c.ChangedBy))
+}
@@ -0,0 +1,171 @@
+package postgresrepositories
+
+import (
+	"context"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+	webhookdomain "dekamond/internal/domain/webhook"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const maxDeliveryErrorLen = 500
+
+type PostgresWebhookRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewPostgresWebhookRepository(pool *pgxpool.Pool) *PostgresWebhookRepository {
+	return &PostgresWebhookRepository{pool: pool}
+}
+
+func (r *PostgresWebhookRepository) CreateEndpoint(ctx context.Context, e *webhookdomain.Endpoint) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	events := make([]string, len(e.Events))
+	for i, t := range e.Events {
+		events[i] = string(t)
+	}
+	return r.pool.QueryRow(ctx, `
+		INSERT INTO webhook_endpoints(id, url, secret, events) VALUES($1, $2, $3, $4)
+		RETURNING created_at`, e.ID, e.URL, e.Secret, events).Scan(&e.CreatedAt)
+}
+
+func (r *PostgresWebhookRepository) ListEndpoints(ctx context.Context) ([]webhookdomain.Endpoint, error) {
+	rows, err := r.pool.Query(ctx, `SELECT id, url, events, created_at FROM webhook_endpoints ORDER BY created_at`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (webhookdomain.Endpoint, error) {
+		var e webhookdomain.Endpoint
+		var events []string
+		if err := row.Scan(&e.ID, &e.URL, &events, &e.CreatedAt); err != nil {
+			return e, err
+		}
+		e.Events = make([]userdomain.EventType, len(events))
+		for i, t := range events {
+			e.Events[i] = userdomain.EventType(t)
+		}
+		return e, nil
+	})
+}
+
+func (r *PostgresWebhookRepository) DeleteEndpoint(ctx context.Context, id string) error {
+	tag, err := r.pool.Exec(ctx, `DELETE FROM webhook_endpoints WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
+
+// FanOut is a single statement, so events are marked dispatched exactly when
+// their deliveries are queued. SKIP LOCKED lets every instance run it.
+func (r *PostgresWebhookRepository) FanOut(ctx context.Context, limit int) (int, error) {
+	tag, err := r.pool.Exec(ctx, `
+		WITH ev AS (
+			SELECT id, event_type FROM outbox_events
+			WHERE dispatched_at IS NULL
+			ORDER BY occurred_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), queued AS (
+			INSERT INTO webhook_deliveries(endpoint_id, event_id)
+			SELECT e.id, ev.id FROM ev
+			JOIN webhook_endpoints e ON cardinality(e.events) = 0 OR ev.event_type = ANY(e.events)
+			ON CONFLICT (endpoint_id, event_id) DO NOTHING
+		)
+		UPDATE outbox_events SET dispatched_at = now() WHERE id IN (SELECT id FROM ev)`, limit)
+	if err != nil {
+		return 0, err
+	}
+	return int(tag.RowsAffected()), nil
+}
+
+func (r *PostgresWebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]webhookdomain.Delivery, error) {
+	rows, err := r.pool.Query(ctx, `
+		WITH due AS (
+			SELECT id FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= now()
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d SET next_attempt_at = now() + make_interval(secs => $2)
+		FROM due, webhook_endpoints e, outbox_events o
+		WHERE d.id = due.id AND e.id = d.endpoint_id AND o.id = d.event_id
+		RETURNING d.id, d.endpoint_id, e.url, e.secret, d.status, d.attempts, d.next_attempt_at,
+			COALESCE(d.last_status, 0), COALESCE(d.last_error, ''), d.created_at, d.delivered_at,
+			o.id, o.event_type, o.occurred_at, o.data`, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (webhookdomain.Delivery, error) {
+		var d webhookdomain.Delivery
+		err := row.Scan(&d.ID, &d.EndpointID, &d.URL, &d.Secret, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+			&d.Event.ID, &d.Event.Type, &d.Event.OccurredAt, &d.Event.Data)
+		return d, err
+	})
+}
+
+func (r *PostgresWebhookRepository) MarkDelivered(ctx context.Context, id int64, status int) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE webhook_deliveries SET
+			status = 'delivered', attempts = attempts + 1, last_status = $2, last_error = NULL, delivered_at = now()
+		WHERE id = $1`, id, status)
+	return err
+}
+
+func (r *PostgresWebhookRepository) MarkFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error {
+	_, err := r.pool.Exec(ctx, `
+		UPDATE webhook_deliveries SET
+			attempts = attempts + 1,
+			last_status = NULLIF($2, 0),
+			last_error = $3,
+			status = CASE WHEN $4::timestamptz IS NULL THEN 'dead' ELSE 'pending' END,
+			next_attempt_at = COALESCE($4, next_attempt_at)
+		WHERE id = $1`, id, status, truncate(reason, maxDeliveryErrorLen), retryAt)
+	return err
+}
+
+func (r *PostgresWebhookRepository) ListDeliveries(ctx context.Context, endpointID string, status webhookdomain.DeliveryStatus, limit int) ([]webhookdomain.Delivery, error) {
+	rows, err := r.pool.Query(ctx, `
+		SELECT d.id, d.endpoint_id, d.status, d.attempts, d.next_attempt_at,
+			COALESCE(d.last_status, 0), COALESCE(d.last_error, ''), d.created_at, d.delivered_at,
+			o.id, o.event_type, o.occurred_at, o.data
+		FROM webhook_deliveries d
+		JOIN outbox_events o ON o.id = d.event_id
+		WHERE d.endpoint_id = $1 AND ($2 = '' OR d.status = $2)
+		ORDER BY d.id DESC
+		LIMIT $3`, endpointID, string(status), limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (webhookdomain.Delivery, error) {
+		var d webhookdomain.Delivery
+		err := row.Scan(&d.ID, &d.EndpointID, &d.Status, &d.Attempts, &d.NextAttemptAt,
+			&d.LastStatus, &d.LastError, &d.CreatedAt, &d.DeliveredAt,
+			&d.Event.ID, &d.Event.Type, &d.Event.OccurredAt, &d.Event.Data)
+		return d, err
+	})
+}
+
+func (r *PostgresWebhookRepository) Redeliver(ctx context.Context, endpointID string, id int64) error {
+	tag, err := r.pool.Exec(ctx, `
+		UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = now()
+		WHERE id = $1 AND endpoint_id = $2 AND status = 'dead'`, id, endpointID)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return pgx.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package jwtkeys
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"dekamond/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet holds every key tokens may currently be signed with. The last key
+// signs new tokens; the others stay published until the tokens they signed
+// have expired, which is how keys are rotated without logging anyone out.
+//
+// Without key files the set falls back to HS256 with the shared secret and
+// publishes nothing.
+type KeySet struct {
+	mu     sync.RWMutex
+	files  []string
+	keys   []*Key
+	secret []byte
+}
+
+func New(cfg config.Config) (*KeySet, error) {
+	if len(cfg.JWTKeyFiles) == 0 {
+		return NewHMAC([]byte(cfg.JWTSecret)), nil
+	}
+	ks := &KeySet{files: cfg.JWTKeyFiles}
+	if err := ks.Reload(); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+func NewHMAC(secret []byte) *KeySet {
+	return &KeySet{secret: secret}
+}
+
+// NewFromKeys builds a set from already parsed keys, oldest first.
+func NewFromKeys(keys ...*Key) (*KeySet, error) {
+	if len(keys) == 0 || keys[len(keys)-1].Private == nil {
+		return nil, errors.New("the newest key must include a private key")
+	}
+	return &KeySet{keys: keys}, nil
+}
+
+// Reload re-reads the key files, so a new key can be appended (or an expired
+// one dropped) without restarting the process.
+func (ks *KeySet) Reload() error {
+	if len(ks.files) == 0 {
+		return nil
+	}
+	keys := make([]*Key, 0, len(ks.files))
+	for _, path := range ks.files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read jwt key %s: %w", path, err)
+		}
+		key, err := ParsePEM(data)
+		if err != nil {
+			return fmt.Errorf("parse jwt key %s: %w", path, err)
+		}
+		keys = append(keys, key)
+	}
+	if keys[len(keys)-1].Private == nil {
+		return fmt.Errorf("jwt key %s: the newest key must be a private key", ks.files[len(ks.files)-1])
+	}
+
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+	return nil
+}
+
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if len(ks.keys) == 0 {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(ks.secret)
+	}
+	key := ks.keys[len(ks.keys)-1]
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.Private)
+}
+
+// Keyfunc resolves the verification key for a parsed token. The token's alg
+// must match the key's own algorithm.
+func (ks *KeySet) Keyfunc(token *jwt.Token) (any, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	if len(ks.keys) == 0 {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+		}
+		return ks.secret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	for _, key := range ks.keys {
+		if key.ID == kid {
+			if token.Method.Alg() != key.Method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method %v", token.Header["alg"])
+			}
+			return key.Public, nil
+		}
+	}
+	return nil, ErrUnknownKey
+}
+
+func (ks *KeySet) JWKS() JWKS {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := JWKS{Keys: make([]JWK, 0, len(ks.keys))}
+	for _, key := range ks.keys {
+		jwk, err := publicJWK(key.Public)
+		if err != nil {
+			continue
+		}
+		jwk.Kid = key.ID
+		jwk.Use = "sig"
+		jwk.Alg = key.Method.Alg()
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -0,0 +1,173 @@
+package jwtkeys
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"dekamond/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func writePrivateKey(t *testing.T, dir, name string, key any) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return path
+}
+
+func claims() jwt.RegisteredClaims {
+	return jwt.RegisteredClaims{Subject: "user-1", ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))}
+}
+
+func TestSignAndVerify(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	tests := []struct {
+		name    string
+		key     any
+		wantAlg string
+		wantKty string
+	}{
+		{name: "rsa", key: rsaKey, wantAlg: "RS256", wantKty: "RSA"},
+		{name: "ecdsa", key: ecKey, wantAlg: "ES256", wantKty: "EC"},
+		{name: "ed25519", key: edKey, wantAlg: "EdDSA", wantKty: "OKP"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writePrivateKey(t, t.TempDir(), "key.pem", tt.key)
+			ks, err := New(config.Config{JWTKeyFiles: []string{path}})
+			if err != nil {
+				t.Fatalf("New() unexpected error: %v", err)
+			}
+
+			signed, err := ks.Sign(claims())
+			if err != nil {
+				t.Fatalf("Sign() unexpected error: %v", err)
+			}
+			token, err := jwt.Parse(signed, ks.Keyfunc)
+			if err != nil {
+				t.Fatalf("Parse() unexpected error: %v", err)
+			}
+			if token.Method.Alg() != tt.wantAlg {
+				t.Errorf("alg = %s, want %s", token.Method.Alg(), tt.wantAlg)
+			}
+
+			set := ks.JWKS()
+			if len(set.Keys) != 1 {
+				t.Fatalf("JWKS() has %d keys, want 1", len(set.Keys))
+			}
+			jwk := set.Keys[0]
+			if jwk.Kty != tt.wantKty || jwk.Alg != tt.wantAlg || jwk.Kid != token.Header["kid"] {
+				t.Errorf("JWKS() key = %+v, token kid = %v", jwk, token.Header["kid"])
+			}
+		})
+	}
+}
+
+func TestRotation(t *testing.T) {
+	dir := t.TempDir()
+	oldKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	newKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	oldPath := writePrivateKey(t, dir, "old.pem", oldKey)
+	newPath := writePrivateKey(t, dir, "new.pem", newKey)
+
+	ks, err := New(config.Config{JWTKeyFiles: []string{oldPath}})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	oldToken, _ := ks.Sign(claims())
+
+	ks.files = append(ks.files, newPath)
+	if err := ks.Reload(); err != nil {
+		t.Fatalf("Reload() unexpected error: %v", err)
+	}
+	newToken, _ := ks.Sign(claims())
+
+	parsedOld, err := jwt.Parse(oldToken, ks.Keyfunc)
+	if err != nil {
+		t.Errorf("token signed before rotation rejected: %v", err)
+	}
+	parsedNew, err := jwt.Parse(newToken, ks.Keyfunc)
+	if err != nil {
+		t.Fatalf("token signed after rotation rejected: %v", err)
+	}
+	if parsedOld.Header["kid"] == parsedNew.Header["kid"] {
+		t.Errorf("new tokens still signed with the old key")
+	}
+	if len(ks.JWKS().Keys) != 2 {
+		t.Errorf("JWKS() has %d keys during rotation, want 2", len(ks.JWKS().Keys))
+	}
+
+	ks.files = []string{newPath}
+	if err := ks.Reload(); err != nil {
+		t.Fatalf("Reload() unexpected error: %v", err)
+	}
+	if _, err := jwt.Parse(oldToken, ks.Keyfunc); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("token of retired key error = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestKeyfuncRejectsAlgorithmMismatch(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key, err := ParsePEM(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}))
+	if err != nil {
+		t.Fatalf("ParsePEM() unexpected error: %v", err)
+	}
+	ks, _ := NewFromKeys(key)
+
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, claims())
+	forged.Header["kid"] = key.ID
+	signed, _ := forged.SignedString([]byte("guessed"))
+	if _, err := jwt.Parse(signed, ks.Keyfunc); err == nil {
+		t.Errorf("Parse() accepted an HS256 token for an RSA key")
+	}
+}
+
+func TestHMACFallback(t *testing.T) {
+	ks, err := New(config.Config{JWTSecret: "secret"})
+	if err != nil {
+		t.Fatalf("New() unexpected error: %v", err)
+	}
+	signed, _ := ks.Sign(claims())
+	if _, err := jwt.Parse(signed, ks.Keyfunc); err != nil {
+		t.Errorf("Parse() unexpected error: %v", err)
+	}
+	if len(ks.JWKS().Keys) != 0 {
+		t.Errorf("JWKS() must not publish the shared secret")
+	}
+}
+
+// Example key and thumbprint from RFC 7638, section 3.1.
+func TestThumbprint(t *testing.T) {
+	n, _ := new(big.Int).SetString("26634547600177008912365441464036882611104634136430581696102639463075266436216946316053845642300166320042915031924501272705275043130211783228252369194856949397782880847235143381529207382262647906987655738647387007320361149854766523417293323739185308113373529512728932838100141612048712597178695720651344295450174895369923383396704334331627261565907266749863744707920606364678231639106403854977302183719246256958550651555767664134467706614553219592981545363271425781391262006405169505726523023628770285432062044391310047445749287563161668548354322560223509946990827691654627968182167826397015368836435965354956581554819", 10)
+	key := &rsa.PublicKey{N: n, E: 65537}
+
+	jwk, err := publicJWK(key)
+	if err != nil {
+		t.Fatalf("publicJWK() unexpected error: %v", err)
+	}
+	if got := thumbprint(jwk); got != "NzbLsXh8uDCcd-6MNwXF4W_7noWXFZAfHkxZsRGC9Xs" {
+		t.Errorf("thumbprint() = %s", got)
+	}
+}
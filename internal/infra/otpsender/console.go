@@ -0,0 +1,28 @@
+package otpsender
+
+import (
+	"context"
+
+	authdomain "dekamond/internal/domain/auth"
+	"dekamond/internal/logging"
+)
+
+// Console is the dev driver: it logs the message instead of delivering it.
+// The code is redacted like any other unless showCode is set.
+type Console struct {
+	showCode bool
+}
+
+func NewConsole(showCode bool) *Console {
+	return &Console{showCode: showCode}
+}
+
+func (c *Console) Send(ctx context.Context, msg authdomain.OTPMessage) error {
+	codeKey := "otp"
+	if c.showCode {
+		// Not one of the redacted keys, so the code reaches the log.
+		codeKey = "dev_code"
+	}
+	logging.FromContext(ctx).Info("otp not sent, dev driver", "phone", msg.Phone, "channel", msg.Channel, codeKey, msg.Code)
+	return nil
+}
@@ -0,0 +1,40 @@
+package otpsender
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	authdomain "dekamond/internal/domain/auth"
+	"dekamond/internal/logging"
+)
+
+func TestConsoleSend(t *testing.T) {
+	msg := authdomain.OTPMessage{Phone: "+989121234567", Code: "482913", Channel: authdomain.OTPChannelSMS}
+
+	tests := []struct {
+		name     string
+		showCode bool
+		wantCode bool
+	}{
+		{name: "code redacted by default", showCode: false, wantCode: false},
+		{name: "code shown when enabled", showCode: true, wantCode: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			ctx := logging.WithLogger(context.Background(), logging.New(&buf, "info"))
+			if err := NewConsole(tt.showCode).Send(ctx, msg); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if got := strings.Contains(buf.String(), msg.Code); got != tt.wantCode {
+				t.Errorf("code in log = %v, want %v: %s", got, tt.wantCode, buf.String())
+			}
+			if strings.Contains(buf.String(), msg.Phone) {
+				t.Errorf("log leaks the phone number: %s", buf.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package otpsender
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"dekamond/internal/config"
+	authdomain "dekamond/internal/domain/auth"
+)
+
+// KavenegarSMS sends codes through the Kavenegar REST API, where the API key
+// is part of the URL path rather than a header.
+type KavenegarSMS struct {
+	client *http.Client
+	conf   config.KavenegarConfig
+}
+
+func NewKavenegarSMS(client *http.Client, conf config.KavenegarConfig) *KavenegarSMS {
+	return &KavenegarSMS{client: client, conf: conf}
+}
+
+func (k *KavenegarSMS) Send(ctx context.Context, msg authdomain.OTPMessage) error {
+	form := url.Values{}
+	form.Set("receptor", msg.Phone)
+	form.Set("message", smsText(msg))
+	if k.conf.Sender != "" {
+		form.Set("sender", k.conf.Sender)
+	}
+	return kavenegarPost(ctx, k.client, k.conf, "sms/send.json", form)
+}
+
+// KavenegarVoice uses the text-to-speech call endpoint.
+type KavenegarVoice struct {
+	client *http.Client
+	conf   config.KavenegarConfig
+}
+
+func NewKavenegarVoice(client *http.Client, conf config.KavenegarConfig) *KavenegarVoice {
+	return &KavenegarVoice{client: client, conf: conf}
+}
+
+func (k *KavenegarVoice) Send(ctx context.Context, msg authdomain.OTPMessage) error {
+	form := url.Values{}
+	form.Set("receptor", msg.Phone)
+	form.Set("message", voiceText(msg))
+	return kavenegarPost(ctx, k.client, k.conf, "call/maketts.json", form)
+}
+
+func kavenegarPost(ctx context.Context, client *http.Client, conf config.KavenegarConfig, resource string, form url.Values) error {
+	endpoint := fmt.Sprintf("%s/v1/%s/%s",
+		strings.TrimRight(conf.BaseURL, "/"), url.PathEscape(conf.APIKey), resource)
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return do(ctx, client, "kavenegar", req)
+}
@@ -0,0 +1,103 @@
+package otpsender
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"dekamond/internal/config"
+	authdomain "dekamond/internal/domain/auth"
+)
+
+func TestKavenegar(t *testing.T) {
+	tests := []struct {
+		name     string
+		sender   func(*http.Client, config.KavenegarConfig) authdomain.OTPSender
+		wantPath string
+		wantText string
+	}{
+		{
+			name: "sms",
+			sender: func(c *http.Client, conf config.KavenegarConfig) authdomain.OTPSender {
+				return NewKavenegarSMS(c, conf)
+			},
+			wantPath: "/v1/api-key/sms/send.json",
+			wantText: "654321",
+		},
+		{
+			name: "voice",
+			sender: func(c *http.Client, conf config.KavenegarConfig) authdomain.OTPSender {
+				return NewKavenegarVoice(c, conf)
+			},
+			wantPath: "/v1/api-key/call/maketts.json",
+			wantText: "6, 5, 4, 3, 2, 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotPath, gotReceptor, gotMessage string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				_ = r.ParseForm()
+				gotReceptor, gotMessage = r.PostForm.Get("receptor"), r.PostForm.Get("message")
+				_, _ = w.Write([]byte(`{"return":{"status":200,"message":"OK"}}`))
+			}))
+			defer srv.Close()
+
+			conf := config.KavenegarConfig{BaseURL: srv.URL, APIKey: "api-key"}
+			err := tt.sender(srv.Client(), conf).Send(context.Background(), authdomain.OTPMessage{
+				Phone: "+989121234567", Code: "654321",
+			})
+			if err != nil {
+				t.Fatalf("Send() unexpected error: %v", err)
+			}
+
+			if gotPath != tt.wantPath {
+				t.Errorf("Send() path = %s, want %s", gotPath, tt.wantPath)
+			}
+			if gotReceptor != "+989121234567" {
+				t.Errorf("Send() receptor = %s", gotReceptor)
+			}
+			if !strings.Contains(gotMessage, tt.wantText) {
+				t.Errorf("Send() message %q does not contain %q", gotMessage, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestKavenegarErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"return":{"status":403,"message":"invalid key for +989121234567"}}`))
+	}))
+	defer srv.Close()
+
+	conf := config.KavenegarConfig{BaseURL: srv.URL, APIKey: "bad"}
+	err := NewKavenegarSMS(srv.Client(), conf).Send(context.Background(), authdomain.OTPMessage{Phone: "+98", Code: "1"})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusForbidden {
+		t.Errorf("Send() error = %v, want 403 APIError", err)
+	}
+	if strings.Contains(err.Error(), "+989121234567") {
+		t.Errorf("Send() error %q repeats the provider's response body", err)
+	}
+}
+
+func TestKavenegarTransportErrorHidesAPIKey(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.Close()
+
+	conf := config.KavenegarConfig{BaseURL: srv.URL, APIKey: "secret-api-key"}
+	err := NewKavenegarSMS(srv.Client(), conf).Send(context.Background(), authdomain.OTPMessage{Phone: "+98", Code: "1"})
+	if err == nil {
+		t.Fatal("Send() expected an error from a closed server")
+	}
+	if strings.Contains(err.Error(), "secret-api-key") {
+		t.Errorf("Send() error %q contains the API key", err)
+	}
+}
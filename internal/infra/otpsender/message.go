@@ -0,0 +1,77 @@
+package otpsender
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+
+	authdomain "dekamond/internal/domain/auth"
+)
+
+func smsText(msg authdomain.OTPMessage) string {
+	minutes := int(math.Ceil(msg.TTL.Minutes()))
+	if minutes < 1 {
+		return fmt.Sprintf("Your verification code is %s", msg.Code)
+	}
+	return fmt.Sprintf("Your verification code is %s. It expires in %d minutes.", msg.Code, minutes)
+}
+
+// voiceText spells the code digit by digit so text-to-speech engines don't
+// read it as a single number, and repeats it once.
+func voiceText(msg authdomain.OTPMessage) string {
+	digits := strings.Join(strings.Split(msg.Code, ""), ", ")
+	return fmt.Sprintf("Your verification code is %s. Again, your code is %s.", digits, digits)
+}
+
+// APIError is a non-2xx answer from a provider. Body is kept for callers
+// that inspect it but left out of Error, since providers echo back request
+// details.
+type APIError struct {
+	Provider string
+	Status   int
+	Body     string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d", e.Provider, e.Status)
+}
+
+func do(ctx context.Context, client *http.Client, provider string, req *http.Request) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		// A *url.Error quotes the request URL, which carries the API key for
+		// some providers.
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("%s: %s: %w", provider, req.Method, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &APIError{Provider: provider, Status: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	return nil
+}
+
+func newJSONRequest(method, url string, payload any) (*http.Request, error) {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(method, url, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return req, nil
+}
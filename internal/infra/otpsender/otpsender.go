@@ -0,0 +1,74 @@
+package otpsender
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"dekamond/internal/config"
+	authdomain "dekamond/internal/domain/auth"
+)
+
+// Router dispatches an OTP message to the driver registered for its channel.
+type Router struct {
+	drivers map[authdomain.OTPChannel]authdomain.OTPSender
+}
+
+func NewRouter() *Router {
+	return &Router{drivers: make(map[authdomain.OTPChannel]authdomain.OTPSender)}
+}
+
+func (r *Router) Register(channel authdomain.OTPChannel, sender authdomain.OTPSender) {
+	r.drivers[channel] = sender
+}
+
+func (r *Router) Send(ctx context.Context, msg authdomain.OTPMessage) error {
+	sender, ok := r.drivers[msg.Channel]
+	if !ok {
+		return authdomain.ErrUnsupportedChannel
+	}
+	return sender.Send(ctx, msg)
+}
+
+func New(cfg config.Config) (*Router, error) {
+	d := cfg.OTPDelivery
+	client := &http.Client{Timeout: 10 * time.Second}
+	router := NewRouter()
+
+	switch d.SMSDriver {
+	case "":
+	case "dev":
+		router.Register(authdomain.OTPChannelSMS, NewConsole(d.DevShowCodes))
+	case "twilio":
+		router.Register(authdomain.OTPChannelSMS, NewTwilioSMS(client, d.Twilio))
+	case "kavenegar":
+		router.Register(authdomain.OTPChannelSMS, NewKavenegarSMS(client, d.Kavenegar))
+	default:
+		return nil, fmt.Errorf("unknown sms driver %q", d.SMSDriver)
+	}
+
+	switch d.WhatsAppDriver {
+	case "":
+	case "dev":
+		router.Register(authdomain.OTPChannelWhatsApp, NewConsole(d.DevShowCodes))
+	case "whatsapp":
+		router.Register(authdomain.OTPChannelWhatsApp, NewWhatsApp(client, d.WhatsApp))
+	default:
+		return nil, fmt.Errorf("unknown whatsapp driver %q", d.WhatsAppDriver)
+	}
+
+	switch d.VoiceDriver {
+	case "":
+	case "dev":
+		router.Register(authdomain.OTPChannelVoice, NewConsole(d.DevShowCodes))
+	case "twilio":
+		router.Register(authdomain.OTPChannelVoice, NewTwilioVoice(client, d.Twilio))
+	case "kavenegar":
+		router.Register(authdomain.OTPChannelVoice, NewKavenegarVoice(client, d.Kavenegar))
+	default:
+		return nil, fmt.Errorf("unknown voice driver %q", d.VoiceDriver)
+	}
+
+	return router, nil
+}
@@ -0,0 +1,73 @@
+package otpsender
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"dekamond/internal/config"
+	authdomain "dekamond/internal/domain/auth"
+)
+
+type recordingSender struct {
+	sent []authdomain.OTPMessage
+}
+
+func (r *recordingSender) Send(ctx context.Context, msg authdomain.OTPMessage) error {
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func TestRouterSend(t *testing.T) {
+	sms := &recordingSender{}
+	router := NewRouter()
+	router.Register(authdomain.OTPChannelSMS, sms)
+
+	if err := router.Send(context.Background(), authdomain.OTPMessage{Channel: authdomain.OTPChannelSMS}); err != nil {
+		t.Errorf("Send() unexpected error: %v", err)
+	}
+	if len(sms.sent) != 1 {
+		t.Errorf("Send() delivered %d messages to sms driver, want 1", len(sms.sent))
+	}
+
+	err := router.Send(context.Background(), authdomain.OTPMessage{Channel: authdomain.OTPChannelVoice})
+	if !errors.Is(err, authdomain.ErrUnsupportedChannel) {
+		t.Errorf("Send() error = %v, want ErrUnsupportedChannel", err)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name     string
+		delivery config.OTPDeliveryConfig
+		wantErr  bool
+	}{
+		{
+			name:     "dev sms only",
+			delivery: config.OTPDeliveryConfig{SMSDriver: "dev"},
+		},
+		{
+			name:     "all channels",
+			delivery: config.OTPDeliveryConfig{SMSDriver: "kavenegar", WhatsAppDriver: "whatsapp", VoiceDriver: "twilio"},
+		},
+		{
+			name:     "unknown sms driver",
+			delivery: config.OTPDeliveryConfig{SMSDriver: "pigeon"},
+			wantErr:  true,
+		},
+		{
+			name:     "whatsapp driver on voice channel",
+			delivery: config.OTPDeliveryConfig{VoiceDriver: "whatsapp"},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(config.Config{OTPDelivery: tt.delivery})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
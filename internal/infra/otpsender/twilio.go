@@ -0,0 +1,66 @@
+package otpsender
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"dekamond/internal/config"
+	authdomain "dekamond/internal/domain/auth"
+)
+
+// TwilioSMS sends codes through the Twilio Messages API. Any gateway that
+// accepts the same form-encoded request works with a different BaseURL.
+type TwilioSMS struct {
+	client *http.Client
+	conf   config.TwilioConfig
+}
+
+func NewTwilioSMS(client *http.Client, conf config.TwilioConfig) *TwilioSMS {
+	return &TwilioSMS{client: client, conf: conf}
+}
+
+func (t *TwilioSMS) Send(ctx context.Context, msg authdomain.OTPMessage) error {
+	form := url.Values{}
+	form.Set("To", msg.Phone)
+	form.Set("From", t.conf.From)
+	form.Set("Body", smsText(msg))
+	return twilioPost(ctx, t.client, t.conf, "Messages.json", form)
+}
+
+// TwilioVoice places a call that reads the code out using TwiML <Say>.
+type TwilioVoice struct {
+	client *http.Client
+	conf   config.TwilioConfig
+}
+
+func NewTwilioVoice(client *http.Client, conf config.TwilioConfig) *TwilioVoice {
+	return &TwilioVoice{client: client, conf: conf}
+}
+
+func (t *TwilioVoice) Send(ctx context.Context, msg authdomain.OTPMessage) error {
+	var say strings.Builder
+	if err := xml.EscapeText(&say, []byte(voiceText(msg))); err != nil {
+		return err
+	}
+	form := url.Values{}
+	form.Set("To", msg.Phone)
+	form.Set("From", t.conf.From)
+	form.Set("Twiml", "<Response><Say>"+say.String()+"</Say></Response>")
+	return twilioPost(ctx, t.client, t.conf, "Calls.json", form)
+}
+
+func twilioPost(ctx context.Context, client *http.Client, conf config.TwilioConfig, resource string, form url.Values) error {
+	endpoint := fmt.Sprintf("%s/2010-04-01/Accounts/%s/%s",
+		strings.TrimRight(conf.BaseURL, "/"), url.PathEscape(conf.AccountSID), resource)
+	req, err := http.NewRequest(http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(conf.AccountSID, conf.AuthToken)
+	return do(ctx, client, "twilio", req)
+}
@@ -0,0 +1,92 @@
+package otpsender
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"dekamond/internal/config"
+	authdomain "dekamond/internal/domain/auth"
+)
+
+func TestTwilioSMS(t *testing.T) {
+	var gotPath, gotUser, gotPass, gotTo, gotFrom, gotBody string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotUser, gotPass, _ = r.BasicAuth()
+		_ = r.ParseForm()
+		gotTo, gotFrom, gotBody = r.PostForm.Get("To"), r.PostForm.Get("From"), r.PostForm.Get("Body")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	conf := config.TwilioConfig{BaseURL: srv.URL, AccountSID: "AC123", AuthToken: "secret", From: "+15550000000"}
+	err := NewTwilioSMS(srv.Client(), conf).Send(context.Background(), authdomain.OTPMessage{
+		Phone: "+15551234567", Code: "123456", Channel: authdomain.OTPChannelSMS, TTL: 2 * time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+
+	if gotPath != "/2010-04-01/Accounts/AC123/Messages.json" {
+		t.Errorf("Send() path = %s", gotPath)
+	}
+	if gotUser != "AC123" || gotPass != "secret" {
+		t.Errorf("Send() basic auth = %s:%s", gotUser, gotPass)
+	}
+	if gotTo != "+15551234567" || gotFrom != "+15550000000" {
+		t.Errorf("Send() To = %s, From = %s", gotTo, gotFrom)
+	}
+	if !strings.Contains(gotBody, "123456") {
+		t.Errorf("Send() body %q does not contain the code", gotBody)
+	}
+}
+
+func TestTwilioVoice(t *testing.T) {
+	var gotPath, gotTwiml string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		_ = r.ParseForm()
+		gotTwiml = r.PostForm.Get("Twiml")
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	conf := config.TwilioConfig{BaseURL: srv.URL, AccountSID: "AC123", AuthToken: "secret", From: "+15550000000"}
+	err := NewTwilioVoice(srv.Client(), conf).Send(context.Background(), authdomain.OTPMessage{
+		Phone: "+15551234567", Code: "123", Channel: authdomain.OTPChannelVoice,
+	})
+	if err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+
+	if gotPath != "/2010-04-01/Accounts/AC123/Calls.json" {
+		t.Errorf("Send() path = %s", gotPath)
+	}
+	if !strings.HasPrefix(gotTwiml, "<Response><Say>") || !strings.Contains(gotTwiml, "1, 2, 3") {
+		t.Errorf("Send() twiml = %s", gotTwiml)
+	}
+}
+
+func TestTwilioErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"code":21211,"message":"invalid To"}`))
+	}))
+	defer srv.Close()
+
+	conf := config.TwilioConfig{BaseURL: srv.URL, AccountSID: "AC123"}
+	err := NewTwilioSMS(srv.Client(), conf).Send(context.Background(), authdomain.OTPMessage{Phone: "+1", Code: "1"})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Send() error = %v, want *APIError", err)
+	}
+	if apiErr.Status != http.StatusBadRequest || !strings.Contains(apiErr.Body, "21211") {
+		t.Errorf("Send() APIError = %+v", apiErr)
+	}
+}
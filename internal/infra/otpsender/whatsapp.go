@@ -0,0 +1,75 @@
+package otpsender
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"dekamond/internal/config"
+	authdomain "dekamond/internal/domain/auth"
+)
+
+// WhatsApp sends an authentication template through the WhatsApp Cloud API.
+// The template must take the code as its only body parameter and have a
+// copy-code URL button, which is what Meta requires for authentication
+// templates.
+type WhatsApp struct {
+	client *http.Client
+	conf   config.WhatsAppConfig
+}
+
+func NewWhatsApp(client *http.Client, conf config.WhatsAppConfig) *WhatsApp {
+	return &WhatsApp{client: client, conf: conf}
+}
+
+type whatsAppParam struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type whatsAppComponent struct {
+	Type       string          `json:"type"`
+	SubType    string          `json:"sub_type,omitempty"`
+	Index      string          `json:"index,omitempty"`
+	Parameters []whatsAppParam `json:"parameters"`
+}
+
+type whatsAppTemplate struct {
+	Name       string              `json:"name"`
+	Language   map[string]string   `json:"language"`
+	Components []whatsAppComponent `json:"components"`
+}
+
+type whatsAppMessage struct {
+	MessagingProduct string           `json:"messaging_product"`
+	To               string           `json:"to"`
+	Type             string           `json:"type"`
+	Template         whatsAppTemplate `json:"template"`
+}
+
+func (w *WhatsApp) Send(ctx context.Context, msg authdomain.OTPMessage) error {
+	codeParam := []whatsAppParam{{Type: "text", Text: msg.Code}}
+	payload := whatsAppMessage{
+		MessagingProduct: "whatsapp",
+		To:               strings.TrimPrefix(msg.Phone, "+"),
+		Type:             "template",
+		Template: whatsAppTemplate{
+			Name:     w.conf.Template,
+			Language: map[string]string{"code": w.conf.Language},
+			Components: []whatsAppComponent{
+				{Type: "body", Parameters: codeParam},
+				{Type: "button", SubType: "url", Index: "0", Parameters: codeParam},
+			},
+		},
+	}
+
+	endpoint := fmt.Sprintf("%s/%s/messages", strings.TrimRight(w.conf.BaseURL, "/"), url.PathEscape(w.conf.PhoneNumberID))
+	req, err := newJSONRequest(http.MethodPost, endpoint, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+w.conf.AccessToken)
+	return do(ctx, w.client, "whatsapp", req)
+}
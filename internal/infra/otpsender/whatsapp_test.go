@@ -0,0 +1,56 @@
+package otpsender
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"dekamond/internal/config"
+	authdomain "dekamond/internal/domain/auth"
+)
+
+func TestWhatsApp(t *testing.T) {
+	var gotPath, gotAuth string
+	var got whatsAppMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotAuth = r.Header.Get("Authorization")
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		_, _ = w.Write([]byte(`{"messages":[{"id":"wamid.1"}]}`))
+	}))
+	defer srv.Close()
+
+	conf := config.WhatsAppConfig{
+		BaseURL:       srv.URL,
+		AccessToken:   "token",
+		PhoneNumberID: "1055",
+		Template:      "otp_code",
+		Language:      "en",
+	}
+	err := NewWhatsApp(srv.Client(), conf).Send(context.Background(), authdomain.OTPMessage{
+		Phone: "+15551234567", Code: "111222", Channel: authdomain.OTPChannelWhatsApp,
+	})
+	if err != nil {
+		t.Fatalf("Send() unexpected error: %v", err)
+	}
+
+	if gotPath != "/1055/messages" {
+		t.Errorf("Send() path = %s", gotPath)
+	}
+	if gotAuth != "Bearer token" {
+		t.Errorf("Send() Authorization = %s", gotAuth)
+	}
+	if got.To != "15551234567" || got.Type != "template" || got.Template.Name != "otp_code" {
+		t.Errorf("Send() payload = %+v", got)
+	}
+	if len(got.Template.Components) != 2 {
+		t.Fatalf("Send() components = %+v", got.Template.Components)
+	}
+	for _, c := range got.Template.Components {
+		if len(c.Parameters) != 1 || c.Parameters[0].Text != "111222" {
+			t.Errorf("Send() component %s parameters = %+v", c.Type, c.Parameters)
+		}
+	}
+}
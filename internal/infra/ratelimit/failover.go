@@ -0,0 +1,107 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+
+	"dekamond/internal/config"
+	"dekamond/internal/metrics"
+)
+
+// ErrUnavailable is returned under the "closed" failure policy when the
+// backing store cannot be reached.
+var ErrUnavailable = errors.New("rate_limit_unavailable")
+
+// Guarded applies a failure policy to a limiter whose backend may be down.
+type Guarded struct {
+	name     string
+	primary  Limiter
+	policy   string
+	local    Limiter
+	nodes    int
+	degraded atomic.Bool
+}
+
+// WithFailurePolicy wraps l with the policy configured for name. Under the
+// "local" policy each node enforces 1/cfg.Nodes of the quota in memory while
+// the backend is failing.
+func WithFailurePolicy(cfg config.RateLimitConfig, name string, l Limiter) *Guarded {
+	alg, err := ParseAlgorithm(cfg.Algorithm)
+	if err != nil {
+		alg = SlidingLog
+	}
+	return &Guarded{
+		name:    name,
+		primary: l,
+		policy:  cfg.PolicyFor(name),
+		local:   NewMemory(alg),
+		nodes:   max(cfg.Nodes, 1),
+	}
+}
+
+// Allow counts every decision, including those made under the failure
+// policy, in ratelimit_decisions_total.
+func (g *Guarded) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	res, err := g.check(ctx, key, limit, true)
+	g.count(res, err, true)
+	return res, err
+}
+
+// Peek counts rejections only; a request that passes is counted when it is
+// charged with Allow.
+func (g *Guarded) Peek(ctx context.Context, key string, limit Limit) (Result, error) {
+	res, err := g.check(ctx, key, limit, false)
+	g.count(res, err, false)
+	return res, err
+}
+
+func (g *Guarded) count(res Result, err error, charged bool) {
+	decision := metrics.DecisionAllow
+	switch {
+	case err != nil:
+		decision = metrics.DecisionError
+	case !res.Allowed:
+		decision = metrics.DecisionDeny
+	case !charged:
+		return
+	}
+	metrics.RateLimitDecisions.WithLabelValues(g.name, decision).Inc()
+}
+
+func (g *Guarded) check(ctx context.Context, key string, limit Limit, charge bool) (Result, error) {
+	primary, local := g.primary.Allow, g.local.Allow
+	if !charge {
+		primary, local = g.primary.Peek, g.local.Peek
+	}
+	res, err := primary(ctx, key, limit)
+	if err == nil {
+		if g.degraded.Swap(false) {
+			slog.Info("rate limiter backend recovered", "limiter", g.name)
+		}
+		return res, nil
+	}
+
+	metrics.RateLimitBackendFailures.WithLabelValues(g.name, g.policy).Inc()
+	if !g.degraded.Swap(true) {
+		slog.Warn("rate limiter backend unavailable", "limiter", g.name, "policy", g.policy, "err", err)
+	}
+
+	switch g.policy {
+	case config.FailOpen:
+		return Result{Allowed: true, Limit: limit.Rate, Remaining: limit.Rate}, nil
+	case config.FailLocal:
+		return local(ctx, key, perNode(limit, g.nodes))
+	}
+	return Result{}, fmt.Errorf("%w: %v", ErrUnavailable, err)
+}
+
+func perNode(l Limit, nodes int) Limit {
+	l.Rate = max(l.Rate/nodes, 1)
+	if l.Burst > 0 {
+		l.Burst = max(l.Burst/nodes, 1)
+	}
+	return l
+}
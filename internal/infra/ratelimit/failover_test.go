@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"dekamond/internal/config"
+	"dekamond/internal/metrics"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+type flakyLimiter struct {
+	err error
+}
+
+func (f *flakyLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	if f.err != nil {
+		return Result{}, f.err
+	}
+	return Result{Allowed: true, Limit: limit.Rate, Remaining: limit.Rate - 1}, nil
+}
+
+func (f *flakyLimiter) Peek(ctx context.Context, key string, limit Limit) (Result, error) {
+	return f.Allow(ctx, key, limit)
+}
+
+func TestWithFailurePolicy(t *testing.T) {
+	limit := PerPeriod(4, time.Minute)
+
+	tests := []struct {
+		name        string
+		policy      string
+		nodes       int
+		wantAllowed []bool
+		wantErr     error
+	}{
+		{
+			name:        "open lets everything through",
+			policy:      config.FailOpen,
+			wantAllowed: []bool{true, true, true, true, true, true},
+		},
+		{
+			name:    "closed rejects",
+			policy:  config.FailClosed,
+			wantErr: ErrUnavailable,
+		},
+		{
+			name:        "local enforces the full quota on one node",
+			policy:      config.FailLocal,
+			nodes:       1,
+			wantAllowed: []bool{true, true, true, true, false},
+		},
+		{
+			name:        "local splits the quota across nodes",
+			policy:      config.FailLocal,
+			nodes:       2,
+			wantAllowed: []bool{true, true, false},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.RateLimitConfig{FailurePolicy: tt.policy, Nodes: tt.nodes}
+			name := strings.ReplaceAll(tt.name, " ", "_")
+			g := WithFailurePolicy(cfg, name, &flakyLimiter{err: errors.New("connection refused")})
+			ctx := context.Background()
+
+			if tt.wantErr != nil {
+				if _, err := g.Allow(ctx, "k", limit); !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Allow() error = %v, want %v", err, tt.wantErr)
+				}
+				if n := testutil.ToFloat64(metrics.RateLimitDecisions.WithLabelValues(name, metrics.DecisionError)); n != 1 {
+					t.Errorf("error decisions = %v, want 1", n)
+				}
+				return
+			}
+			wantDecisions := map[string]float64{}
+			for i, want := range tt.wantAllowed {
+				res, err := g.Allow(ctx, "k", limit)
+				if err != nil {
+					t.Fatalf("request %d: unexpected error: %v", i, err)
+				}
+				if res.Allowed != want {
+					t.Errorf("request %d: allowed = %v, want %v", i, res.Allowed, want)
+				}
+				if want {
+					wantDecisions[metrics.DecisionAllow]++
+				} else {
+					wantDecisions[metrics.DecisionDeny]++
+				}
+			}
+			for decision, want := range wantDecisions {
+				if n := testutil.ToFloat64(metrics.RateLimitDecisions.WithLabelValues(name, decision)); n != want {
+					t.Errorf("%s decisions = %v, want %v", decision, n, want)
+				}
+			}
+			if n := testutil.ToFloat64(metrics.RateLimitBackendFailures.WithLabelValues(name, tt.policy)); n != float64(len(tt.wantAllowed)) {
+				t.Errorf("failure counter = %v, want %d", n, len(tt.wantAllowed))
+			}
+		})
+	}
+}
+
+func TestWithFailurePolicyOverridesAndRecovery(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		FailurePolicy:   config.FailOpen,
+		FailurePolicies: map[string]string{"otp_send": config.FailClosed},
+	}
+	flaky := &flakyLimiter{err: errors.New("i/o timeout")}
+	g := WithFailurePolicy(cfg, "otp_send", flaky)
+	ctx := context.Background()
+
+	if _, err := g.Allow(ctx, "k", PerPeriod(1, time.Minute)); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("Allow() error = %v, want %v", err, ErrUnavailable)
+	}
+	if !g.degraded.Load() {
+		t.Errorf("limiter should be marked degraded")
+	}
+
+	flaky.err = nil
+	res, err := g.Allow(ctx, "k", PerPeriod(1, time.Minute))
+	if err != nil || !res.Allowed {
+		t.Fatalf("Allow() after recovery = %+v, %v", res, err)
+	}
+	if g.degraded.Load() {
+		t.Errorf("limiter should no longer be degraded")
+	}
+}
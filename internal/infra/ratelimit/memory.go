@@ -0,0 +1,127 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const sweepInterval = time.Minute
+
+// Memory keeps limiter state in process. Limits are per node, so it is
+// meant for development and single-instance deployments.
+type Memory struct {
+	mu        sync.Mutex
+	alg       Algorithm
+	entries   map[string]*memoryEntry
+	lastSweep int64
+	now       func() time.Time
+}
+
+type memoryEntry struct {
+	expires int64
+
+	log []int64 // SlidingLog
+
+	idx, cur, prev int64 // SlidingWindow
+
+	tat int64 // GCRA
+}
+
+func NewMemory(alg Algorithm) *Memory {
+	return &Memory{alg: alg, entries: make(map[string]*memoryEntry), now: time.Now}
+}
+
+func (l *Memory) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	return l.run(key, limit, true)
+}
+
+// Peek reports whether a request would be allowed without counting it.
+func (l *Memory) Peek(ctx context.Context, key string, limit Limit) (Result, error) {
+	return l.run(key, limit, false)
+}
+
+func (l *Memory) run(key string, limit Limit, charge bool) (Result, error) {
+	if limit.Rate <= 0 || limit.Period <= 0 {
+		return Result{}, fmt.Errorf("invalid rate limit %+v", limit)
+	}
+	now := l.now().UnixMicro()
+	period := limit.Period.Microseconds()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	e, ok := l.entries[key]
+	if !ok || e.expires <= now {
+		e = &memoryEntry{}
+		l.entries[key] = e
+	}
+
+	switch l.alg {
+	case SlidingWindow:
+		idx := now / period
+		switch {
+		case idx == e.idx+1:
+			e.prev, e.cur = e.cur, 0
+		case idx != e.idx:
+			e.prev, e.cur = 0, 0
+		}
+		e.idx = idx
+		elapsed := now - idx*period
+		weighted := float64(e.prev)*float64(period-elapsed)/float64(period) + float64(e.cur)
+		allowed := weighted+1 <= float64(limit.Rate)
+		if allowed && charge {
+			e.cur++
+		}
+		e.expires = (idx + 2) * period
+		return slidingWindowResult(limit, elapsed, e.cur, e.prev, allowed), nil
+
+	case GCRA:
+		emission := gcraEmission(limit)
+		tolerance := emission * int64(limit.burst())
+		tat := max(e.tat, now)
+		allowed := tat+emission-tolerance <= now
+		// Like the Redis script, a peek reports the state without the
+		// request, so both backends agree on Remaining.
+		if allowed && charge {
+			tat += emission
+			e.tat = tat
+		}
+		e.expires = e.tat
+		return gcraResult(limit, now, tat, allowed), nil
+
+	default:
+		live := e.log[:0]
+		for _, t := range e.log {
+			if t > now-period {
+				live = append(live, t)
+			}
+		}
+		e.log = live
+		allowed := len(e.log) < limit.Rate
+		if allowed && charge {
+			e.log = append(e.log, now)
+		}
+		if len(e.log) == 0 {
+			return slidingLogResult(limit, now, 0, 0, 0, allowed), nil
+		}
+		e.expires = e.log[len(e.log)-1] + period
+		return slidingLogResult(limit, now, e.log[0], e.log[len(e.log)-1], len(e.log), allowed), nil
+	}
+}
+
+// sweep drops expired keys so the map does not grow with every client ever
+// seen. Callers hold l.mu.
+func (l *Memory) sweep(now int64) {
+	if now-l.lastSweep < sweepInterval.Microseconds() {
+		return
+	}
+	l.lastSweep = now
+	for k, e := range l.entries {
+		if e.expires <= now {
+			delete(l.entries, k)
+		}
+	}
+}
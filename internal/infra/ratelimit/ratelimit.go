@@ -0,0 +1,158 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"dekamond/internal/config"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Algorithm selects how requests are counted.
+type Algorithm string
+
+const (
+	// SlidingLog remembers every accepted request in the window. Exact, but
+	// memory grows with Rate, so it suits small limits like OTP sends.
+	SlidingLog Algorithm = "sliding_log"
+	// SlidingWindow weights the previous fixed window's count by how much of
+	// it still overlaps the sliding window. Two counters per key.
+	SlidingWindow Algorithm = "sliding_window"
+	// GCRA is a token bucket (generic cell rate algorithm) holding a single
+	// timestamp per key. Burst requests are allowed at once, then one every
+	// Period/Rate.
+	GCRA Algorithm = "gcra"
+)
+
+func ParseAlgorithm(s string) (Algorithm, error) {
+	switch a := Algorithm(s); a {
+	case SlidingLog, SlidingWindow, GCRA:
+		return a, nil
+	case "":
+		return SlidingLog, nil
+	}
+	return "", fmt.Errorf("unknown rate limit algorithm %q", s)
+}
+
+// Limit allows Rate requests per Period. Burst only applies to GCRA and
+// defaults to Rate.
+type Limit struct {
+	Rate   int
+	Period time.Duration
+	Burst  int
+}
+
+func PerPeriod(rate int, period time.Duration) Limit {
+	return Limit{Rate: rate, Period: period}
+}
+
+func (l Limit) burst() int {
+	if l.Burst > 0 {
+		return l.Burst
+	}
+	return l.Rate
+}
+
+// Result describes the decision for one request. ResetAfter is when the key
+// is back to its full quota; RetryAfter is only set when the request was
+// rejected.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// Limiter counts requests against a limit. Allow checks and counts one
+// request atomically; Peek only checks, so a caller enforcing several limits
+// can make sure all of them pass before charging any.
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+	Peek(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// New builds the limiter selected by cfg.RateLimit.
+func New(cfg config.Config, client *redis.Client) (Limiter, error) {
+	alg, err := ParseAlgorithm(cfg.RateLimit.Algorithm)
+	if err != nil {
+		return nil, err
+	}
+	switch cfg.RateLimit.Backend {
+	case "", "redis":
+		return NewRedis(client, alg), nil
+	case "memory":
+		return NewMemory(alg), nil
+	}
+	return nil, fmt.Errorf("unknown rate limit backend %q", cfg.RateLimit.Backend)
+}
+
+// The functions below turn the state an algorithm left behind into a Result.
+// They are shared by the Redis and memory limiters; all times are unix
+// microseconds.
+
+func slidingLogResult(l Limit, now, oldest, newest int64, count int, allowed bool) Result {
+	window := l.Period.Microseconds()
+	res := Result{Allowed: allowed, Limit: l.Rate, Remaining: max(l.Rate-count, 0)}
+	if count > 0 {
+		res.ResetAfter = micros(newest + window - now)
+	}
+	if !allowed {
+		res.RetryAfter = micros(oldest + window - now)
+	}
+	return res
+}
+
+func slidingWindowResult(l Limit, elapsed, cur, prev int64, allowed bool) Result {
+	period := l.Period.Microseconds()
+	weighted := float64(prev)*float64(period-elapsed)/float64(period) + float64(cur)
+
+	res := Result{Allowed: allowed, Limit: l.Rate, Remaining: max(int(float64(l.Rate)-weighted), 0)}
+	switch {
+	case cur > 0:
+		res.ResetAfter = micros(2*period - elapsed)
+	case prev > 0:
+		res.ResetAfter = micros(period - elapsed)
+	}
+	if allowed {
+		return res
+	}
+
+	// Find when the weighted count first drops to Rate-1.
+	rest := int64(l.Rate - 1)
+	if cur > rest {
+		// Only once the current window has become the previous one.
+		res.RetryAfter = micros(period - elapsed + ceilDiv(period*(cur-rest), cur))
+	} else {
+		res.RetryAfter = micros(ceilDiv(period*(prev-rest+cur), prev) - elapsed)
+	}
+	return res
+}
+
+func gcraResult(l Limit, now, tat int64, allowed bool) Result {
+	emission := gcraEmission(l)
+	tolerance := emission * int64(l.burst())
+
+	res := Result{Allowed: allowed, Limit: l.burst(), ResetAfter: micros(tat - now)}
+	if free := tolerance - (tat - now); free > 0 {
+		res.Remaining = int(free / emission)
+	}
+	if !allowed {
+		res.RetryAfter = micros(tat + emission - tolerance - now)
+	}
+	return res
+}
+
+func gcraEmission(l Limit) int64 {
+	return max(l.Period.Microseconds()/int64(l.Rate), 1)
+}
+
+func ceilDiv(a, b int64) int64 {
+	return (a + b - 1) / b
+}
+
+func micros(us int64) time.Duration {
+	return time.Duration(max(us, 0)) * time.Microsecond
+}
@@ -0,0 +1,271 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+type clock struct{ t time.Time }
+
+func (c *clock) now() time.Time          { return c.t }
+func (c *clock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+// newLimiters returns the memory and Redis implementations of alg sharing c.
+func newLimiters(t *testing.T, alg Algorithm, c *clock) map[string]Limiter {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	mem := NewMemory(alg)
+	mem.now = c.now
+	rds := NewRedis(client, alg)
+	rds.now = c.now
+	return map[string]Limiter{"memory": mem, "redis": rds}
+}
+
+type step struct {
+	advance     time.Duration
+	wantAllowed bool
+	wantRemain  int
+	wantRetry   time.Duration
+}
+
+func TestLimiters(t *testing.T) {
+	limit := PerPeriod(3, time.Minute)
+
+	tests := []struct {
+		name  string
+		alg   Algorithm
+		limit Limit
+		steps []step
+	}{
+		{
+			name:  "sliding log allows rate per window",
+			alg:   SlidingLog,
+			limit: limit,
+			steps: []step{
+				{wantAllowed: true, wantRemain: 2},
+				{advance: 10 * time.Second, wantAllowed: true, wantRemain: 1},
+				{advance: 10 * time.Second, wantAllowed: true, wantRemain: 0},
+				{advance: 10 * time.Second, wantAllowed: false, wantRetry: 30 * time.Second},
+				// The first request has left the window.
+				{advance: 30 * time.Second, wantAllowed: true, wantRemain: 0},
+				{wantAllowed: false, wantRetry: 10 * time.Second},
+			},
+		},
+		{
+			name:  "sliding log has no boundary burst",
+			alg:   SlidingLog,
+			limit: limit,
+			steps: []step{
+				{advance: 59 * time.Second, wantAllowed: true, wantRemain: 2},
+				{wantAllowed: true, wantRemain: 1},
+				{wantAllowed: true, wantRemain: 0},
+				{advance: 2 * time.Second, wantAllowed: false, wantRetry: 58 * time.Second},
+			},
+		},
+		{
+			name:  "sliding window weights the previous window",
+			alg:   SlidingWindow,
+			limit: limit,
+			steps: []step{
+				{wantAllowed: true, wantRemain: 2},
+				{wantAllowed: true, wantRemain: 1},
+				{wantAllowed: true, wantRemain: 0},
+				{wantAllowed: false, wantRetry: 80 * time.Second},
+				// Half way into the next window 1.5 of the 3 still count.
+				{advance: 90 * time.Second, wantAllowed: true, wantRemain: 0},
+				{wantAllowed: false, wantRetry: 10 * time.Second},
+			},
+		},
+		{
+			name:  "gcra allows a burst then one per interval",
+			alg:   GCRA,
+			limit: limit,
+			steps: []step{
+				{wantAllowed: true, wantRemain: 2},
+				{wantAllowed: true, wantRemain: 1},
+				{wantAllowed: true, wantRemain: 0},
+				{wantAllowed: false, wantRetry: 20 * time.Second},
+				{advance: 20 * time.Second, wantAllowed: true, wantRemain: 0},
+				{advance: 45 * time.Second, wantAllowed: true, wantRemain: 1},
+			},
+		},
+		{
+			name:  "gcra with a smaller burst",
+			alg:   GCRA,
+			limit: Limit{Rate: 6, Period: time.Minute, Burst: 1},
+			steps: []step{
+				{wantAllowed: true, wantRemain: 0},
+				{advance: 5 * time.Second, wantAllowed: false, wantRetry: 5 * time.Second},
+				{advance: 5 * time.Second, wantAllowed: true, wantRemain: 0},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		c := &clock{t: time.Unix(1_700_000_040, 0)}
+		start := c.t
+		for name, l := range newLimiters(t, tt.alg, c) {
+			t.Run(tt.name+"/"+name, func(t *testing.T) {
+				c.t = start
+				for i, s := range tt.steps {
+					c.advance(s.advance)
+					res, err := l.Allow(context.Background(), "k", tt.limit)
+					if err != nil {
+						t.Fatalf("step %d: unexpected error: %v", i, err)
+					}
+					if res.Allowed != s.wantAllowed {
+						t.Fatalf("step %d: allowed = %v, want %v (%+v)", i, res.Allowed, s.wantAllowed, res)
+					}
+					if s.wantAllowed && res.Remaining != s.wantRemain {
+						t.Errorf("step %d: remaining = %d, want %d", i, res.Remaining, s.wantRemain)
+					}
+					if !s.wantAllowed && res.RetryAfter != s.wantRetry {
+						t.Errorf("step %d: retry after = %s, want %s", i, res.RetryAfter, s.wantRetry)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestLimitersSeparateKeys(t *testing.T) {
+	for _, alg := range []Algorithm{SlidingLog, SlidingWindow, GCRA} {
+		c := &clock{t: time.Unix(1_700_000_000, 0)}
+		for name, l := range newLimiters(t, alg, c) {
+			t.Run(string(alg)+"/"+name, func(t *testing.T) {
+				ctx := context.Background()
+				limit := PerPeriod(1, time.Minute)
+				if res, _ := l.Allow(ctx, "a", limit); !res.Allowed {
+					t.Fatalf("first request for a rejected")
+				}
+				if res, _ := l.Allow(ctx, "a", limit); res.Allowed {
+					t.Fatalf("second request for a allowed")
+				}
+				if res, _ := l.Allow(ctx, "b", limit); !res.Allowed {
+					t.Errorf("first request for b rejected")
+				}
+			})
+		}
+	}
+}
+
+func TestRedisKeysExpire(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	for _, alg := range []Algorithm{SlidingLog, SlidingWindow, GCRA} {
+		l := NewRedis(client, alg)
+		if _, err := l.Allow(context.Background(), "ttl:"+string(alg), PerPeriod(5, time.Minute)); err != nil {
+			t.Fatalf("%s: unexpected error: %v", alg, err)
+		}
+	}
+	for _, key := range mr.Keys() {
+		if ttl := mr.TTL(key); ttl <= 0 || ttl > 2*time.Minute {
+			t.Errorf("key %s ttl = %s", key, ttl)
+		}
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Algorithm
+		wantErr bool
+	}{
+		{in: "", want: SlidingLog},
+		{in: "sliding_log", want: SlidingLog},
+		{in: "sliding_window", want: SlidingWindow},
+		{in: "gcra", want: GCRA},
+		{in: "fixed_window", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseAlgorithm(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseAlgorithm(%q) = %q, %v", tt.in, got, err)
+		}
+	}
+}
+
+func TestPeekDoesNotCharge(t *testing.T) {
+	limit := PerPeriod(2, time.Minute)
+	for _, alg := range []Algorithm{SlidingLog, SlidingWindow, GCRA} {
+		c := &clock{t: time.Unix(1_700_000_000, 0)}
+		for backend, l := range newLimiters(t, alg, c) {
+			t.Run(string(alg)+"/"+backend, func(t *testing.T) {
+				ctx := context.Background()
+				for i := 0; i < 5; i++ {
+					if res, err := l.Peek(ctx, "k", limit); err != nil || !res.Allowed {
+						t.Fatalf("peek %d = %+v, %v, want allowed", i, res, err)
+					}
+				}
+				for i := 0; i < 2; i++ {
+					if res, _ := l.Allow(ctx, "k", limit); !res.Allowed {
+						t.Fatalf("allow %d rejected after peeks", i)
+					}
+				}
+				if res, err := l.Peek(ctx, "k", limit); err != nil || res.Allowed {
+					t.Errorf("peek on a full key = %+v, %v, want rejected", res, err)
+				}
+			})
+		}
+	}
+}
+
+// TestBackendsAgree replays the same calls on both backends, which must
+// report identical results so that failing over between them does not change
+// the X-RateLimit-* headers clients see.
+func TestBackendsAgree(t *testing.T) {
+	limit := PerPeriod(3, time.Minute)
+	type call struct {
+		advance time.Duration
+		peek    bool
+	}
+	calls := []call{
+		{peek: true},
+		{},
+		{peek: true},
+		{advance: 5 * time.Second},
+		{},
+		{peek: true},
+		{},
+		{peek: true},
+		{advance: 25 * time.Second, peek: true},
+		{},
+		{advance: 40 * time.Second, peek: true},
+	}
+
+	for _, alg := range []Algorithm{SlidingLog, SlidingWindow, GCRA} {
+		t.Run(string(alg), func(t *testing.T) {
+			c := &clock{t: time.Unix(1_700_000_040, 0)}
+			limiters := newLimiters(t, alg, c)
+			mem, rds := limiters["memory"], limiters["redis"]
+			ctx := context.Background()
+			for i, cl := range calls {
+				c.advance(cl.advance)
+				op := mem.Allow
+				other := rds.Allow
+				if cl.peek {
+					op, other = mem.Peek, rds.Peek
+				}
+				got, err := op(ctx, "k", limit)
+				if err != nil {
+					t.Fatalf("call %d: memory error: %v", i, err)
+				}
+				want, err := other(ctx, "k", limit)
+				if err != nil {
+					t.Fatalf("call %d: redis error: %v", i, err)
+				}
+				if got != want {
+					t.Errorf("call %d (peek %v): memory = %+v, redis = %+v", i, cl.peek, got, want)
+				}
+			}
+		})
+	}
+}
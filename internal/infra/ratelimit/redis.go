@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// Each algorithm runs as a single script so the check and the update are
+// atomic and every key written gets its TTL in the same step. The last
+// argument is 1 to charge the request and 0 to only check it (Peek).
+
+var slidingLogScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+local count = redis.call('ZCARD', KEYS[1])
+local allowed = 0
+if count < limit then
+	allowed = 1
+	if ARGV[5] == '1' then
+		redis.call('ZADD', KEYS[1], now, ARGV[4])
+		count = count + 1
+	end
+end
+if count == 0 then
+	return {allowed, 0, 0, 0}
+end
+redis.call('PEXPIRE', KEYS[1], math.ceil(window / 1000))
+local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+local newest = redis.call('ZRANGE', KEYS[1], -1, -1, 'WITHSCORES')
+return {allowed, count, tonumber(oldest[2]), tonumber(newest[2])}
+`)
+
+var slidingWindowScript = redis.NewScript(`
+local elapsed = tonumber(ARGV[1])
+local period = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+local cur = tonumber(redis.call('GET', KEYS[1]) or '0')
+local prev = tonumber(redis.call('GET', KEYS[2]) or '0')
+if prev * (period - elapsed) / period + cur + 1 > limit then
+	return {0, cur, prev}
+end
+if ARGV[4] ~= '1' then
+	return {1, cur, prev}
+end
+cur = redis.call('INCR', KEYS[1])
+redis.call('PEXPIRE', KEYS[1], math.ceil(2 * period / 1000))
+return {1, cur, prev}
+`)
+
+var gcraScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local emission = tonumber(ARGV[2])
+local tolerance = tonumber(ARGV[3])
+local tat = tonumber(redis.call('GET', KEYS[1]) or '0')
+if tat < now then
+	tat = now
+end
+local new_tat = tat + emission
+if new_tat - tolerance > now then
+	return {0, tat}
+end
+if ARGV[4] ~= '1' then
+	return {1, tat}
+end
+redis.call('SET', KEYS[1], string.format('%d', new_tat), 'PX', math.ceil((new_tat - now) / 1000))
+return {1, new_tat}
+`)
+
+type Redis struct {
+	client redis.Scripter
+	alg    Algorithm
+	now    func() time.Time
+}
+
+func NewRedis(client redis.Scripter, alg Algorithm) *Redis {
+	return &Redis{client: client, alg: alg, now: time.Now}
+}
+
+func (l *Redis) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	return l.run(ctx, key, limit, true)
+}
+
+// Peek reports whether a request would be allowed without counting it.
+func (l *Redis) Peek(ctx context.Context, key string, limit Limit) (Result, error) {
+	return l.run(ctx, key, limit, false)
+}
+
+func (l *Redis) run(ctx context.Context, key string, limit Limit, charge bool) (Result, error) {
+	if limit.Rate <= 0 || limit.Period <= 0 {
+		return Result{}, fmt.Errorf("invalid rate limit %+v", limit)
+	}
+	now := l.now().UnixMicro()
+	period := limit.Period.Microseconds()
+
+	switch l.alg {
+	case SlidingWindow:
+		idx := now / period
+		// The hash tag keeps both windows in one cluster slot.
+		keys := []string{fmt.Sprintf("{%s}:%d", key, idx), fmt.Sprintf("{%s}:%d", key, idx-1)}
+		elapsed := now - idx*period
+		v, err := slidingWindowScript.Run(ctx, l.client, keys, elapsed, period, limit.Rate, flag(charge)).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		return slidingWindowResult(limit, elapsed, v[1], v[2], v[0] == 1), nil
+
+	case GCRA:
+		emission := gcraEmission(limit)
+		v, err := gcraScript.Run(ctx, l.client, []string{key}, now, emission, emission*int64(limit.burst()), flag(charge)).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		return gcraResult(limit, now, v[1], v[0] == 1), nil
+
+	default:
+		v, err := slidingLogScript.Run(ctx, l.client, []string{key}, now, period, limit.Rate, uuid.NewString(), flag(charge)).Int64Slice()
+		if err != nil {
+			return Result{}, err
+		}
+		return slidingLogResult(limit, now, v[2], v[3], int(v[1]), v[0] == 1), nil
+	}
+}
+
+func flag(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
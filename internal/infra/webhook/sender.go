@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+
+	webhookdomain "dekamond/internal/domain/webhook"
+)
+
+// maxResponseBody is how much of a response is read so the connection can
+// be reused; the body itself is ignored.
+const maxResponseBody = 64 << 10
+
+// HTTPSender POSTs webhook requests. Redirects are not followed, so a 3xx
+// counts as a failed delivery. Every connection is checked against the
+// address policy after DNS resolution, so a hostname cannot be pointed at an
+// internal service, and proxies from the environment are not used.
+type HTTPSender struct {
+	client *http.Client
+}
+
+func NewHTTPSender(timeout time.Duration, policy webhookdomain.AddressPolicy) *HTTPSender {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			ap, err := netip.ParseAddrPort(address)
+			if err != nil || !policy.Permits(ap.Addr()) {
+				return fmt.Errorf("dial %s: %w", address, webhookdomain.ErrForbiddenAddress)
+			}
+			return nil
+		},
+	}
+	return &HTTPSender{client: &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 4,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}}
+}
+
+func (s *HTTPSender) Send(ctx context.Context, r webhookdomain.Request) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.URL, bytes.NewReader(r.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range r.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,55 @@
+// Package logging sets up the JSON logger and carries a request-scoped
+// logger and request ID through context.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+)
+
+// New returns a JSON logger writing to w at the given level ("debug",
+// "info", "warn" or "error"). Phone numbers and secrets are masked on the
+// way out; see Redact.
+func New(w io.Writer, level string) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       ParseLevel(level),
+		ReplaceAttr: Redact,
+	}))
+}
+
+// ParseLevel reads a level name, falling back to info.
+func ParseLevel(s string) slog.Level {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.TrimSpace(s))); err != nil {
+		return slog.LevelInfo
+	}
+	return l
+}
+
+type loggerKey struct{}
+
+type requestIDKey struct{}
+
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, l)
+}
+
+// FromContext returns the request's logger, or the default logger outside
+// of a request.
+func FromContext(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return l
+	}
+	return slog.Default()
+}
+
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+const redacted = "[REDACTED]"
+
+// secretKeys are dropped from logs whatever their value.
+var secretKeys = map[string]bool{
+	"otp":           true,
+	"code":          true,
+	"otp_code":      true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"secret":        true,
+	"authorization": true,
+}
+
+// phoneKeys hold phone numbers in whatever form the client sent them.
+var phoneKeys = map[string]bool{
+	"phone":     true,
+	"old_phone": true,
+	"new_phone": true,
+}
+
+// phoneNumber finds phone numbers embedded in messages, errors and cache
+// keys: E.164 numbers and national ones with a trunk or international
+// prefix, like 09121234567 or 00989121234567.
+var phoneNumber = regexp.MustCompile(`\+\d{7,15}|\b0\d{9,14}\b`)
+
+// Redact is a slog ReplaceAttr func. Attributes named like secrets (otp,
+// code, token, ...) are replaced outright, attributes named like phone
+// numbers are masked, and phone numbers are masked inside every other string
+// and error, including the message.
+func Redact(_ []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	if secretKeys[key] {
+		return slog.String(a.Key, redacted)
+	}
+	switch a.Value.Kind() {
+	case slog.KindString:
+		if phoneKeys[key] {
+			return slog.String(a.Key, MaskPhone(a.Value.String()))
+		}
+		return slog.String(a.Key, MaskPhones(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, MaskPhones(err.Error()))
+		}
+	}
+	return a
+}
+
+// MaskPhone keeps the first four and last two characters of a number, e.g.
+// "+989*******67", which is enough to tell numbers apart in a log without
+// exposing them.
+func MaskPhone(s string) string {
+	r := []rune(s)
+	if len(r) <= 6 {
+		return strings.Repeat("*", len(r))
+	}
+	return string(r[:4]) + strings.Repeat("*", len(r)-6) + string(r[len(r)-2:])
+}
+
+// MaskPhones masks every phone number in s.
+func MaskPhones(s string) string {
+	return phoneNumber.ReplaceAllStringFunc(s, MaskPhone)
+}
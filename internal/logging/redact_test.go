@@ -0,0 +1,119 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name  string
+		log   func(*slog.Logger)
+		key   string
+		want  string
+		leaks string
+	}{
+		{
+			name:  "otp attribute",
+			log:   func(l *slog.Logger) { l.Info("otp sent", "otp", "482913") },
+			key:   "otp",
+			want:  redacted,
+			leaks: "482913",
+		},
+		{
+			name:  "code in a group",
+			log:   func(l *slog.Logger) { l.Info("verify", slog.Group("req", "code", "482913")) },
+			leaks: "482913",
+		},
+		{
+			name:  "phone attribute in national format",
+			log:   func(l *slog.Logger) { l.Info("otp sent", "phone", "09121234567") },
+			key:   "phone",
+			want:  "0912*****67",
+			leaks: "09121234567",
+		},
+		{
+			name:  "number in the message",
+			log:   func(l *slog.Logger) { l.Info("resend too soon for +989121234567") },
+			key:   "msg",
+			want:  "resend too soon for +989*******67",
+			leaks: "+989121234567",
+		},
+		{
+			name:  "number in an error",
+			log:   func(l *slog.Logger) { l.Error("send failed", "err", errors.New("gateway rejected +15551234567")) },
+			key:   "err",
+			want:  "gateway rejected +155******67",
+			leaks: "+15551234567",
+		},
+		{
+			name:  "national number in the message",
+			log:   func(l *slog.Logger) { l.Info("unsupported region for 09121234567") },
+			key:   "msg",
+			want:  "unsupported region for 0912*****67",
+			leaks: "09121234567",
+		},
+		{
+			name:  "number with international prefix in an error",
+			log:   func(l *slog.Logger) { l.Error("send failed", "err", errors.New("gateway rejected 00989121234567")) },
+			key:   "err",
+			want:  "gateway rejected 0098********67",
+			leaks: "00989121234567",
+		},
+		{
+			name: "short numbers pass",
+			log:  func(l *slog.Logger) { l.Info("request", "path", "/api/users?limit=050") },
+			key:  "path",
+			want: "/api/users?limit=050",
+		},
+		{
+			name: "other values pass",
+			log:  func(l *slog.Logger) { l.Info("request", "status", 200, "path", "/api/users/me") },
+			key:  "path",
+			want: "/api/users/me",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tt.log(New(&buf, "info"))
+			if tt.leaks != "" && strings.Contains(buf.String(), tt.leaks) {
+				t.Fatalf("log leaks %q: %s", tt.leaks, buf.String())
+			}
+			if tt.key == "" {
+				return
+			}
+			var line map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+				t.Fatalf("log line is not JSON: %s", buf.String())
+			}
+			if line[tt.key] != tt.want {
+				t.Errorf("%s = %v, want %q", tt.key, line[tt.key], tt.want)
+			}
+		})
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("FromContext without a logger should return the default logger")
+	}
+	l := New(&bytes.Buffer{}, "debug")
+	if FromContext(WithLogger(context.Background(), l)) != l {
+		t.Error("FromContext did not return the stored logger")
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	for in, want := range map[string]slog.Level{"debug": slog.LevelDebug, "WARN": slog.LevelWarn, "": slog.LevelInfo, "loud": slog.LevelInfo} {
+		if got := ParseLevel(in); got != want {
+			t.Errorf("ParseLevel(%q) = %v, want %v", in, got, want)
+		}
+	}
+}
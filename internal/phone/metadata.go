@@ -0,0 +1,111 @@
+package phone
+
+import "regexp"
+
+// regionMetadata follows the shape of libphonenumber's per-region metadata,
+// trimmed to what Parse needs. Patterns match the national significant
+// number (the digits after the calling code and without the national
+// prefix) and are anchored.
+type regionMetadata struct {
+	callingCode         string
+	internationalPrefix string
+	nationalPrefix      string
+	lengths             []int
+
+	mobile        *regexp.Regexp
+	fixedLine     *regexp.Regexp
+	fixedOrMobile *regexp.Regexp // where the two cannot be told apart
+	tollFree      *regexp.Regexp
+	premiumRate   *regexp.Regexp
+	voip          *regexp.Regexp
+}
+
+func re(pattern string) *regexp.Regexp {
+	return regexp.MustCompile(`^(?:` + pattern + `)$`)
+}
+
+var nanp = regionMetadata{
+	callingCode:         "1",
+	internationalPrefix: "011",
+	nationalPrefix:      "1",
+	lengths:             []int{10},
+	fixedOrMobile:       re(`[2-9]\d{9}`),
+	tollFree:            re(`8(?:00|33|44|55|66|77|88)[2-9]\d{6}`),
+	premiumRate:         re(`900[2-9]\d{6}`),
+}
+
+// metadata covers the regions we serve. Numbers from other regions are
+// accepted on length alone and get TypeUnknown.
+var metadata = map[string]regionMetadata{
+	"US": nanp,
+	"CA": nanp,
+	"IR": {
+		callingCode:         "98",
+		internationalPrefix: "00",
+		nationalPrefix:      "0",
+		lengths:             []int{10},
+		mobile:              re(`9(?:0[0-5]|[1-4]\d|9[0-9])\d{7}`),
+		fixedLine:           re(`(?:1[137]|2[13-68]|3[1458]|4[145]|5[1468]|6[16]|7[1467]|8[13467])\d{8}`),
+	},
+	"GB": {
+		callingCode:         "44",
+		internationalPrefix: "00",
+		nationalPrefix:      "0",
+		lengths:             []int{9, 10},
+		mobile:              re(`7(?:[1-3]\d|4[0-46-9]|5[0-689]|7[0-24-9]|8\d|9[0-9])\d{7}`),
+		fixedLine:           re(`[1-3]\d{8,9}`),
+		tollFree:            re(`80[08]\d{7}|800\d{6}`),
+		premiumRate:         re(`9[018]\d{8}`),
+		voip:                re(`56\d{8}`),
+	},
+	"DE": {
+		callingCode:         "49",
+		internationalPrefix: "00",
+		nationalPrefix:      "0",
+		lengths:             []int{6, 7, 8, 9, 10, 11},
+		mobile:              re(`15[0-25-9]\d{8}|1(?:6[023]|7\d)\d{7,8}`),
+		fixedLine:           re(`[2-9]\d{5,10}`),
+		tollFree:            re(`800\d{7}`),
+		premiumRate:         re(`900\d{7}`),
+	},
+	"FR": {
+		callingCode:         "33",
+		internationalPrefix: "00",
+		nationalPrefix:      "0",
+		lengths:             []int{9},
+		mobile:              re(`[67]\d{8}`),
+		fixedLine:           re(`[1-5]\d{8}`),
+		tollFree:            re(`80[0-5]\d{6}`),
+		premiumRate:         re(`8[1-9]\d{7}`),
+		voip:                re(`9\d{8}`),
+	},
+	"TR": {
+		callingCode:         "90",
+		internationalPrefix: "00",
+		nationalPrefix:      "0",
+		lengths:             []int{10},
+		mobile:              re(`5(?:0[0-8]|[1-5]\d|6[01]|9[0-9])\d{7}`),
+		fixedLine:           re(`[2-4]\d{9}`),
+		tollFree:            re(`800\d{7}`),
+		premiumRate:         re(`900\d{7}`),
+	},
+	"AE": {
+		callingCode:         "971",
+		internationalPrefix: "00",
+		nationalPrefix:      "0",
+		lengths:             []int{8, 9},
+		mobile:              re(`5[024-68]\d{7}`),
+		fixedLine:           re(`[2-4679][2-8]\d{6}`),
+		tollFree:            re(`800\d{5,6}`),
+		premiumRate:         re(`900\d{6}`),
+	},
+	"IN": {
+		callingCode:         "91",
+		internationalPrefix: "00",
+		nationalPrefix:      "0",
+		lengths:             []int{10},
+		mobile:              re(`[6-9]\d{9}`),
+		fixedLine:           re(`[1-5]\d{9}`),
+		tollFree:            re(`1800\d{6}`),
+	},
+}
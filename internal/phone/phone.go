@@ -0,0 +1,198 @@
+// Package phone parses phone numbers written in international or national
+// format and normalizes them to E.164.
+package phone
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrInvalid   = errors.New("invalid_phone")
+	ErrNotMobile = errors.New("phone_not_mobile")
+)
+
+type Type int
+
+const (
+	TypeUnknown Type = iota
+	TypeMobile
+	TypeFixedLine
+	TypeFixedOrMobile
+	TypeTollFree
+	TypePremiumRate
+	TypeVoIP
+)
+
+func (t Type) String() string {
+	switch t {
+	case TypeMobile:
+		return "mobile"
+	case TypeFixedLine:
+		return "fixed_line"
+	case TypeFixedOrMobile:
+		return "fixed_line_or_mobile"
+	case TypeTollFree:
+		return "toll_free"
+	case TypePremiumRate:
+		return "premium_rate"
+	case TypeVoIP:
+		return "voip"
+	}
+	return "unknown"
+}
+
+// Number is a parsed phone number. National is the national significant
+// number: no calling code and no national prefix.
+type Number struct {
+	CallingCode string
+	National    string
+	Region      string
+	Type        Type
+}
+
+func (n Number) E164() string {
+	return "+" + n.CallingCode + n.National
+}
+
+// CanReceiveSMS reports whether the number may be a mobile. Numbers from
+// regions without metadata are given the benefit of the doubt.
+func (n Number) CanReceiveSMS() bool {
+	return n.Type == TypeMobile || n.Type == TypeFixedOrMobile || n.Type == TypeUnknown
+}
+
+// Normalize parses raw and returns it in E.164, rejecting numbers that are
+// not mobiles.
+func Normalize(raw, defaultRegion string) (string, error) {
+	n, err := Parse(raw, defaultRegion)
+	if err != nil {
+		return "", err
+	}
+	if !n.CanReceiveSMS() {
+		return "", ErrNotMobile
+	}
+	return n.E164(), nil
+}
+
+// Parse reads "+98 912 123 4567", "0098 912...", or, given defaultRegion
+// "IR", "0912 123 4567". Spaces, dots, dashes, slashes and parentheses are
+// ignored, and Arabic-Indic and Persian digits are accepted.
+func Parse(raw, defaultRegion string) (Number, error) {
+	digits, plus, ok := clean(raw)
+	if !ok || digits == "" {
+		return Number{}, ErrInvalid
+	}
+	defaultRegion = strings.ToUpper(defaultRegion)
+	def, hasDef := metadata[defaultRegion]
+
+	switch {
+	case plus:
+		return parseInternational(digits, defaultRegion)
+	case hasDef && strings.HasPrefix(digits, def.internationalPrefix):
+		return parseInternational(digits[len(def.internationalPrefix):], defaultRegion)
+	case !hasDef && strings.HasPrefix(digits, "00"):
+		return parseInternational(digits[2:], defaultRegion)
+	case !hasDef:
+		return Number{}, ErrInvalid
+	}
+
+	if n, err := parseNational(def.callingCode, digits, defaultRegion); err == nil {
+		return n, nil
+	}
+	// "989121234567": the calling code without a plus.
+	if rest, ok := strings.CutPrefix(digits, def.callingCode); ok {
+		return parseNational(def.callingCode, rest, defaultRegion)
+	}
+	return Number{}, ErrInvalid
+}
+
+func parseInternational(digits, defaultRegion string) (Number, error) {
+	cc, ok := CallingCode(digits)
+	if !ok {
+		return Number{}, ErrInvalid
+	}
+	return parseNational(cc, digits[len(cc):], defaultRegion)
+}
+
+// parseNational validates nsn, optionally still carrying its national prefix,
+// as a number under calling code cc. Regions sharing cc also share its
+// numbering plan, so the default or main region's metadata validates the
+// number and regionOf then says which region it belongs to.
+func parseNational(cc, nsn, defaultRegion string) (Number, error) {
+	region := defaultRegion
+	if md, ok := metadata[region]; !ok || md.callingCode != cc {
+		region = callingCodes[cc][0]
+	}
+
+	md, ok := metadata[region]
+	if !ok {
+		if len(nsn) < 4 || len(cc)+len(nsn) > 15 {
+			return Number{}, ErrInvalid
+		}
+		return Number{CallingCode: cc, National: nsn, Region: regionOf(cc, nsn)}, nil
+	}
+
+	candidates := []string{nsn}
+	if rest, ok := strings.CutPrefix(nsn, md.nationalPrefix); ok && md.nationalPrefix != "" {
+		candidates = append(candidates, rest)
+	}
+	for _, c := range candidates {
+		if !slices.Contains(md.lengths, len(c)) {
+			continue
+		}
+		if t := md.numberType(c); t != TypeUnknown {
+			return Number{CallingCode: cc, National: c, Region: regionOf(cc, c), Type: t}, nil
+		}
+	}
+	return Number{}, ErrInvalid
+}
+
+func (md regionMetadata) numberType(nsn string) Type {
+	checks := []struct {
+		t  Type
+		re *regexp.Regexp
+	}{
+		{TypeTollFree, md.tollFree},
+		{TypePremiumRate, md.premiumRate},
+		{TypeVoIP, md.voip},
+		{TypeMobile, md.mobile},
+		{TypeFixedOrMobile, md.fixedOrMobile},
+		{TypeFixedLine, md.fixedLine},
+	}
+	for _, c := range checks {
+		if c.re != nil && c.re.MatchString(nsn) {
+			return c.t
+		}
+	}
+	return TypeUnknown
+}
+
+// clean strips formatting from raw and maps digits to ASCII. plus reports a
+// leading "+"; ok is false if anything else is left over.
+func clean(raw string) (digits string, plus bool, ok bool) {
+	raw = strings.TrimSpace(raw)
+	raw, plus = strings.CutPrefix(raw, "+")
+	if !plus {
+		raw, plus = strings.CutPrefix(raw, "＋")
+	}
+
+	var b strings.Builder
+	for _, r := range raw {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case r >= '٠' && r <= '٩': // Arabic-Indic
+			b.WriteRune('0' + r - '٠')
+		case r >= '۰' && r <= '۹': // Persian
+			b.WriteRune('0' + r - '۰')
+		case r >= '０' && r <= '９': // fullwidth
+			b.WriteRune('0' + r - '０')
+		case strings.ContainsRune(" -.()/\u00a0", r):
+		default:
+			return "", false, false
+		}
+	}
+	return b.String(), plus, true
+}
@@ -0,0 +1,86 @@
+package phone
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name          string
+		raw           string
+		defaultRegion string
+		want          string
+		wantRegion    string
+		wantType      Type
+		wantErr       error
+	}{
+		{name: "e164", raw: "+989121234567", want: "+989121234567", wantRegion: "IR", wantType: TypeMobile},
+		{name: "spaced international", raw: "+98 912 123 4567", want: "+989121234567", wantRegion: "IR", wantType: TypeMobile},
+		{name: "international prefix", raw: "0098 912 123 4567", defaultRegion: "IR", want: "+989121234567", wantRegion: "IR", wantType: TypeMobile},
+		{name: "double zero without region", raw: "00989121234567", want: "+989121234567", wantRegion: "IR", wantType: TypeMobile},
+		{name: "national with prefix", raw: "0912-123-4567", defaultRegion: "IR", want: "+989121234567", wantRegion: "IR", wantType: TypeMobile},
+		{name: "national without prefix", raw: "9121234567", defaultRegion: "ir", want: "+989121234567", wantRegion: "IR", wantType: TypeMobile},
+		{name: "calling code without plus", raw: "989121234567", defaultRegion: "IR", want: "+989121234567", wantRegion: "IR", wantType: TypeMobile},
+		{name: "national prefix after calling code", raw: "+98 (0) 912 123 4567", want: "+989121234567", wantRegion: "IR", wantType: TypeMobile},
+		{name: "persian digits", raw: "۰۹۱۲۱۲۳۴۵۶۷", defaultRegion: "IR", want: "+989121234567", wantRegion: "IR", wantType: TypeMobile},
+		{name: "arabic-indic digits", raw: "+٩٧١٥٠١٢٣٤٥٦٧", want: "+971501234567", wantRegion: "AE", wantType: TypeMobile},
+		{name: "iran landline", raw: "021 1234 5678", defaultRegion: "IR", want: "+982112345678", wantRegion: "IR", wantType: TypeFixedLine},
+		{name: "nanp", raw: "(555) 123-4567", defaultRegion: "US", want: "+15551234567", wantRegion: "US", wantType: TypeFixedOrMobile},
+		{name: "nanp with trunk prefix", raw: "1 555 123 4567", defaultRegion: "US", want: "+15551234567", wantRegion: "US", wantType: TypeFixedOrMobile},
+		{name: "nanp international prefix", raw: "011 44 7400 123456", defaultRegion: "US", want: "+447400123456", wantRegion: "GB", wantType: TypeMobile},
+		{name: "canada keeps its default region", raw: "416 555 0123", defaultRegion: "CA", want: "+14165550123", wantRegion: "CA", wantType: TypeFixedOrMobile},
+		{name: "us toll free", raw: "+1 800 555 0199", want: "+18005550199", wantRegion: "US", wantType: TypeTollFree},
+		{name: "uk mobile national", raw: "07400 123456", defaultRegion: "GB", want: "+447400123456", wantRegion: "GB", wantType: TypeMobile},
+		{name: "uk premium rate", raw: "+44 909 876 5432", want: "+449098765432", wantRegion: "GB", wantType: TypePremiumRate},
+		{name: "jamaica under nanp", raw: "+1 876 555 0123", want: "+18765550123", wantRegion: "JM", wantType: TypeFixedOrMobile},
+		{name: "nanp default region does not override area code", raw: "(876) 555-0123", defaultRegion: "US", want: "+18765550123", wantRegion: "JM", wantType: TypeFixedOrMobile},
+		{name: "isle of man under +44", raw: "+44 1624 123456", want: "+441624123456", wantRegion: "IM", wantType: TypeFixedLine},
+		{name: "kazakhstan under +7", raw: "+7 701 123 4567", want: "+77011234567", wantRegion: "KZ", wantType: TypeUnknown},
+		{name: "region without metadata", raw: "+234 803 123 4567", want: "+2348031234567", wantRegion: "NG", wantType: TypeUnknown},
+		{name: "national number needs a region", raw: "09121234567", wantErr: ErrInvalid},
+		{name: "unknown calling code", raw: "+8001234567", wantErr: ErrInvalid},
+		{name: "too short for region", raw: "+98912123", wantErr: ErrInvalid},
+		{name: "too long", raw: "+2348031234567890123", wantErr: ErrInvalid},
+		{name: "letters", raw: "+98912abc4567", wantErr: ErrInvalid},
+		{name: "empty", raw: "  ", wantErr: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := Parse(tt.raw, tt.defaultRegion)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Parse(%q) error = %v, want %v", tt.raw, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if n.E164() != tt.want || n.Region != tt.wantRegion || n.Type != tt.wantType {
+				t.Errorf("Parse(%q) = %s %s %s, want %s %s %s", tt.raw, n.E164(), n.Region, n.Type, tt.want, tt.wantRegion, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		raw     string
+		want    string
+		wantErr error
+	}{
+		{raw: "0912 123 4567", want: "+989121234567"},
+		{raw: "+15551234567", want: "+15551234567"},
+		{raw: "+2348031234567", want: "+2348031234567"},
+		{raw: "021 1234 5678", wantErr: ErrNotMobile},
+		{raw: "+18005550199", wantErr: ErrNotMobile},
+		{raw: "+449098765432", wantErr: ErrNotMobile},
+		{raw: "12", wantErr: ErrInvalid},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize(tt.raw, "IR")
+		if !errors.Is(err, tt.wantErr) || got != tt.want {
+			t.Errorf("Normalize(%q) = %q, %v, want %q, %v", tt.raw, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
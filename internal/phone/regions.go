@@ -0,0 +1,303 @@
+package phone
+
+import "strings"
+
+// callingCodes maps ITU country calling codes to the regions (ISO 3166-1
+// alpha-2) that use them, main region first. Codes are prefix-free, so at
+// most one matches the start of a number.
+var callingCodes = map[string][]string{
+	"1":   {"US", "CA", "PR", "DO", "JM", "TT", "BS", "BB", "AG", "DM", "GD", "KN", "LC", "VC", "BM", "KY", "TC", "VG", "VI", "AI", "MS", "GU", "MP", "AS", "SX"},
+	"7":   {"RU", "KZ"},
+	"20":  {"EG"},
+	"27":  {"ZA"},
+	"30":  {"GR"},
+	"31":  {"NL"},
+	"32":  {"BE"},
+	"33":  {"FR"},
+	"34":  {"ES"},
+	"36":  {"HU"},
+	"39":  {"IT", "VA"},
+	"40":  {"RO"},
+	"41":  {"CH"},
+	"43":  {"AT"},
+	"44":  {"GB", "GG", "IM", "JE"},
+	"45":  {"DK"},
+	"46":  {"SE"},
+	"47":  {"NO", "SJ"},
+	"48":  {"PL"},
+	"49":  {"DE"},
+	"51":  {"PE"},
+	"52":  {"MX"},
+	"53":  {"CU"},
+	"54":  {"AR"},
+	"55":  {"BR"},
+	"56":  {"CL"},
+	"57":  {"CO"},
+	"58":  {"VE"},
+	"60":  {"MY"},
+	"61":  {"AU", "CC", "CX"},
+	"62":  {"ID"},
+	"63":  {"PH"},
+	"64":  {"NZ"},
+	"65":  {"SG"},
+	"66":  {"TH"},
+	"81":  {"JP"},
+	"82":  {"KR"},
+	"84":  {"VN"},
+	"86":  {"CN"},
+	"90":  {"TR"},
+	"91":  {"IN"},
+	"92":  {"PK"},
+	"93":  {"AF"},
+	"94":  {"LK"},
+	"95":  {"MM"},
+	"98":  {"IR"},
+	"211": {"SS"},
+	"212": {"MA", "EH"},
+	"213": {"DZ"},
+	"216": {"TN"},
+	"218": {"LY"},
+	"220": {"GM"},
+	"221": {"SN"},
+	"222": {"MR"},
+	"223": {"ML"},
+	"224": {"GN"},
+	"225": {"CI"},
+	"226": {"BF"},
+	"227": {"NE"},
+	"228": {"TG"},
+	"229": {"BJ"},
+	"230": {"MU"},
+	"231": {"LR"},
+	"232": {"SL"},
+	"233": {"GH"},
+	"234": {"NG"},
+	"235": {"TD"},
+	"236": {"CF"},
+	"237": {"CM"},
+	"238": {"CV"},
+	"239": {"ST"},
+	"240": {"GQ"},
+	"241": {"GA"},
+	"242": {"CG"},
+	"243": {"CD"},
+	"244": {"AO"},
+	"245": {"GW"},
+	"246": {"IO"},
+	"248": {"SC"},
+	"249": {"SD"},
+	"250": {"RW"},
+	"251": {"ET"},
+	"252": {"SO"},
+	"253": {"DJ"},
+	"254": {"KE"},
+	"255": {"TZ"},
+	"256": {"UG"},
+	"257": {"BI"},
+	"258": {"MZ"},
+	"260": {"ZM"},
+	"261": {"MG"},
+	"262": {"RE", "YT"},
+	"263": {"ZW"},
+	"264": {"NA"},
+	"265": {"MW"},
+	"266": {"LS"},
+	"267": {"BW"},
+	"268": {"SZ"},
+	"269": {"KM"},
+	"290": {"SH"},
+	"291": {"ER"},
+	"297": {"AW"},
+	"298": {"FO"},
+	"299": {"GL"},
+	"350": {"GI"},
+	"351": {"PT"},
+	"352": {"LU"},
+	"353": {"IE"},
+	"354": {"IS"},
+	"355": {"AL"},
+	"356": {"MT"},
+	"357": {"CY"},
+	"358": {"FI", "AX"},
+	"359": {"BG"},
+	"370": {"LT"},
+	"371": {"LV"},
+	"372": {"EE"},
+	"373": {"MD"},
+	"374": {"AM"},
+	"375": {"BY"},
+	"376": {"AD"},
+	"377": {"MC"},
+	"378": {"SM"},
+	"380": {"UA"},
+	"381": {"RS"},
+	"382": {"ME"},
+	"383": {"XK"},
+	"385": {"HR"},
+	"386": {"SI"},
+	"387": {"BA"},
+	"389": {"MK"},
+	"420": {"CZ"},
+	"421": {"SK"},
+	"423": {"LI"},
+	"500": {"FK"},
+	"501": {"BZ"},
+	"502": {"GT"},
+	"503": {"SV"},
+	"504": {"HN"},
+	"505": {"NI"},
+	"506": {"CR"},
+	"507": {"PA"},
+	"508": {"PM"},
+	"509": {"HT"},
+	"590": {"GP", "BL", "MF"},
+	"591": {"BO"},
+	"592": {"GY"},
+	"593": {"EC"},
+	"594": {"GF"},
+	"595": {"PY"},
+	"596": {"MQ"},
+	"597": {"SR"},
+	"598": {"UY"},
+	"599": {"CW", "BQ"},
+	"670": {"TL"},
+	"672": {"NF"},
+	"673": {"BN"},
+	"674": {"NR"},
+	"675": {"PG"},
+	"676": {"TO"},
+	"677": {"SB"},
+	"678": {"VU"},
+	"679": {"FJ"},
+	"680": {"PW"},
+	"681": {"WF"},
+	"682": {"CK"},
+	"683": {"NU"},
+	"685": {"WS"},
+	"686": {"KI"},
+	"687": {"NC"},
+	"688": {"TV"},
+	"689": {"PF"},
+	"690": {"TK"},
+	"691": {"FM"},
+	"692": {"MH"},
+	"850": {"KP"},
+	"852": {"HK"},
+	"853": {"MO"},
+	"855": {"KH"},
+	"856": {"LA"},
+	"880": {"BD"},
+	"886": {"TW"},
+	"960": {"MV"},
+	"961": {"LB"},
+	"962": {"JO"},
+	"963": {"SY"},
+	"964": {"IQ"},
+	"965": {"KW"},
+	"966": {"SA"},
+	"967": {"YE"},
+	"968": {"OM"},
+	"970": {"PS"},
+	"971": {"AE"},
+	"972": {"IL"},
+	"973": {"BH"},
+	"974": {"QA"},
+	"975": {"BT"},
+	"976": {"MN"},
+	"977": {"NP"},
+	"992": {"TJ"},
+	"993": {"TM"},
+	"994": {"AZ"},
+	"995": {"GE"},
+	"996": {"KG"},
+	"998": {"UZ"},
+}
+
+// CallingCode returns the country calling code of an E.164 number.
+func CallingCode(e164 string) (string, bool) {
+	digits := strings.TrimPrefix(e164, "+")
+	for n := 1; n <= 3 && n <= len(digits); n++ {
+		if _, ok := callingCodes[digits[:n]]; ok {
+			return digits[:n], true
+		}
+	}
+	return "", false
+}
+
+// sharedCodeRegions tells apart the regions of a shared calling code by the
+// start of the national significant number. The longest matching prefix
+// wins; numbers matching none belong to the code's main region.
+var sharedCodeRegions = map[string]map[string]string{
+	"1":  nanpAreaCodes,
+	"7":  {"7": "KZ", "33622": "KZ"},
+	"39": {"06698": "VA"},
+	"44": {
+		"1481": "GG", "7781": "GG", "7839": "GG", "79111": "GG", "79117": "GG",
+		"1624": "IM", "74576": "IM", "7524": "IM", "7624": "IM", "7924": "IM",
+		"1534": "JE", "7509": "JE", "77003": "JE", "77007": "JE", "77008": "JE", "7797": "JE", "7829": "JE", "7937": "JE",
+	},
+	"47":  {"79": "SJ"},
+	"61":  {"89162": "CC", "89164": "CX"},
+	"212": {"5288": "EH", "5289": "EH"},
+	"262": {"269": "YT", "639": "YT"},
+	"358": {"18": "AX"},
+	"590": {"59027": "BL", "59087": "MF"},
+	"599": {"3": "BQ", "4": "BQ", "7": "BQ"},
+}
+
+// nanpAreaCodes are the +1 area codes outside the US. Non-geographic codes
+// such as 800 and 900 stay with the US.
+var nanpAreaCodes = map[string]string{
+	"204": "CA", "226": "CA", "236": "CA", "249": "CA", "250": "CA", "257": "CA", "263": "CA", "289": "CA",
+	"306": "CA", "343": "CA", "354": "CA", "365": "CA", "367": "CA", "368": "CA", "382": "CA", "387": "CA",
+	"403": "CA", "416": "CA", "418": "CA", "428": "CA", "431": "CA", "437": "CA", "438": "CA", "450": "CA",
+	"460": "CA", "468": "CA", "474": "CA", "506": "CA", "514": "CA", "519": "CA", "548": "CA", "579": "CA",
+	"581": "CA", "584": "CA", "587": "CA", "604": "CA", "613": "CA", "639": "CA", "647": "CA", "672": "CA",
+	"683": "CA", "705": "CA", "709": "CA", "742": "CA", "753": "CA", "778": "CA", "780": "CA", "782": "CA",
+	"807": "CA", "819": "CA", "825": "CA", "867": "CA", "873": "CA", "879": "CA", "902": "CA", "905": "CA",
+	"942": "CA",
+	"787": "PR", "939": "PR",
+	"809": "DO", "829": "DO", "849": "DO",
+	"658": "JM", "876": "JM",
+	"868": "TT",
+	"242": "BS",
+	"246": "BB",
+	"268": "AG",
+	"767": "DM",
+	"473": "GD",
+	"869": "KN",
+	"758": "LC",
+	"784": "VC",
+	"441": "BM",
+	"345": "KY",
+	"649": "TC",
+	"284": "VG",
+	"340": "VI",
+	"264": "AI",
+	"664": "MS",
+	"671": "GU",
+	"670": "MP",
+	"684": "AS",
+	"721": "SX",
+}
+
+// Region returns the region of an E.164 number, e.g. "IR" for
+// "+989121234567" and "JM" for "+18765550123".
+func Region(e164 string) (string, bool) {
+	cc, ok := CallingCode(e164)
+	if !ok {
+		return "", false
+	}
+	return regionOf(cc, strings.TrimPrefix(e164, "+")[len(cc):]), true
+}
+
+// regionOf resolves a national significant number under calling code cc.
+func regionOf(cc, nsn string) string {
+	prefixes := sharedCodeRegions[cc]
+	for n := min(len(nsn), 5); n > 0; n-- {
+		if region, ok := prefixes[nsn[:n]]; ok {
+			return region
+		}
+	}
+	return callingCodes[cc][0]
+}
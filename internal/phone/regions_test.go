@@ -0,0 +1,52 @@
+package phone
+
+import "testing"
+
+func TestRegion(t *testing.T) {
+	tests := []struct {
+		number     string
+		wantCode   string
+		wantRegion string
+		wantOK     bool
+	}{
+		{number: "+15551234567", wantCode: "1", wantRegion: "US", wantOK: true},
+		{number: "+989121234567", wantCode: "98", wantRegion: "IR", wantOK: true},
+		{number: "+447400123456", wantCode: "44", wantRegion: "GB", wantOK: true},
+		{number: "+16135550123", wantCode: "1", wantRegion: "CA", wantOK: true},
+		{number: "+18765550123", wantCode: "1", wantRegion: "JM", wantOK: true},
+		{number: "+18095550123", wantCode: "1", wantRegion: "DO", wantOK: true},
+		{number: "+19005550123", wantCode: "1", wantRegion: "US", wantOK: true},
+		{number: "+74951234567", wantCode: "7", wantRegion: "RU", wantOK: true},
+		{number: "+77011234567", wantCode: "7", wantRegion: "KZ", wantOK: true},
+		{number: "+441624123456", wantCode: "44", wantRegion: "IM", wantOK: true},
+		{number: "+447911123456", wantCode: "44", wantRegion: "GG", wantOK: true},
+		{number: "+441534123456", wantCode: "44", wantRegion: "JE", wantOK: true},
+		{number: "+971501234567", wantCode: "971", wantRegion: "AE", wantOK: true},
+		{number: "+2250712345678", wantCode: "225", wantRegion: "CI", wantOK: true},
+		{number: "+8001234567", wantOK: false},
+		{number: "", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.number, func(t *testing.T) {
+			code, ok := CallingCode(tt.number)
+			if ok != tt.wantOK || code != tt.wantCode {
+				t.Errorf("CallingCode(%q) = %q, %v, want %q, %v", tt.number, code, ok, tt.wantCode, tt.wantOK)
+			}
+			region, ok := Region(tt.number)
+			if ok != tt.wantOK || region != tt.wantRegion {
+				t.Errorf("Region(%q) = %q, %v, want %q, %v", tt.number, region, ok, tt.wantRegion, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestCallingCodesArePrefixFree(t *testing.T) {
+	for a := range callingCodes {
+		for b := range callingCodes {
+			if a != b && len(a) < len(b) && b[:len(a)] == a {
+				t.Errorf("calling code %s is a prefix of %s", a, b)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	auditdomain "dekamond/internal/domain/audit"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrInvalidActor   = errors.New("invalid_actor")
+	ErrInvalidOutcome = errors.New("invalid_outcome")
+	ErrInvalidRange   = errors.New("invalid_range")
+)
+
+type AuditUsecase struct {
+	events   auditdomain.Repository
+	phoneKey []byte
+}
+
+// New takes the key phone numbers are hashed with in the log, so they can be
+// searched for by number.
+func New(events auditdomain.Repository, phoneKey string) *AuditUsecase {
+	return &AuditUsecase{events: events, phoneKey: []byte(phoneKey)}
+}
+
+// Query selects audit events, newest first. From is inclusive and To
+// exclusive. Before takes NextBefore from a previous Page. A Target starting
+// with "+" is taken as a phone number.
+type Query struct {
+	ActorID string
+	Action  string
+	Target  string
+	Outcome string
+	From    *time.Time
+	To      *time.Time
+	Before  int64
+	Limit   int
+}
+
+// Page is one page of events. NextBefore is zero on the last page.
+type Page struct {
+	Items      []auditdomain.Event `json:"items"`
+	Limit      int                 `json:"limit"`
+	NextBefore int64               `json:"next_before,omitempty"`
+}
+
+func (a *AuditUsecase) List(ctx context.Context, q Query) (*Page, error) {
+	if q.ActorID != "" {
+		if _, err := uuid.Parse(q.ActorID); err != nil {
+			return nil, ErrInvalidActor
+		}
+	}
+	switch auditdomain.Outcome(q.Outcome) {
+	case "", auditdomain.OutcomeSuccess, auditdomain.OutcomeFailure:
+	default:
+		return nil, ErrInvalidOutcome
+	}
+	if q.From != nil && q.To != nil && !q.From.Before(*q.To) {
+		return nil, ErrInvalidRange
+	}
+	if q.Limit < 1 || q.Limit > 200 {
+		q.Limit = 50
+	}
+	if strings.HasPrefix(q.Target, "+") {
+		q.Target = auditdomain.PhoneTarget(a.phoneKey, q.Target)
+	}
+
+	events, err := a.events.List(ctx, auditdomain.Filter{
+		ActorID:  q.ActorID,
+		Action:   auditdomain.Action(q.Action),
+		Target:   q.Target,
+		Outcome:  auditdomain.Outcome(q.Outcome),
+		From:     q.From,
+		To:       q.To,
+		BeforeID: q.Before,
+		Limit:    q.Limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Items: events, Limit: q.Limit}
+	if len(events) > q.Limit {
+		page.Items = events[:q.Limit]
+		page.NextBefore = page.Items[q.Limit-1].ID
+	}
+	if page.Items == nil {
+		page.Items = []auditdomain.Event{}
+	}
+	return page, nil
+}
@@ -0,0 +1,161 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	auditdomain "dekamond/internal/domain/audit"
+)
+
+type mockAuditRepository struct {
+	events     []auditdomain.Event // newest first
+	lastFilter auditdomain.Filter
+}
+
+func (m *mockAuditRepository) Insert(ctx context.Context, events []auditdomain.Event) error {
+	return nil
+}
+
+func (m *mockAuditRepository) List(ctx context.Context, f auditdomain.Filter) ([]auditdomain.Event, error) {
+	m.lastFilter = f
+	var out []auditdomain.Event
+	for _, e := range m.events {
+		if f.ActorID != "" && e.ActorID != f.ActorID {
+			continue
+		}
+		if f.BeforeID > 0 && e.ID >= f.BeforeID {
+			continue
+		}
+		out = append(out, e)
+		if len(out) == f.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func TestList(t *testing.T) {
+	const actor = "7b0f6c1e-8d2a-4f5e-9c3b-1a2b3c4d5e6f"
+	repo := &mockAuditRepository{}
+	for id := int64(5); id >= 1; id-- {
+		repo.events = append(repo.events, auditdomain.Event{ID: id, ActorID: actor, Action: auditdomain.ActionUserViewed})
+	}
+	repo.events = append(repo.events, auditdomain.Event{ID: 0, Action: auditdomain.ActionOTPRequested})
+	uc := New(repo, "test-key")
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	tests := []struct {
+		name           string
+		query          Query
+		wantIDs        []int64
+		wantNextBefore int64
+		wantErr        error
+	}{
+		{
+			name:           "first page",
+			query:          Query{ActorID: actor, Limit: 2},
+			wantIDs:        []int64{5, 4},
+			wantNextBefore: 4,
+		},
+		{
+			name:           "next page",
+			query:          Query{ActorID: actor, Before: 4, Limit: 2},
+			wantIDs:        []int64{3, 2},
+			wantNextBefore: 2,
+		},
+		{
+			name:    "last page",
+			query:   Query{ActorID: actor, Before: 2, Limit: 2},
+			wantIDs: []int64{1},
+		},
+		{
+			name:    "time range",
+			query:   Query{ActorID: actor, From: &from, To: &to, Limit: 10},
+			wantIDs: []int64{5, 4, 3, 2, 1},
+		},
+		{
+			name:    "actor must be a user id",
+			query:   Query{ActorID: "admin"},
+			wantErr: ErrInvalidActor,
+		},
+		{
+			name:    "unknown outcome",
+			query:   Query{Outcome: "maybe"},
+			wantErr: ErrInvalidOutcome,
+		},
+		{
+			name:    "empty range",
+			query:   Query{From: &to, To: &from},
+			wantErr: ErrInvalidRange,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := uc.List(context.Background(), tt.query)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("List: %v", err)
+			}
+			var ids []int64
+			for _, e := range page.Items {
+				ids = append(ids, e.ID)
+			}
+			if len(ids) != len(tt.wantIDs) {
+				t.Fatalf("ids = %v, want %v", ids, tt.wantIDs)
+			}
+			for i := range ids {
+				if ids[i] != tt.wantIDs[i] {
+					t.Fatalf("ids = %v, want %v", ids, tt.wantIDs)
+				}
+			}
+			if page.NextBefore != tt.wantNextBefore {
+				t.Errorf("NextBefore = %d, want %d", page.NextBefore, tt.wantNextBefore)
+			}
+			if tt.query.From != nil && (repo.lastFilter.From != tt.query.From || repo.lastFilter.To != tt.query.To) {
+				t.Errorf("time range not passed to the repository: %+v", repo.lastFilter)
+			}
+		})
+	}
+}
+
+func TestListDefaultsLimit(t *testing.T) {
+	repo := &mockAuditRepository{}
+	page, err := New(repo, "test-key").List(context.Background(), Query{Limit: 10000})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if page.Limit != 50 || repo.lastFilter.Limit != 51 {
+		t.Errorf("Limit = %d, repository limit = %d, want 50 and 51", page.Limit, repo.lastFilter.Limit)
+	}
+	if page.Items == nil {
+		t.Error("Items is nil, want an empty list")
+	}
+}
+
+func TestListHashesPhoneTargets(t *testing.T) {
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "+15551234567", want: auditdomain.PhoneTarget([]byte("test-key"), "+15551234567")},
+		{target: "7b0f6c1e-8d2a-4f5e-9c3b-1a2b3c4d5e6f", want: "7b0f6c1e-8d2a-4f5e-9c3b-1a2b3c4d5e6f"},
+	}
+	for _, tt := range tests {
+		repo := &mockAuditRepository{}
+		if _, err := New(repo, "test-key").List(context.Background(), Query{Target: tt.target}); err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		if repo.lastFilter.Target != tt.want {
+			t.Errorf("target %q: filter = %q, want %q", tt.target, repo.lastFilter.Target, tt.want)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package auth
+
+import (
+	"context"
+
+	auditdomain "dekamond/internal/domain/audit"
+)
+
+// record adds e to the audit log, marking it failed when err is not nil.
+func (auc *AuthUsecase) record(ctx context.Context, e auditdomain.Event, err error) {
+	if auc.audit != nil {
+		auc.audit.Record(ctx, auditdomain.Finish(e, err))
+	}
+}
+
+// phoneTarget is the audit target for a phone number no user is known for.
+func (auc *AuthUsecase) phoneTarget(phone string) string {
+	return auditdomain.PhoneTarget(auc.auditPhoneKey, phone)
+}
@@ -0,0 +1,143 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	auditdomain "dekamond/internal/domain/audit"
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/infra/jwtkeys"
+)
+
+type mockRecorder struct {
+	events []auditdomain.Event
+}
+
+func (m *mockRecorder) Record(ctx context.Context, e auditdomain.Event) {
+	m.events = append(m.events, e)
+}
+
+func (m *mockRecorder) actions() []auditdomain.Action {
+	var out []auditdomain.Action
+	for _, e := range m.events {
+		out = append(out, e.Action)
+	}
+	return out
+}
+
+func TestLoginIsAudited(t *testing.T) {
+	const phone = "+15551234567"
+
+	tests := []struct {
+		name        string
+		code        string
+		wantActions []auditdomain.Action
+		wantOutcome auditdomain.Outcome
+		wantTarget  string
+		wantError   string
+	}{
+		{
+			name:        "first login creates the user",
+			code:        "123456",
+			wantActions: []auditdomain.Action{auditdomain.ActionUserCreated, auditdomain.ActionLogin},
+			wantOutcome: auditdomain.OutcomeSuccess,
+			wantTarget:  "new-user-id",
+		},
+		{
+			name:        "wrong code",
+			code:        "000000",
+			wantActions: []auditdomain.Action{auditdomain.ActionLogin},
+			wantOutcome: auditdomain.OutcomeFailure,
+			wantTarget:  auditdomain.PhoneTarget([]byte("audit-key"), phone),
+			wantError:   ErrInvalidOTP.Error(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newMockCacheStore()
+			cache.store[otpKey(phone)] = otpRecordFor(nil, phone, "123456")
+			recorder := &mockRecorder{}
+			auc := &AuthUsecase{
+				users:         &mockUserRepositoryWithStorage{users: map[string]*userdomain.User{}},
+				refreshTokens: newMockRefreshTokenRepository(),
+				sessions:      newMockSessionRepository(),
+				cache:         cache,
+				policy:        testOTPPolicy,
+				auditPhoneKey: []byte("audit-key"),
+				keys:          jwtkeys.NewHMAC([]byte("test-secret")),
+				tokenTTL:      time.Hour,
+				refreshTTL:    24 * time.Hour,
+				audit:         recorder,
+			}
+
+			_, _, _ = auc.VerifyOTPAndIssueToken(context.Background(), phone, tt.code, "")
+
+			got := recorder.actions()
+			if len(got) != len(tt.wantActions) {
+				t.Fatalf("recorded %v, want %v", got, tt.wantActions)
+			}
+			for i := range got {
+				if got[i] != tt.wantActions[i] {
+					t.Fatalf("recorded %v, want %v", got, tt.wantActions)
+				}
+			}
+			login := recorder.events[len(recorder.events)-1]
+			if login.Outcome != tt.wantOutcome || login.Target != tt.wantTarget {
+				t.Errorf("login event = %+v, want outcome %s and target %s", login, tt.wantOutcome, tt.wantTarget)
+			}
+			if login.Details["error"] != tt.wantError {
+				t.Errorf("error detail = %q, want %q", login.Details["error"], tt.wantError)
+			}
+		})
+	}
+}
+
+func TestModerationIsAudited(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newModerationTestUsecase()
+	recorder := &mockRecorder{}
+	auc.audit = recorder
+
+	until := time.Now().Add(time.Hour)
+	if _, err := auc.Suspend(ctx, testAdminID, "user-1", "spam", until); err != nil {
+		t.Fatalf("Suspend() unexpected error: %v", err)
+	}
+	_, _ = auc.Ban(ctx, "user-1", "user-1", "spam", nil)
+
+	if len(recorder.events) != 2 {
+		t.Fatalf("recorded %v, want two events", recorder.actions())
+	}
+	suspended, banned := recorder.events[0], recorder.events[1]
+	if suspended.Action != auditdomain.ActionUserSuspended || suspended.ActorID != testAdminID || suspended.Target != "user-1" ||
+		suspended.Outcome != auditdomain.OutcomeSuccess || suspended.Details["reason"] != "spam" || suspended.Details["until"] == "" {
+		t.Errorf("suspend event = %+v", suspended)
+	}
+	if banned.Action != auditdomain.ActionUserBanned || banned.Outcome != auditdomain.OutcomeFailure ||
+		banned.Details["error"] != ErrCannotModerateSelf.Error() {
+		t.Errorf("ban event = %+v", banned)
+	}
+}
+
+func TestFailedOTPDeliveryIsAuditedByCode(t *testing.T) {
+	recorder := &mockRecorder{}
+	auc := &AuthUsecase{
+		cache:         newMockCacheStore(),
+		sender:        &mockOTPSender{err: errors.New(`kavenegar: POST: dial tcp: secret-api-key refused`)},
+		policy:        testOTPPolicy,
+		auditPhoneKey: []byte("audit-key"),
+		audit:         recorder,
+	}
+
+	_, _ = auc.RequestOTP(context.Background(), "+15551234567", authdomain.OTPChannelSMS)
+
+	if len(recorder.events) != 1 {
+		t.Fatalf("recorded %v, want one event", recorder.actions())
+	}
+	if got := recorder.events[0].Details["error"]; got != ErrOTPDeliveryFailed.Error() {
+		t.Errorf("error detail = %q, want %q", got, ErrOTPDeliveryFailed.Error())
+	}
+}
@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/logging"
+	"dekamond/internal/metrics"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrSamePhone  = errors.New("same_phone")
+	ErrPhoneTaken = errors.New("phone_taken")
+)
+
+// phoneChange is a pending move to a new number, stored under
+// phonechange:<user id> until it is confirmed. Like otpRecord it keeps only
+// hashes of the codes. OldHash is empty when the old number need not confirm.
+type phoneChange struct {
+	OldPhone  string    `json:"old_phone"`
+	NewPhone  string    `json:"new_phone"`
+	NewHash   string    `json:"new_hash"`
+	OldHash   string    `json:"old_hash,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PhoneChangeStarted says how long the codes are valid and whether the
+// current number was sent one too.
+type PhoneChangeStarted struct {
+	OTPIssued
+	ConfirmOld bool
+}
+
+// StartPhoneChange sends a code to newPhone and, if configured, another to the
+// user's current number. Both numbers are held to the resend cooldown, as in
+// RequestOTP. Starting again replaces any pending change.
+func (auc *AuthUsecase) StartPhoneChange(ctx context.Context, userID, newPhone string, channel authdomain.OTPChannel) (*PhoneChangeStarted, error) {
+	user, err := auc.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Phone == newPhone {
+		return nil, ErrSamePhone
+	}
+	if !auc.countryAllowed(newPhone) {
+		return nil, ErrCountryNotAllowed
+	}
+	other, err := auc.users.GetByPhone(ctx, newPhone)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if err == nil && other != nil {
+		return nil, ErrPhoneTaken
+	}
+	if err := auc.checkResendCooldown(ctx, newPhone); err != nil {
+		return nil, err
+	}
+	if auc.confirmOld {
+		if err := auc.checkResendCooldown(ctx, user.Phone); err != nil {
+			return nil, err
+		}
+	}
+
+	now := time.Now()
+	pc := &phoneChange{OldPhone: user.Phone, NewPhone: newPhone, ExpiresAt: now.Add(auc.policy.TTL)}
+	type target struct {
+		phone string
+		hash  *string
+	}
+	targets := []target{{newPhone, &pc.NewHash}}
+	if auc.confirmOld {
+		targets = append(targets, target{user.Phone, &pc.OldHash})
+	}
+	msgs := make([]authdomain.OTPMessage, 0, len(targets))
+	for _, t := range targets {
+		code, err := generateOTP(auc.policy.Length, otpAlphabet(auc.policy.Alphabet))
+		if err != nil {
+			return nil, err
+		}
+		*t.hash = hashOTP(auc.otpPepper, t.phone, code)
+		msgs = append(msgs, authdomain.OTPMessage{Phone: t.phone, Code: code, Channel: channel, TTL: auc.policy.TTL})
+	}
+
+	if err := auc.storePhoneChange(ctx, userID, pc); err != nil {
+		return nil, err
+	}
+	_ = auc.cache.Delete(ctx, phoneChangeAttemptsKey(userID))
+	for _, msg := range msgs {
+		if err := auc.sender.Send(ctx, msg); err != nil {
+			_ = auc.cache.Delete(ctx, phoneChangeKey(userID))
+			logging.FromContext(ctx).Warn("otp delivery failed", "phone", msg.Phone, "channel", msg.Channel, "err", err)
+			return nil, sendError(err)
+		}
+		metrics.OTPRequested.WithLabelValues(string(msg.Channel)).Inc()
+	}
+
+	for _, t := range targets {
+		auc.startResendCooldown(ctx, t.phone, now)
+	}
+	return &PhoneChangeStarted{
+		OTPIssued:  OTPIssued{ExpiresIn: auc.policy.TTL, ResendAfter: auc.policy.ResendInterval},
+		ConfirmOld: auc.confirmOld,
+	}, nil
+}
+
+// ConfirmPhoneChange checks the codes sent by StartPhoneChange, moves the user
+// to the new number and revokes all of their sessions, so every device has
+// to log in again with the new number.
+func (auc *AuthUsecase) ConfirmPhoneChange(ctx context.Context, userID, newCode, oldCode string) (*userdomain.User, error) {
+	pc, err := auc.loadPhoneChange(ctx, userID)
+	if err != nil {
+		return nil, ErrInvalidOTP
+	}
+	if err := auc.checkOTPLock(ctx, pc.NewPhone); err != nil {
+		return nil, err
+	}
+
+	ok := (&otpRecord{Hash: pc.NewHash}).matches(auc.otpPepper, pc.NewPhone, newCode)
+	if pc.OldHash != "" {
+		ok = (&otpRecord{Hash: pc.OldHash}).matches(auc.otpPepper, pc.OldPhone, oldCode) && ok
+	}
+	if !ok {
+		return nil, auc.registerPhoneChangeFailure(ctx, userID)
+	}
+	// Codes are single use even if the update below fails.
+	_ = auc.cache.Delete(ctx, phoneChangeKey(userID))
+	_ = auc.cache.Delete(ctx, phoneChangeAttemptsKey(userID))
+
+	client := authdomain.ClientInfoFromContext(ctx)
+	user, err := auc.users.ChangePhone(ctx, userdomain.PhoneChange{
+		UserID:    userID,
+		OldPhone:  pc.OldPhone,
+		NewPhone:  pc.NewPhone,
+		IP:        client.IP,
+		UserAgent: client.UserAgent,
+		ChangedAt: time.Now(),
+	})
+	switch {
+	case errors.Is(err, userdomain.ErrPhoneTaken):
+		return nil, ErrPhoneTaken
+	case errors.Is(err, pgx.ErrNoRows):
+		// The number changed since the codes were sent.
+		return nil, ErrInvalidOTP
+	case err != nil:
+		return nil, fmt.Errorf("failed to change phone: %w", err)
+	}
+
+	if err := auc.revokeAllForUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// registerPhoneChangeFailure counts a wrong code against the IP and the
+// pending change, which is dropped once it runs out of attempts.
+func (auc *AuthUsecase) registerPhoneChangeFailure(ctx context.Context, userID string) error {
+	if err := auc.registerIPFailure(ctx); err != nil {
+		return err
+	}
+	attempts, err := auc.cache.Increment(ctx, phoneChangeAttemptsKey(userID), auc.policy.TTL)
+	if err != nil {
+		return err
+	}
+	if attempts < int64(auc.policy.MaxVerifyAttempts) {
+		return ErrInvalidOTP
+	}
+	_ = auc.cache.Delete(ctx, phoneChangeKey(userID))
+	_ = auc.cache.Delete(ctx, phoneChangeAttemptsKey(userID))
+	return ErrTooManyAttempts
+}
+
+func (auc *AuthUsecase) storePhoneChange(ctx context.Context, userID string, pc *phoneChange) error {
+	b, err := json.Marshal(pc)
+	if err != nil {
+		return err
+	}
+	return auc.cache.Set(ctx, phoneChangeKey(userID), string(b), time.Until(pc.ExpiresAt))
+}
+
+func (auc *AuthUsecase) loadPhoneChange(ctx context.Context, userID string) (*phoneChange, error) {
+	val, err := auc.cache.Get(ctx, phoneChangeKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	var pc phoneChange
+	if err := json.Unmarshal([]byte(val), &pc); err != nil {
+		return nil, err
+	}
+	return &pc, nil
+}
+
+func phoneChangeKey(userID string) string {
+	return fmt.Sprintf("phonechange:%s", userID)
+}
+
+func phoneChangeAttemptsKey(userID string) string {
+	return fmt.Sprintf("phonechange:attempts:%s", userID)
+}
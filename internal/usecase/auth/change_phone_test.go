@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"dekamond/internal/config"
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+)
+
+const (
+	oldTestPhone = "+15551234567"
+	newTestPhone = "+15559876543"
+)
+
+func newPhoneChangeTestUsecase(confirmOld bool) (*AuthUsecase, *mockUserRepositoryWithStorage, *mockOTPSender) {
+	auc, _ := newRefreshTestUsecase()
+	users := auc.users.(*mockUserRepositoryWithStorage)
+	sender := &mockOTPSender{}
+	auc.sender = sender
+	auc.confirmOld = confirmOld
+	return auc, users, sender
+}
+
+func TestChangePhone(t *testing.T) {
+	ctx := authdomain.WithClientInfo(context.Background(), authdomain.ClientInfo{IP: "203.0.113.7", UserAgent: "test-agent"})
+	auc, users, sender := newPhoneChangeTestUsecase(true)
+	tokens := auc.refreshTokens.(*mockRefreshTokenRepository)
+
+	session, err := auc.startSession(ctx, users.users[oldTestPhone], "")
+	if err != nil {
+		t.Fatalf("startSession() unexpected error: %v", err)
+	}
+
+	started, err := auc.StartPhoneChange(ctx, "user-1", newTestPhone, authdomain.OTPChannelSMS)
+	if err != nil {
+		t.Fatalf("StartPhoneChange() unexpected error: %v", err)
+	}
+	if !started.ConfirmOld || started.ExpiresIn != testOTPPolicy.TTL {
+		t.Errorf("StartPhoneChange() = %+v, want ConfirmOld and ExpiresIn %v", started, testOTPPolicy.TTL)
+	}
+	if len(sender.sent) != 2 || sender.sent[0].Phone != newTestPhone || sender.sent[1].Phone != oldTestPhone {
+		t.Fatalf("StartPhoneChange() sent %+v, want codes to the new then the old number", sender.sent)
+	}
+	newCode, oldCode := sender.sent[0].Code, sender.sent[1].Code
+
+	if _, err := auc.ConfirmPhoneChange(ctx, "user-1", newCode, ""); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("ConfirmPhoneChange() without old code error = %v, want %v", err, ErrInvalidOTP)
+	}
+	if _, err := auc.ConfirmPhoneChange(ctx, "user-1", oldCode, newCode); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("ConfirmPhoneChange() with swapped codes error = %v, want %v", err, ErrInvalidOTP)
+	}
+
+	user, err := auc.ConfirmPhoneChange(ctx, "user-1", newCode, oldCode)
+	if err != nil {
+		t.Fatalf("ConfirmPhoneChange() unexpected error: %v", err)
+	}
+	if user.Phone != newTestPhone {
+		t.Errorf("ConfirmPhoneChange() phone = %s, want %s", user.Phone, newTestPhone)
+	}
+	if len(users.changes) != 1 {
+		t.Fatalf("ConfirmPhoneChange() recorded %d changes, want 1", len(users.changes))
+	}
+	if c := users.changes[0]; c.OldPhone != oldTestPhone || c.NewPhone != newTestPhone || c.IP != "203.0.113.7" || c.UserAgent != "test-agent" {
+		t.Errorf("ConfirmPhoneChange() audit = %+v", c)
+	}
+
+	if _, err := auc.VerifyAccessToken(ctx, session.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("VerifyAccessToken() after phone change error = %v, want %v", err, ErrTokenRevoked)
+	}
+	if tokens.tokens[hashRefreshToken(session.RefreshToken)].RevokedAt == nil {
+		t.Errorf("ConfirmPhoneChange() did not revoke refresh tokens")
+	}
+	if _, err := auc.ConfirmPhoneChange(ctx, "user-1", newCode, oldCode); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("ConfirmPhoneChange() replay error = %v, want %v", err, ErrInvalidOTP)
+	}
+}
+
+func TestChangePhoneWithoutOldNumber(t *testing.T) {
+	ctx := context.Background()
+	auc, users, sender := newPhoneChangeTestUsecase(false)
+
+	started, err := auc.StartPhoneChange(ctx, "user-1", newTestPhone, authdomain.OTPChannelSMS)
+	if err != nil {
+		t.Fatalf("StartPhoneChange() unexpected error: %v", err)
+	}
+	if started.ConfirmOld || len(sender.sent) != 1 {
+		t.Fatalf("StartPhoneChange() = %+v with %d codes sent, want one code and no old-number confirmation", started, len(sender.sent))
+	}
+	if _, err := auc.ConfirmPhoneChange(ctx, "user-1", sender.last().Code, ""); err != nil {
+		t.Fatalf("ConfirmPhoneChange() unexpected error: %v", err)
+	}
+	if _, ok := users.users[newTestPhone]; !ok {
+		t.Errorf("ConfirmPhoneChange() did not move the user to %s", newTestPhone)
+	}
+}
+
+func TestStartPhoneChangeRejects(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		phone   string
+		setup   func(*AuthUsecase, *mockUserRepositoryWithStorage)
+		wantErr error
+	}{
+		{
+			name:    "same number",
+			phone:   oldTestPhone,
+			wantErr: ErrSamePhone,
+		},
+		{
+			name:  "number taken",
+			phone: newTestPhone,
+			setup: func(_ *AuthUsecase, users *mockUserRepositoryWithStorage) {
+				users.users[newTestPhone] = &userdomain.User{ID: "user-2", Phone: newTestPhone}
+			},
+			wantErr: ErrPhoneTaken,
+		},
+		{
+			name:  "country denied",
+			phone: "+989121234567",
+			setup: func(auc *AuthUsecase, _ *mockUserRepositoryWithStorage) {
+				auc.policy.DeniedCountries = []string{"IR"}
+			},
+			wantErr: ErrCountryNotAllowed,
+		},
+		{
+			name:  "resend cooldown",
+			phone: newTestPhone,
+			setup: func(auc *AuthUsecase, _ *mockUserRepositoryWithStorage) {
+				auc.startResendCooldown(context.Background(), newTestPhone, time.Now())
+			},
+			wantErr: ErrResendTooSoon,
+		},
+		{
+			name:  "resend cooldown on the current number",
+			phone: newTestPhone,
+			setup: func(auc *AuthUsecase, _ *mockUserRepositoryWithStorage) {
+				auc.startResendCooldown(context.Background(), oldTestPhone, time.Now())
+			},
+			wantErr: ErrResendTooSoon,
+		},
+		{
+			name:    "unknown user",
+			phone:   newTestPhone,
+			setup:   func(_ *AuthUsecase, users *mockUserRepositoryWithStorage) { delete(users.users, oldTestPhone) },
+			wantErr: ErrInvalidToken,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auc, users, sender := newPhoneChangeTestUsecase(true)
+			if tt.setup != nil {
+				tt.setup(auc, users)
+			}
+			_, err := auc.StartPhoneChange(ctx, "user-1", tt.phone, authdomain.OTPChannelSMS)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("StartPhoneChange() error = %v, want %v", err, tt.wantErr)
+			}
+			if len(sender.sent) != 0 {
+				t.Errorf("StartPhoneChange() sent %d codes, want none", len(sender.sent))
+			}
+		})
+	}
+}
+
+func TestConfirmPhoneChangeAttempts(t *testing.T) {
+	ctx := context.Background()
+	auc, _, sender := newPhoneChangeTestUsecase(false)
+	auc.policy = config.OTPPolicy{Length: 6, TTL: time.Minute, MaxVerifyAttempts: 2}
+
+	if _, err := auc.StartPhoneChange(ctx, "user-1", newTestPhone, authdomain.OTPChannelSMS); err != nil {
+		t.Fatalf("StartPhoneChange() unexpected error: %v", err)
+	}
+	code := sender.last().Code
+
+	if _, err := auc.ConfirmPhoneChange(ctx, "user-1", "000000x", ""); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("ConfirmPhoneChange() first miss error = %v, want %v", err, ErrInvalidOTP)
+	}
+	if _, err := auc.ConfirmPhoneChange(ctx, "user-1", "000000x", ""); !errors.Is(err, ErrTooManyAttempts) {
+		t.Errorf("ConfirmPhoneChange() last miss error = %v, want %v", err, ErrTooManyAttempts)
+	}
+	if _, err := auc.ConfirmPhoneChange(ctx, "user-1", code, ""); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("ConfirmPhoneChange() after too many attempts error = %v, want %v", err, ErrInvalidOTP)
+	}
+}
+
+func TestConfirmPhoneChangeNumberTakenMeanwhile(t *testing.T) {
+	ctx := context.Background()
+	auc, users, sender := newPhoneChangeTestUsecase(false)
+
+	if _, err := auc.StartPhoneChange(ctx, "user-1", newTestPhone, authdomain.OTPChannelSMS); err != nil {
+		t.Fatalf("StartPhoneChange() unexpected error: %v", err)
+	}
+	users.users[newTestPhone] = &userdomain.User{ID: "user-2", Phone: newTestPhone}
+
+	if _, err := auc.ConfirmPhoneChange(ctx, "user-1", sender.last().Code, ""); !errors.Is(err, ErrPhoneTaken) {
+		t.Errorf("ConfirmPhoneChange() error = %v, want %v", err, ErrPhoneTaken)
+	}
+	if users.users[oldTestPhone].Phone != oldTestPhone {
+		t.Errorf("ConfirmPhoneChange() changed the phone despite the conflict")
+	}
+}
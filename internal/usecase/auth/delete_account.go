@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// DeleteAccount soft-deletes the user and revokes all of their sessions. It
+// returns when the account will be purged; logging in before then restores it.
+func (auc *AuthUsecase) DeleteAccount(ctx context.Context, userID string) (time.Time, error) {
+	deletedAt, err := auc.users.SoftDelete(ctx, userID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return time.Time{}, ErrInvalidToken
+		}
+		return time.Time{}, fmt.Errorf("failed to delete user: %w", err)
+	}
+	if err := auc.revokeAllForUser(ctx, userID); err != nil {
+		return time.Time{}, err
+	}
+	return deletedAt.Add(auc.deletionGrace), nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+	auc, tokens := newRefreshTestUsecase()
+	auc.deletionGrace = 30 * 24 * time.Hour
+	users := auc.users.(*mockUserRepositoryWithStorage)
+
+	session, err := auc.startSession(ctx, users.users["+15551234567"], "")
+	if err != nil {
+		t.Fatalf("startSession() unexpected error: %v", err)
+	}
+
+	purgeAt, err := auc.DeleteAccount(ctx, "user-1")
+	if err != nil {
+		t.Fatalf("DeleteAccount() unexpected error: %v", err)
+	}
+	if d := time.Until(purgeAt); d < auc.deletionGrace-time.Minute || d > auc.deletionGrace {
+		t.Errorf("DeleteAccount() purge in %v, want about %v", d, auc.deletionGrace)
+	}
+	if _, err := auc.VerifyAccessToken(ctx, session.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("VerifyAccessToken() after delete error = %v, want %v", err, ErrTokenRevoked)
+	}
+	if tokens.tokens[hashRefreshToken(session.RefreshToken)].RevokedAt == nil {
+		t.Errorf("DeleteAccount() did not revoke refresh tokens")
+	}
+	if _, err := auc.DeleteAccount(ctx, "user-1"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("DeleteAccount() twice error = %v, want %v", err, ErrInvalidToken)
+	}
+}
+
+func TestLoginRestoresDeletedAccount(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+
+	if _, err := auc.DeleteAccount(ctx, "user-1"); err != nil {
+		t.Fatalf("DeleteAccount() unexpected error: %v", err)
+	}
+	user, err := auc.getOrCreateUser(ctx, "+15551234567")
+	if err != nil {
+		t.Fatalf("getOrCreateUser() unexpected error: %v", err)
+	}
+	if user.ID != "user-1" {
+		t.Errorf("getOrCreateUser() ID = %s, want the restored user-1", user.ID)
+	}
+}
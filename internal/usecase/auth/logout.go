@@ -0,0 +1,68 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Logout ends the session of the presented access token and, if given and
+// different, the session of the refresh token.
+func (auc *AuthUsecase) Logout(ctx context.Context, accessToken, refreshToken string) error {
+	claims, err := auc.parseAccessToken(accessToken)
+	if err != nil {
+		return err
+	}
+	if err := auc.denyToken(ctx, claims); err != nil {
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+
+	sessions := []string{claims.SessionID}
+	if refreshToken != "" {
+		stored, err := auc.refreshTokens.GetByHash(ctx, hashRefreshToken(refreshToken))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to get refresh token: %w", err)
+		}
+		if err == nil && stored.UserID == claims.Subject && stored.FamilyID != claims.SessionID {
+			sessions = append(sessions, stored.FamilyID)
+		}
+	}
+	for _, sid := range sessions {
+		if sid == "" {
+			continue
+		}
+		if err := auc.closeSession(ctx, claims.Subject, sid); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogoutAll revokes every access and refresh token issued to the caller up to
+// now. Access tokens can't be enumerated, so a per-user cutoff is stored
+// instead and kept for one access token lifetime.
+func (auc *AuthUsecase) LogoutAll(ctx context.Context, accessToken string) error {
+	claims, err := auc.parseAccessToken(accessToken)
+	if err != nil {
+		return err
+	}
+	return auc.revokeAllForUser(ctx, claims.Subject)
+}
+
+func (auc *AuthUsecase) revokeAllForUser(ctx context.Context, userID string) error {
+	cutoff := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	if err := auc.cache.Set(ctx, revokedBeforeKey(userID), cutoff, auc.tokenTTL+tokenLeeway); err != nil {
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	if err := auc.refreshTokens.RevokeAllForUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if err := auc.sessions.RevokeAllForUser(ctx, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
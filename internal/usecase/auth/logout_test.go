@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+)
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	auc, tokens := newRefreshTestUsecase()
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+
+	pair, err := auc.startSession(ctx, user, "")
+	if err != nil {
+		t.Fatalf("startSession() unexpected error: %v", err)
+	}
+	other, err := auc.startSession(ctx, user, "")
+	if err != nil {
+		t.Fatalf("startSession() unexpected error: %v", err)
+	}
+
+	if err := auc.Logout(ctx, pair.AccessToken, pair.RefreshToken); err != nil {
+		t.Fatalf("Logout() unexpected error: %v", err)
+	}
+
+	if _, err := auc.VerifyAccessToken(ctx, pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("VerifyAccessToken() after logout error = %v, want %v", err, ErrTokenRevoked)
+	}
+	if tokens.tokens[hashRefreshToken(pair.RefreshToken)].RevokedAt == nil {
+		t.Errorf("Logout() did not revoke the refresh token")
+	}
+	if _, err := auc.VerifyAccessToken(ctx, other.AccessToken); err != nil {
+		t.Errorf("VerifyAccessToken() for another session error = %v, want nil", err)
+	}
+	if tokens.tokens[hashRefreshToken(other.RefreshToken)].RevokedAt != nil {
+		t.Errorf("Logout() revoked the refresh token of another session")
+	}
+}
+
+func TestLogoutWithoutRefreshTokenEndsSession(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+
+	pair, err := auc.startSession(ctx, user, "")
+	if err != nil {
+		t.Fatalf("startSession() unexpected error: %v", err)
+	}
+	// A second access token of the same session, as issued by a refresh.
+	principal, _ := auc.VerifyAccessToken(ctx, pair.AccessToken)
+	sibling, err := auc.generateJWT(user, principal.SessionID)
+	if err != nil {
+		t.Fatalf("generateJWT() unexpected error: %v", err)
+	}
+
+	if err := auc.Logout(ctx, pair.AccessToken, ""); err != nil {
+		t.Fatalf("Logout() unexpected error: %v", err)
+	}
+	if _, err := auc.VerifyAccessToken(ctx, sibling); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("VerifyAccessToken() for same session error = %v, want %v", err, ErrTokenRevoked)
+	}
+	if _, err := auc.Refresh(ctx, pair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() after logout error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+	if list, _ := auc.ListSessions(ctx, user.ID, ""); len(list) != 0 {
+		t.Errorf("ListSessions() after logout = %d sessions, want 0", len(list))
+	}
+}
+
+func TestLogoutAll(t *testing.T) {
+	ctx := context.Background()
+	auc, tokens := newRefreshTestUsecase()
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+
+	first, _ := auc.startSession(ctx, user, "")
+	second, _ := auc.startSession(ctx, user, "")
+
+	if err := auc.LogoutAll(ctx, first.AccessToken); err != nil {
+		t.Fatalf("LogoutAll() unexpected error: %v", err)
+	}
+
+	for _, pair := range []*TokenPair{first, second} {
+		if _, err := auc.VerifyAccessToken(ctx, pair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+			t.Errorf("VerifyAccessToken() after logout-all error = %v, want %v", err, ErrTokenRevoked)
+		}
+		if tokens.tokens[hashRefreshToken(pair.RefreshToken)].RevokedAt == nil {
+			t.Errorf("LogoutAll() did not revoke refresh token")
+		}
+	}
+
+	// Tokens issued after the cutoff are accepted, even within the same second.
+	time.Sleep(2 * time.Millisecond)
+	fresh, _ := auc.startSession(ctx, user, "")
+	if _, err := auc.VerifyAccessToken(ctx, fresh.AccessToken); err != nil {
+		t.Errorf("VerifyAccessToken() for token issued after logout-all error = %v", err)
+	}
+}
+
+func TestDenylistOutlivesTokenLeeway(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+	cache := auc.cache.(*mockCacheStore)
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+
+	pair, _ := auc.startSession(ctx, user, "")
+	other, _ := auc.startSession(ctx, user, "")
+	claims, err := auc.parseAccessToken(pair.AccessToken)
+	if err != nil {
+		t.Fatalf("parseAccessToken() unexpected error: %v", err)
+	}
+	if err := auc.Logout(ctx, pair.AccessToken, pair.RefreshToken); err != nil {
+		t.Fatalf("Logout() unexpected error: %v", err)
+	}
+	if err := auc.LogoutAll(ctx, other.AccessToken); err != nil {
+		t.Fatalf("LogoutAll() unexpected error: %v", err)
+	}
+
+	for _, key := range []string{deniedTokenKey(claims.ID), deniedSessionKey(claims.SessionID), revokedBeforeKey(user.ID)} {
+		if ttl := cache.ttls[key]; ttl <= auc.tokenTTL {
+			t.Errorf("ttl of %s = %s, want more than the token lifetime %s plus leeway", key, ttl, auc.tokenTTL)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	auditdomain "dekamond/internal/domain/audit"
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const maxStatusReasonLen = 500
+
+var (
+	ErrAccountSuspended = errors.New("account_suspended")
+	ErrAccountBanned    = errors.New("account_banned")
+
+	ErrUserNotFound       = errors.New("user_not_found")
+	ErrReasonRequired     = errors.New("reason_required")
+	ErrReasonTooLong      = errors.New("reason_too_long")
+	ErrInvalidUntil       = errors.New("invalid_until")
+	ErrCannotModerateSelf = errors.New("cannot_moderate_self")
+	// ErrCannotModerateRole means the user's role ranks as high as the
+	// moderator's or higher.
+	ErrCannotModerateRole = errors.New("cannot_moderate_role")
+)
+
+// Suspend blocks the user until the given time. Their tokens are rejected
+// while the suspension lasts and work again once it ends.
+func (auc *AuthUsecase) Suspend(ctx context.Context, actorID, userID, reason string, until time.Time) (*userdomain.User, error) {
+	user, err := auc.restrict(ctx, actorID, userID, userdomain.StatusSuspended, reason, &until)
+	auc.record(ctx, moderationEvent(auditdomain.ActionUserSuspended, actorID, userID, reason, &until), err)
+	return user, err
+}
+
+// Ban blocks the user, until the given time or for good if until is nil, and
+// revokes all of their sessions.
+func (auc *AuthUsecase) Ban(ctx context.Context, actorID, userID, reason string, until *time.Time) (*userdomain.User, error) {
+	user, err := auc.ban(ctx, actorID, userID, reason, until)
+	auc.record(ctx, moderationEvent(auditdomain.ActionUserBanned, actorID, userID, reason, until), err)
+	return user, err
+}
+
+func (auc *AuthUsecase) ban(ctx context.Context, actorID, userID, reason string, until *time.Time) (*userdomain.User, error) {
+	user, err := auc.restrict(ctx, actorID, userID, userdomain.StatusBanned, reason, until)
+	if err != nil {
+		return nil, err
+	}
+	if err := auc.revokeAllForUser(ctx, userID); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// Unban lifts a ban or suspension.
+func (auc *AuthUsecase) Unban(ctx context.Context, actorID, userID string) (*userdomain.User, error) {
+	user, err := auc.unban(ctx, actorID, userID)
+	auc.record(ctx, moderationEvent(auditdomain.ActionUserUnbanned, actorID, userID, "", nil), err)
+	return user, err
+}
+
+func (auc *AuthUsecase) unban(ctx context.Context, actorID, userID string) (*userdomain.User, error) {
+	if err := auc.checkCanModerate(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+	user, err := auc.setStatus(ctx, userID, userdomain.StatusChange{
+		Restriction: userdomain.Restriction{Status: userdomain.StatusActive},
+		ChangedBy:   actorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := auc.cache.Delete(ctx, userStatusKey(userID)); err != nil {
+		return nil, fmt.Errorf("failed to clear user status: %w", err)
+	}
+	return user, nil
+}
+
+// ForceLogout revokes every access and refresh token of the user.
+func (auc *AuthUsecase) ForceLogout(ctx context.Context, actorID, userID string) error {
+	err := auc.forceLogout(ctx, actorID, userID)
+	auc.record(ctx, auditdomain.Event{Action: auditdomain.ActionUserLoggedOut, ActorID: actorID, Target: userID}, err)
+	return err
+}
+
+func (auc *AuthUsecase) forceLogout(ctx context.Context, actorID, userID string) error {
+	if err := auc.checkCanModerate(ctx, actorID, userID); err != nil {
+		return err
+	}
+	return auc.revokeAllForUser(ctx, userID)
+}
+
+func moderationEvent(action auditdomain.Action, actorID, userID, reason string, until *time.Time) auditdomain.Event {
+	e := auditdomain.Event{Action: action, ActorID: actorID, Target: userID, Details: map[string]string{}}
+	if reason != "" {
+		e.Details["reason"] = reason
+	}
+	if until != nil {
+		e.Details["until"] = until.UTC().Format(time.RFC3339)
+	}
+	return e
+}
+
+func (auc *AuthUsecase) restrict(ctx context.Context, actorID, userID string, status userdomain.Status, reason string, until *time.Time) (*userdomain.User, error) {
+	if actorID == userID {
+		return nil, ErrCannotModerateSelf
+	}
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrReasonRequired
+	}
+	if utf8.RuneCountInString(reason) > maxStatusReasonLen {
+		return nil, ErrReasonTooLong
+	}
+	var ttl time.Duration
+	if until != nil {
+		if ttl = time.Until(*until); ttl <= 0 {
+			return nil, ErrInvalidUntil
+		}
+	}
+	if err := auc.checkCanModerate(ctx, actorID, userID); err != nil {
+		return nil, err
+	}
+
+	user, err := auc.setStatus(ctx, userID, userdomain.StatusChange{
+		Restriction: userdomain.Restriction{Status: status, StatusReason: reason, StatusUntil: until},
+		ChangedBy:   actorID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Postgres is what login and refresh check; this copy lets every request
+	// check it without a query. A zero TTL keeps it until Unban.
+	if err := auc.cache.Set(ctx, userStatusKey(userID), string(status), ttl); err != nil {
+		return nil, fmt.Errorf("failed to cache user status: %w", err)
+	}
+	return user, nil
+}
+
+// checkCanModerate makes sure the actor is not the user and outranks them, so
+// support staff can't act on admins and admins can't act on each other.
+func (auc *AuthUsecase) checkCanModerate(ctx context.Context, actorID, userID string) error {
+	if actorID == userID {
+		return ErrCannotModerateSelf
+	}
+	user, err := auc.users.GetByID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	actor, err := auc.users.GetByID(ctx, actorID)
+	if err != nil {
+		return fmt.Errorf("failed to get moderator: %w", err)
+	}
+	if !authdomain.Outranks(actor.Roles, user.Roles) {
+		return ErrCannotModerateRole
+	}
+	return nil
+}
+
+func (auc *AuthUsecase) setStatus(ctx context.Context, userID string, c userdomain.StatusChange) (*userdomain.User, error) {
+	user, err := auc.users.SetStatus(ctx, userID, c)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to set user status: %w", err)
+	}
+	return user, nil
+}
+
+// statusCacheTTL is how long checkUserStatus caches that a user is active.
+// A Suspend or Ban racing the cache fill can go unnoticed for this long.
+const statusCacheTTL = time.Minute
+
+// checkUserStatus rejects users that are currently suspended or banned, going
+// by the cached status. On a miss, e.g. after Redis lost its data, the status
+// is read from Postgres and cached again, so restrictions are never lifted
+// by the cache alone. Users that no longer exist get ErrTokenRevoked.
+func (auc *AuthUsecase) checkUserStatus(ctx context.Context, userID string) error {
+	val, err := auc.cache.Get(ctx, userStatusKey(userID))
+	if err == nil {
+		return statusError(userdomain.Status(val))
+	}
+	if !errors.Is(err, userdomain.ErrNotFound) {
+		return err
+	}
+
+	user, err := auc.users.GetByID(ctx, userID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrTokenRevoked
+	}
+	if err != nil {
+		return err
+	}
+	status, ttl := user.StatusAt(time.Now()), statusCacheTTL
+	if status != userdomain.StatusActive {
+		ttl = 0
+		if user.StatusUntil != nil {
+			ttl = time.Until(*user.StatusUntil)
+		}
+	}
+	if err := auc.cache.Set(ctx, userStatusKey(userID), string(status), ttl); err != nil {
+		return err
+	}
+	return statusError(status)
+}
+
+// restrictionError is checkUserStatus for a user loaded from Postgres.
+func restrictionError(user *userdomain.User) error {
+	return statusError(user.StatusAt(time.Now()))
+}
+
+func statusError(s userdomain.Status) error {
+	switch s {
+	case userdomain.StatusSuspended:
+		return ErrAccountSuspended
+	case userdomain.StatusBanned:
+		return ErrAccountBanned
+	}
+	return nil
+}
+
+func userStatusKey(userID string) string {
+	return fmt.Sprintf("user:status:%s", userID)
+}
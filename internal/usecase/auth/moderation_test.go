@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+)
+
+const testAdminID = "admin-1"
+
+// newModerationTestUsecase adds an admin and a support agent to the users
+// of newRefreshTestUsecase; user-1 only has the user role.
+func newModerationTestUsecase() (*AuthUsecase, *mockRefreshTokenRepository) {
+	auc, tokens := newRefreshTestUsecase()
+	users := auc.users.(*mockUserRepositoryWithStorage)
+	users.users["+15550000001"] = &userdomain.User{ID: testAdminID, Phone: "+15550000001", Roles: []string{"admin"}}
+	users.users["+15550000002"] = &userdomain.User{ID: "support-1", Phone: "+15550000002", Roles: []string{"support"}}
+	users.users["+15550000003"] = &userdomain.User{ID: "admin-2", Phone: "+15550000003", Roles: []string{"admin"}}
+	return auc, tokens
+}
+
+func TestSuspend(t *testing.T) {
+	ctx := context.Background()
+	auc, tokens := newModerationTestUsecase()
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+	session, _ := auc.startSession(ctx, user, "")
+
+	until := time.Now().Add(time.Hour)
+	got, err := auc.Suspend(ctx, testAdminID, "user-1", "spam", until)
+	if err != nil {
+		t.Fatalf("Suspend() unexpected error: %v", err)
+	}
+	if got.Status != userdomain.StatusSuspended || got.StatusReason != "spam" {
+		t.Errorf("Suspend() restriction = %+v", got.Restriction)
+	}
+
+	if _, err := auc.VerifyAccessToken(ctx, session.AccessToken); !errors.Is(err, ErrAccountSuspended) {
+		t.Errorf("VerifyAccessToken() while suspended error = %v, want %v", err, ErrAccountSuspended)
+	}
+	if _, err := auc.Refresh(ctx, session.RefreshToken); !errors.Is(err, ErrAccountSuspended) {
+		t.Errorf("Refresh() while suspended error = %v, want %v", err, ErrAccountSuspended)
+	}
+	if tokens.tokens[hashRefreshToken(session.RefreshToken)].UsedAt != nil {
+		t.Errorf("Refresh() used up the refresh token of a suspended user")
+	}
+
+	if _, err := auc.Unban(ctx, testAdminID, "user-1"); err != nil {
+		t.Fatalf("Unban() unexpected error: %v", err)
+	}
+	if _, err := auc.VerifyAccessToken(ctx, session.AccessToken); err != nil {
+		t.Errorf("VerifyAccessToken() after unban error = %v, want nil", err)
+	}
+	if _, err := auc.Refresh(ctx, session.RefreshToken); err != nil {
+		t.Errorf("Refresh() after unban error = %v, want nil", err)
+	}
+}
+
+func TestSuspensionSurvivesCacheLoss(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newModerationTestUsecase()
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+	session, _ := auc.startSession(ctx, user, "")
+
+	if _, err := auc.Suspend(ctx, testAdminID, "user-1", "spam", time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("Suspend() unexpected error: %v", err)
+	}
+	// As after a Redis flush, eviction or failover.
+	_ = auc.cache.Delete(ctx, userStatusKey("user-1"))
+
+	if _, err := auc.VerifyAccessToken(ctx, session.AccessToken); !errors.Is(err, ErrAccountSuspended) {
+		t.Errorf("VerifyAccessToken() after cache loss error = %v, want %v", err, ErrAccountSuspended)
+	}
+	if v, err := auc.cache.Get(ctx, userStatusKey("user-1")); err != nil || v != string(userdomain.StatusSuspended) {
+		t.Errorf("cached status = %q, %v, want it restored from the database", v, err)
+	}
+}
+
+func TestBan(t *testing.T) {
+	ctx := context.Background()
+	auc, tokens := newModerationTestUsecase()
+	auc.sender = &mockOTPSender{}
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+	session, _ := auc.startSession(ctx, user, "")
+
+	if _, err := auc.Ban(ctx, testAdminID, "user-1", "fraud", nil); err != nil {
+		t.Fatalf("Ban() unexpected error: %v", err)
+	}
+	if _, err := auc.VerifyAccessToken(ctx, session.AccessToken); err == nil {
+		t.Errorf("VerifyAccessToken() after ban succeeded")
+	}
+	if tokens.tokens[hashRefreshToken(session.RefreshToken)].RevokedAt == nil {
+		t.Errorf("Ban() did not revoke refresh tokens")
+	}
+
+	auc.cache.Set(ctx, otpKey(user.Phone), otpRecordFor(auc.otpPepper, user.Phone, "123456"), time.Minute)
+	if _, _, err := auc.VerifyOTPAndIssueToken(ctx, user.Phone, "123456", ""); !errors.Is(err, ErrAccountBanned) {
+		t.Errorf("VerifyOTPAndIssueToken() for banned user error = %v, want %v", err, ErrAccountBanned)
+	}
+}
+
+func TestExpiredRestrictionAllowsLogin(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newModerationTestUsecase()
+	users := auc.users.(*mockUserRepositoryWithStorage)
+	past := time.Now().Add(-time.Minute)
+	users.users["+15551234567"].Restriction = userdomain.Restriction{Status: userdomain.StatusBanned, StatusUntil: &past}
+
+	auc.cache.Set(ctx, otpKey("+15551234567"), otpRecordFor(auc.otpPepper, "+15551234567", "123456"), time.Minute)
+	if _, _, err := auc.VerifyOTPAndIssueToken(ctx, "+15551234567", "123456", ""); err != nil {
+		t.Errorf("VerifyOTPAndIssueToken() after ban expired error = %v, want nil", err)
+	}
+}
+
+func TestRestrictRejects(t *testing.T) {
+	ctx := context.Background()
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		actor   string
+		user    string
+		reason  string
+		until   *time.Time
+		wantErr error
+	}{
+		{name: "self", actor: "user-1", user: "user-1", reason: "x", wantErr: ErrCannotModerateSelf},
+		{name: "no reason", actor: testAdminID, user: "user-1", reason: "  ", wantErr: ErrReasonRequired},
+		{name: "long reason", actor: testAdminID, user: "user-1", reason: strings.Repeat("a", 501), wantErr: ErrReasonTooLong},
+		{name: "support bans admin", actor: "support-1", user: testAdminID, reason: "x", wantErr: ErrCannotModerateRole},
+		{name: "admin bans admin", actor: testAdminID, user: "admin-2", reason: "x", wantErr: ErrCannotModerateRole},
+		{name: "until in the past", actor: testAdminID, user: "user-1", reason: "x", until: &past, wantErr: ErrInvalidUntil},
+		{name: "unknown user", actor: testAdminID, user: "user-2", reason: "x", until: &future, wantErr: ErrUserNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auc, _ := newModerationTestUsecase()
+			if _, err := auc.Ban(ctx, tt.actor, tt.user, tt.reason, tt.until); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Ban() error = %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestForceLogout(t *testing.T) {
+	ctx := context.Background()
+	auc, tokens := newModerationTestUsecase()
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+	session, _ := auc.startSession(ctx, user, "")
+
+	if err := auc.ForceLogout(ctx, testAdminID, "user-1"); err != nil {
+		t.Fatalf("ForceLogout() unexpected error: %v", err)
+	}
+	if _, err := auc.VerifyAccessToken(ctx, session.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("VerifyAccessToken() after force logout error = %v, want %v", err, ErrTokenRevoked)
+	}
+	if tokens.tokens[hashRefreshToken(session.RefreshToken)].RevokedAt == nil {
+		t.Errorf("ForceLogout() did not revoke refresh tokens")
+	}
+	if err := auc.ForceLogout(ctx, testAdminID, "user-2"); !errors.Is(err, ErrUserNotFound) {
+		t.Errorf("ForceLogout() unknown user error = %v, want %v", err, ErrUserNotFound)
+	}
+	if err := auc.ForceLogout(ctx, "support-1", testAdminID); !errors.Is(err, ErrCannotModerateRole) {
+		t.Errorf("ForceLogout() of an admin by support error = %v, want %v", err, ErrCannotModerateRole)
+	}
+	if err := auc.ForceLogout(ctx, "support-1", "user-1"); err != nil {
+		t.Errorf("ForceLogout() of a user by support error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+)
+
+const (
+	maxIPFailures   = 20
+	ipFailureWindow = 15 * time.Minute
+	lockoutMemory   = 24 * time.Hour
+)
+
+// lockoutSteps is how long a phone or IP is locked the 1st, 2nd, ... time
+// within lockoutMemory.
+var lockoutSteps = []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 24 * time.Hour}
+
+var (
+	ErrOTPLocked       = errors.New("otp_locked")
+	ErrTooManyAttempts = errors.New("too_many_attempts")
+)
+
+// RetryError is returned when the caller has to wait before trying again.
+type RetryError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *RetryError) Error() string { return e.Err.Error() }
+func (e *RetryError) Unwrap() error { return e.Err }
+
+// checkOTPLock rejects verification while the phone or the client IP is
+// locked out.
+func (auc *AuthUsecase) checkOTPLock(ctx context.Context, phone string) error {
+	keys := []string{otpLockKey("phone", phone)}
+	if ip := authdomain.ClientInfoFromContext(ctx).IP; ip != "" {
+		keys = append(keys, otpLockKey("ip", ip))
+	}
+	for _, key := range keys {
+		val, err := auc.cache.Get(ctx, key)
+		if errors.Is(err, userdomain.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		until, _ := strconv.ParseInt(val, 10, 64)
+		return &RetryError{Err: ErrOTPLocked, RetryAfter: time.Until(time.Unix(until, 0))}
+	}
+	return nil
+}
+
+// registerIPFailure counts any failed verification against the client IP, so
+// an IP guessing across many phones gets locked out on its own.
+func (auc *AuthUsecase) registerIPFailure(ctx context.Context) error {
+	ip := authdomain.ClientInfoFromContext(ctx).IP
+	if ip == "" {
+		return nil
+	}
+	failures, err := auc.cache.Increment(ctx, otpIPFailuresKey(ip), ipFailureWindow)
+	if err != nil {
+		return err
+	}
+	if failures >= maxIPFailures {
+		_ = auc.cache.Delete(ctx, otpIPFailuresKey(ip))
+		if _, err := auc.lockOut(ctx, "ip", ip); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// registerPhoneFailure counts a wrong code against the phone's current OTP.
+// The counter only moves while the OTP still exists, so a guess racing a
+// successful verify changes nothing. The guess that uses up the attempts
+// discards the OTP and locks the phone; any racing it just fail.
+func (auc *AuthUsecase) registerPhoneFailure(ctx context.Context, phone string) error {
+	attempts, err := auc.cache.IncrementIfExists(ctx, otpKey(phone), otpAttemptsKey(phone))
+	if errors.Is(err, userdomain.ErrNotFound) {
+		return ErrInvalidOTP
+	}
+	if err != nil {
+		return err
+	}
+	if attempts != int64(auc.policy.MaxVerifyAttempts) {
+		return ErrInvalidOTP
+	}
+
+	_ = auc.cache.Delete(ctx, otpKey(phone))
+	_ = auc.cache.Delete(ctx, otpAttemptsKey(phone))
+	lock, err := auc.lockOut(ctx, "phone", phone)
+	if err != nil {
+		return err
+	}
+	return &RetryError{Err: ErrTooManyAttempts, RetryAfter: lock}
+}
+
+func (auc *AuthUsecase) lockOut(ctx context.Context, kind, id string) (time.Duration, error) {
+	n, err := auc.cache.Increment(ctx, otpLockCountKey(kind, id), lockoutMemory)
+	if err != nil {
+		return 0, err
+	}
+	// Keep remembering previous lockouts for as long as they keep happening.
+	_ = auc.cache.SetExpiry(ctx, otpLockCountKey(kind, id), lockoutMemory)
+
+	step := int(n) - 1
+	if step >= len(lockoutSteps) {
+		step = len(lockoutSteps) - 1
+	}
+	d := lockoutSteps[step]
+	until := strconv.FormatInt(time.Now().Add(d).Unix(), 10)
+	if err := auc.cache.Set(ctx, otpLockKey(kind, id), until, d); err != nil {
+		return 0, err
+	}
+	return d, nil
+}
+
+func otpAttemptsKey(phone string) string {
+	return fmt.Sprintf("otp:attempts:%s", phone)
+}
+
+func otpIPFailuresKey(ip string) string {
+	return fmt.Sprintf("otp:fail:ip:%s", ip)
+}
+
+func otpLockKey(kind, id string) string {
+	return fmt.Sprintf("otp:lock:%s:%s", kind, id)
+}
+
+func otpLockCountKey(kind, id string) string {
+	return fmt.Sprintf("otp:lockcount:%s:%s", kind, id)
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+)
+
+func TestVerifyOTPLocksPhoneAfterMaxAttempts(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+	cache := auc.cache.(*mockCacheStore)
+	phone := "+15551234567"
+	cache.store[otpKey(phone)] = otpRecordFor(auc.otpPepper, phone, "123456")
+
+	for i := 1; i < auc.policy.MaxVerifyAttempts; i++ {
+		_, _, err := auc.VerifyOTPAndIssueToken(ctx, phone, "000000", "")
+		if !errors.Is(err, ErrInvalidOTP) {
+			t.Fatalf("attempt %d: error = %v, want %v", i, err, ErrInvalidOTP)
+		}
+	}
+
+	_, _, err := auc.VerifyOTPAndIssueToken(ctx, phone, "000000", "")
+	var retry *RetryError
+	if !errors.As(err, &retry) || !errors.Is(err, ErrTooManyAttempts) {
+		t.Fatalf("last attempt: error = %v, want %v", err, ErrTooManyAttempts)
+	}
+	if retry.RetryAfter != lockoutSteps[0] {
+		t.Errorf("last attempt: retry after = %s, want %s", retry.RetryAfter, lockoutSteps[0])
+	}
+	if _, ok := cache.store[otpKey(phone)]; ok {
+		t.Errorf("OTP should be invalidated after too many attempts")
+	}
+
+	// Even the right code is refused while locked.
+	cache.store[otpKey(phone)] = otpRecordFor(auc.otpPepper, phone, "123456")
+	_, _, err = auc.VerifyOTPAndIssueToken(ctx, phone, "123456", "")
+	if !errors.As(err, &retry) || !errors.Is(err, ErrOTPLocked) {
+		t.Fatalf("while locked: error = %v, want %v", err, ErrOTPLocked)
+	}
+	if retry.RetryAfter <= 0 || retry.RetryAfter > lockoutSteps[0] {
+		t.Errorf("while locked: retry after = %s", retry.RetryAfter)
+	}
+}
+
+func TestLockoutEscalates(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+
+	for i, want := range []time.Duration{time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour, 24 * time.Hour, 24 * time.Hour} {
+		got, err := auc.lockOut(ctx, "phone", "+15551234567")
+		if err != nil {
+			t.Fatalf("lockOut() unexpected error: %v", err)
+		}
+		if got != want {
+			t.Errorf("lockout %d = %s, want %s", i+1, got, want)
+		}
+	}
+}
+
+func TestVerifyOTPLocksIPAcrossPhones(t *testing.T) {
+	auc, _ := newRefreshTestUsecase()
+	ctx := authdomain.WithClientInfo(context.Background(), authdomain.ClientInfo{IP: "203.0.113.7"})
+
+	for i := 0; i < maxIPFailures; i++ {
+		phone := fmt.Sprintf("+1555000%04d", i)
+		if _, _, err := auc.VerifyOTPAndIssueToken(ctx, phone, "000000", ""); !errors.Is(err, ErrInvalidOTP) {
+			t.Fatalf("attempt %d: error = %v, want %v", i+1, err, ErrInvalidOTP)
+		}
+	}
+
+	_, _, err := auc.VerifyOTPAndIssueToken(ctx, "+15559999999", "000000", "")
+	if !errors.Is(err, ErrOTPLocked) {
+		t.Errorf("after %d failures: error = %v, want %v", maxIPFailures, err, ErrOTPLocked)
+	}
+
+	other := authdomain.WithClientInfo(context.Background(), authdomain.ClientInfo{IP: "198.51.100.1"})
+	if _, _, err := auc.VerifyOTPAndIssueToken(other, "+15559999999", "000000", ""); !errors.Is(err, ErrInvalidOTP) || errors.Is(err, ErrOTPLocked) {
+		t.Errorf("other IP: error = %v, want %v", err, ErrInvalidOTP)
+	}
+}
+
+func TestVerifyOTPResetsAttemptsOnSuccess(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+	cache := auc.cache.(*mockCacheStore)
+	phone := "+15551234567"
+	cache.store[otpKey(phone)] = otpRecordFor(auc.otpPepper, phone, "123456")
+
+	_, _, _ = auc.VerifyOTPAndIssueToken(ctx, phone, "000000", "")
+	if _, _, err := auc.VerifyOTPAndIssueToken(ctx, phone, "123456", ""); err != nil {
+		t.Fatalf("VerifyOTPAndIssueToken() unexpected error: %v", err)
+	}
+	if _, ok := cache.store[otpAttemptsKey(phone)]; ok {
+		t.Errorf("attempt counter should be cleared after a successful verification")
+	}
+}
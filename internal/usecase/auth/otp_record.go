@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+)
+
+// otpRecord is what is stored under otp:<phone>. The code itself is never
+// stored, only an HMAC keyed with the server-side pepper, so a cache dump
+// does not reveal live codes. The record is written once when the code is
+// issued; failed attempts are counted under otp:attempts:<phone>.
+type otpRecord struct {
+	Hash      string                `json:"hash"`
+	Channel   authdomain.OTPChannel `json:"channel"`
+	RequestID string                `json:"request_id"`
+	IssuedAt  time.Time             `json:"issued_at"`
+	ExpiresAt time.Time             `json:"expires_at"`
+}
+
+func hashOTP(pepper []byte, phone, code string) string {
+	mac := hmac.New(sha256.New, pepper)
+	mac.Write([]byte(phone))
+	mac.Write([]byte{0})
+	mac.Write([]byte(code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (r *otpRecord) matches(pepper []byte, phone, code string) bool {
+	want := []byte(r.Hash)
+	got := []byte(hashOTP(pepper, phone, code))
+	return subtle.ConstantTimeCompare(want, got) == 1
+}
+
+func (auc *AuthUsecase) storeOTPRecord(ctx context.Context, phone string, rec *otpRecord) error {
+	ttl := time.Until(rec.ExpiresAt)
+	if ttl <= 0 {
+		return auc.cache.Delete(ctx, otpKey(phone))
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	return auc.cache.Set(ctx, otpKey(phone), string(b), ttl)
+}
+
+// loadOTPRecord returns errOTPNotFound if no code is pending for the phone.
+// Cache errors and unreadable records are returned as they are.
+func (auc *AuthUsecase) loadOTPRecord(ctx context.Context, phone string) (*otpRecord, error) {
+	val, err := auc.cache.Get(ctx, otpKey(phone))
+	if errors.Is(err, userdomain.ErrNotFound) {
+		return nil, errOTPNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load otp: %w", err)
+	}
+	var rec otpRecord
+	if err := json.Unmarshal([]byte(val), &rec); err != nil {
+		return nil, fmt.Errorf("failed to decode otp record: %w", err)
+	}
+	return &rec, nil
+}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestOTPRecordMatches(t *testing.T) {
+	rec := &otpRecord{Hash: hashOTP([]byte("pepper"), "+15551234567", "123456")}
+
+	tests := []struct {
+		name   string
+		pepper string
+		phone  string
+		code   string
+		want   bool
+	}{
+		{name: "same code", pepper: "pepper", phone: "+15551234567", code: "123456", want: true},
+		{name: "wrong code", pepper: "pepper", phone: "+15551234567", code: "123457", want: false},
+		{name: "other phone", pepper: "pepper", phone: "+15551234568", code: "123456", want: false},
+		{name: "other pepper", pepper: "rotated", phone: "+15551234567", code: "123456", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rec.matches([]byte(tt.pepper), tt.phone, tt.code); got != tt.want {
+				t.Errorf("matches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailedAttemptsCountedApartFromOTP(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+	cache := auc.cache.(*mockCacheStore)
+	phone := "+15551234567"
+	stored := otpRecordFor(auc.otpPepper, phone, "123456")
+	cache.store[otpKey(phone)] = stored
+
+	_, _, _ = auc.VerifyOTPAndIssueToken(ctx, phone, "000000", "")
+	_, _, _ = auc.VerifyOTPAndIssueToken(ctx, phone, "000001", "")
+
+	if got := cache.store[otpAttemptsKey(phone)]; got != "2" {
+		t.Errorf("attempts = %q, want 2", got)
+	}
+	if cache.store[otpKey(phone)] != stored {
+		t.Errorf("record was rewritten after failed attempts")
+	}
+}
+
+func TestFailedAttemptAfterVerifyDoesNotRestoreOTP(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+	cache := auc.cache.(*mockCacheStore)
+	phone := "+15551234567"
+	cache.store[otpKey(phone)] = otpRecordFor(auc.otpPepper, phone, "123456")
+
+	// A wrong guess that read the record before a successful verify
+	// consumed it, and is counted after.
+	if err := auc.validateOTP(ctx, phone, "000000"); !errors.Is(err, errOTPMismatch) {
+		t.Fatalf("validateOTP() error = %v, want %v", err, errOTPMismatch)
+	}
+	if _, _, err := auc.VerifyOTPAndIssueToken(ctx, phone, "123456", ""); err != nil {
+		t.Fatalf("VerifyOTPAndIssueToken() unexpected error: %v", err)
+	}
+	if err := auc.registerPhoneFailure(ctx, phone); !errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("registerPhoneFailure() error = %v, want %v", err, ErrInvalidOTP)
+	}
+	if _, ok := cache.store[otpKey(phone)]; ok {
+		t.Errorf("consumed OTP was written back")
+	}
+	if _, ok := cache.store[otpAttemptsKey(phone)]; ok {
+		t.Errorf("attempt counted against a consumed OTP")
+	}
+}
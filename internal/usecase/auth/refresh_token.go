@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/logging"
+
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid_refresh_token")
+	ErrRefreshTokenReused  = errors.New("refresh_token_reused")
+)
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// Refresh exchanges a refresh token for a new pair. Every refresh token can
+// be used once; presenting one again means it was copied, so the whole family
+// is revoked and both the attacker and the legitimate client must log in again.
+func (auc *AuthUsecase) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, ErrInvalidRefreshToken
+	}
+	stored, err := auc.refreshTokens.GetByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+	if stored.UsedAt != nil {
+		return nil, auc.revokeReusedFamily(ctx, stored)
+	}
+
+	user, err := auc.users.GetByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	// Checked before the token is used up, so it works again once a
+	// suspension ends.
+	if err := restrictionError(user); err != nil {
+		return nil, err
+	}
+
+	client := authdomain.ClientInfoFromContext(ctx)
+	if err := auc.sessions.Touch(ctx, stored.FamilyID, client.IP, client.UserAgent, time.Now().Add(auc.refreshTTL)); err != nil {
+		return nil, fmt.Errorf("failed to update session: %w", err)
+	}
+
+	marked, err := auc.refreshTokens.MarkUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+	if !marked {
+		return nil, auc.revokeReusedFamily(ctx, stored)
+	}
+	return auc.issueTokens(ctx, user, stored.FamilyID)
+}
+
+func (auc *AuthUsecase) revokeReusedFamily(ctx context.Context, stored *authdomain.RefreshToken) error {
+	logging.FromContext(ctx).Warn("refresh token reused, revoking session", "user_id", stored.UserID, "session_id", stored.FamilyID)
+	if err := auc.closeSession(ctx, stored.UserID, stored.FamilyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+// issueTokens signs an access token and stores a fresh refresh token for the
+// session, whose ID is also the refresh token family.
+func (auc *AuthUsecase) issueTokens(ctx context.Context, user *userdomain.User, sessionID string) (*TokenPair, error) {
+	access, err := auc.generateJWT(user, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := generateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	err = auc.refreshTokens.Create(ctx, &authdomain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  sessionID,
+		TokenHash: hashRefreshToken(raw),
+		ExpiresAt: time.Now().Add(auc.refreshTTL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  access,
+		RefreshToken: raw,
+		ExpiresIn:    int64(auc.tokenTTL.Seconds()),
+	}, nil
+}
+
+func generateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,187 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/infra/jwtkeys"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type mockRefreshTokenRepository struct {
+	tokens map[string]*authdomain.RefreshToken
+}
+
+func newMockRefreshTokenRepository() *mockRefreshTokenRepository {
+	return &mockRefreshTokenRepository{tokens: make(map[string]*authdomain.RefreshToken)}
+}
+
+func (m *mockRefreshTokenRepository) Create(ctx context.Context, t *authdomain.RefreshToken) error {
+	t.ID = uuid.New().String()
+	t.CreatedAt = time.Now()
+	m.tokens[t.TokenHash] = t
+	return nil
+}
+
+func (m *mockRefreshTokenRepository) GetByHash(ctx context.Context, hash string) (*authdomain.RefreshToken, error) {
+	t, ok := m.tokens[hash]
+	if !ok {
+		return nil, pgx.ErrNoRows
+	}
+	cp := *t
+	return &cp, nil
+}
+
+func (m *mockRefreshTokenRepository) MarkUsed(ctx context.Context, id string) (bool, error) {
+	for _, t := range m.tokens {
+		if t.ID == id {
+			if t.UsedAt != nil || t.RevokedAt != nil {
+				return false, nil
+			}
+			now := time.Now()
+			t.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *mockRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	for _, t := range m.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (m *mockRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID string) error {
+	now := time.Now()
+	for _, t := range m.tokens {
+		if t.UserID == userID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func newRefreshTestUsecase() (*AuthUsecase, *mockRefreshTokenRepository) {
+	tokens := newMockRefreshTokenRepository()
+	users := &mockUserRepositoryWithStorage{
+		users: map[string]*userdomain.User{
+			"+15551234567": {ID: "user-1", Phone: "+15551234567", CreatedAt: time.Now()},
+		},
+	}
+	return &AuthUsecase{
+		users:         users,
+		refreshTokens: tokens,
+		sessions:      newMockSessionRepository(),
+		cache:         newMockCacheStore(),
+		otpPepper:     []byte("test-pepper"),
+		policy:        testOTPPolicy,
+		keys:          jwtkeys.NewHMAC([]byte("test-secret")),
+		issuer:        "dekamond",
+		audience:      "dekamond-api",
+		tokenTTL:      15 * time.Minute,
+		refreshTTL:    time.Hour,
+	}, tokens
+}
+
+func TestRefreshRotatesToken(t *testing.T) {
+	ctx := context.Background()
+	auc, tokens := newRefreshTestUsecase()
+
+	first, err := auc.startSession(ctx, &userdomain.User{ID: "user-1", Phone: "+15551234567"}, "")
+	if err != nil {
+		t.Fatalf("startSession() unexpected error: %v", err)
+	}
+
+	second, err := auc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() unexpected error: %v", err)
+	}
+	if second.AccessToken == "" || second.RefreshToken == "" || second.RefreshToken == first.RefreshToken {
+		t.Errorf("Refresh() tokens = %+v, want a rotated pair", second)
+	}
+	if second.ExpiresIn != int64((15 * time.Minute).Seconds()) {
+		t.Errorf("Refresh() expires_in = %d", second.ExpiresIn)
+	}
+
+	old := tokens.tokens[hashRefreshToken(first.RefreshToken)]
+	rotated := tokens.tokens[hashRefreshToken(second.RefreshToken)]
+	if old.UsedAt == nil {
+		t.Errorf("Refresh() old token not marked used")
+	}
+	if rotated.FamilyID != old.FamilyID {
+		t.Errorf("Refresh() rotated family = %s, want %s", rotated.FamilyID, old.FamilyID)
+	}
+}
+
+func TestRefreshReuseRevokesFamily(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+
+	first, err := auc.startSession(ctx, &userdomain.User{ID: "user-1", Phone: "+15551234567"}, "")
+	if err != nil {
+		t.Fatalf("startSession() unexpected error: %v", err)
+	}
+	second, err := auc.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() unexpected error: %v", err)
+	}
+
+	if _, err := auc.Refresh(ctx, first.RefreshToken); !errors.Is(err, ErrRefreshTokenReused) {
+		t.Errorf("Refresh() with used token error = %v, want %v", err, ErrRefreshTokenReused)
+	}
+	if _, err := auc.Refresh(ctx, second.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() after reuse error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+}
+
+func TestRefreshInvalidToken(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name  string
+		setup func(*mockRefreshTokenRepository) string
+	}{
+		{
+			name:  "empty token",
+			setup: func(m *mockRefreshTokenRepository) string { return "" },
+		},
+		{
+			name:  "unknown token",
+			setup: func(m *mockRefreshTokenRepository) string { return "does-not-exist" },
+		},
+		{
+			name: "expired token",
+			setup: func(m *mockRefreshTokenRepository) string {
+				_ = m.Create(ctx, &authdomain.RefreshToken{
+					UserID:    "user-1",
+					FamilyID:  "family-1",
+					TokenHash: hashRefreshToken("expired"),
+					ExpiresAt: time.Now().Add(-time.Minute),
+				})
+				return "expired"
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auc, tokens := newRefreshTestUsecase()
+			raw := tt.setup(tokens)
+
+			if _, err := auc.Refresh(ctx, raw); !errors.Is(err, ErrInvalidRefreshToken) {
+				t.Errorf("Refresh() error = %v, want %v", err, ErrInvalidRefreshToken)
+			}
+		})
+	}
+}
@@ -3,36 +3,147 @@ package auth
 import (
 	"context"
 	"crypto/rand"
+	"errors"
 	"fmt"
 	"math/big"
+	"strconv"
 	"time"
+
+	"dekamond/internal/config"
+	auditdomain "dekamond/internal/domain/audit"
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/logging"
+	"dekamond/internal/metrics"
+	"dekamond/internal/phone"
+
+	"github.com/google/uuid"
 )
 
-func (auc *AuthUsecase) RequestOTP(ctx context.Context, phone string) (string, error) {
-	otp, err := generateNumericOTP(6)
+const (
+	numericAlphabet = "0123456789"
+	// No 0/O, 1/I/L, so codes survive being read out or typed by hand.
+	alphanumericAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+)
+
+var (
+	ErrUnsupportedChannel = errors.New("unsupported_channel")
+	ErrOTPDeliveryFailed  = errors.New("otp_delivery_failed")
+	ErrResendTooSoon      = errors.New("resend_too_soon")
+	ErrCountryNotAllowed  = errors.New("country_not_allowed")
+)
+
+// OTPIssued tells the client how long the code is valid and when it may ask
+// for another one.
+type OTPIssued struct {
+	ExpiresIn   time.Duration
+	ResendAfter time.Duration
+}
+
+func (auc *AuthUsecase) RequestOTP(ctx context.Context, phone string, channel authdomain.OTPChannel) (*OTPIssued, error) {
+	issued, err := auc.requestOTP(ctx, phone, channel)
+	auc.record(ctx, auditdomain.Event{
+		Action:  auditdomain.ActionOTPRequested,
+		Target:  auc.phoneTarget(phone),
+		Details: map[string]string{"channel": string(channel)},
+	}, err)
+	return issued, err
+}
+
+func (auc *AuthUsecase) requestOTP(ctx context.Context, phone string, channel authdomain.OTPChannel) (*OTPIssued, error) {
+	if !auc.countryAllowed(phone) {
+		return nil, ErrCountryNotAllowed
+	}
+	if err := auc.checkResendCooldown(ctx, phone); err != nil {
+		return nil, err
+	}
+
+	otp, err := generateOTP(auc.policy.Length, otpAlphabet(auc.policy.Alphabet))
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	rec := &otpRecord{
+		Hash:      hashOTP(auc.otpPepper, phone, otp),
+		Channel:   channel,
+		RequestID: uuid.New().String(),
+		IssuedAt:  now,
+		ExpiresAt: now.Add(auc.policy.TTL),
+	}
+	if err := auc.storeOTPRecord(ctx, phone, rec); err != nil {
+		return nil, err
+	}
+	_ = auc.cache.Delete(ctx, otpAttemptsKey(phone))
+
+	msg := authdomain.OTPMessage{Phone: phone, Code: otp, Channel: channel, TTL: auc.policy.TTL}
+	if err := auc.sender.Send(ctx, msg); err != nil {
+		_ = auc.cache.Delete(ctx, otpKey(phone))
+		logging.FromContext(ctx).Warn("otp delivery failed", "phone", phone, "channel", channel, "err", err)
+		return nil, sendError(err)
+	}
+	metrics.OTPRequested.WithLabelValues(string(channel)).Inc()
+
+	auc.startResendCooldown(ctx, phone, now)
+	return &OTPIssued{ExpiresIn: auc.policy.TTL, ResendAfter: auc.policy.ResendInterval}, nil
+}
+
+func sendError(err error) error {
+	if errors.Is(err, authdomain.ErrUnsupportedChannel) {
+		return ErrUnsupportedChannel
+	}
+	return fmt.Errorf("%w: %v", ErrOTPDeliveryFailed, err)
+}
+
+func (auc *AuthUsecase) startResendCooldown(ctx context.Context, phone string, now time.Time) {
+	if auc.policy.ResendInterval > 0 {
+		until := strconv.FormatInt(now.Add(auc.policy.ResendInterval).Unix(), 10)
+		_ = auc.cache.Set(ctx, otpCooldownKey(phone), until, auc.policy.ResendInterval)
+	}
+}
+
+func (auc *AuthUsecase) checkResendCooldown(ctx context.Context, phone string) error {
+	val, err := auc.cache.Get(ctx, otpCooldownKey(phone))
+	if errors.Is(err, userdomain.ErrNotFound) {
+		return nil
+	}
 	if err != nil {
-		return "", err
+		return err
 	}
+	until, _ := strconv.ParseInt(val, 10, 64)
+	return &RetryError{Err: ErrResendTooSoon, RetryAfter: time.Until(time.Unix(until, 0))}
+}
 
-	key := otpKey(phone)
-	if err := auc.cache.Set(ctx, key, otp, 2*time.Minute); err != nil {
-		return "", err
+// countryAllowed applies the policy's allow and deny lists to the region of
+// the number.
+func (auc *AuthUsecase) countryAllowed(number string) bool {
+	region, _ := phone.Region(number)
+	return auc.policy.CountryAllowed(region)
+}
+
+func otpAlphabet(name string) string {
+	if name == config.OTPAlphabetAlphanumeric {
+		return alphanumericAlphabet
 	}
-	return otp, nil
+	return numericAlphabet
 }
 
 func generateNumericOTP(n int) (string, error) {
+	return generateOTP(n, numericAlphabet)
+}
+
+func generateOTP(n int, alphabet string) (string, error) {
 	if n <= 0 {
 		return "", fmt.Errorf("otp length must be > 0")
 	}
-	const base = 10
+	base := big.NewInt(int64(len(alphabet)))
 	b := make([]byte, n)
 	for i := 0; i < n; i++ {
-		d, err := rand.Int(rand.Reader, big.NewInt(base))
+		d, err := rand.Int(rand.Reader, base)
 		if err != nil {
 			return "", err
 		}
-		b[i] = byte('0' + d.Int64())
+		b[i] = alphabet[d.Int64()]
 	}
 	return string(b), nil
 }
@@ -41,3 +152,6 @@ func otpKey(phone string) string {
 	return fmt.Sprintf("otp:%s", phone)
 }
 
+func otpCooldownKey(phone string) string {
+	return fmt.Sprintf("otp:cooldown:%s", phone)
+}
@@ -2,10 +2,17 @@ package auth
 
 import (
 	"context"
+	"errors"
+	"encoding/json"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
+	"dekamond/internal/config"
+	authdomain "dekamond/internal/domain/auth"
 	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/infra/jwtkeys"
 )
 
 type mockUserRepository struct{}
@@ -26,22 +33,61 @@ func (m *mockUserRepository) GetByID(ctx context.Context, id string) (*userdomai
 	return nil, nil
 }
 
-func (m *mockUserRepository) List(ctx context.Context, search string, limit, offset int) ([]userdomain.User, int, error) {
-	return nil, 0, nil
+func (m *mockUserRepository) List(ctx context.Context, f userdomain.ListFilter) ([]userdomain.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserRepository) Count(ctx context.Context, f userdomain.ListFilter, exact bool) (int, error) {
+	return 0, nil
+}
+
+func (m *mockUserRepository) Stream(ctx context.Context, f userdomain.ListFilter, fn func(userdomain.User) error) error {
+	return nil
+}
+
+func (m *mockUserRepository) UpdateProfile(ctx context.Context, id string, p userdomain.Profile, version int64) (*userdomain.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserRepository) ChangePhone(ctx context.Context, c userdomain.PhoneChange) (*userdomain.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserRepository) SoftDelete(ctx context.Context, id string) (time.Time, error) {
+	return time.Now(), nil
+}
+
+func (m *mockUserRepository) Restore(ctx context.Context, phone string) (*userdomain.User, error) {
+	return nil, nil
+}
+
+func (m *mockUserRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockUserRepository) Export(ctx context.Context, id string) (*userdomain.Export, error) {
+	return nil, nil
+}
+
+func (m *mockUserRepository) SetStatus(ctx context.Context, id string, c userdomain.StatusChange) (*userdomain.User, error) {
+	return nil, nil
 }
 
 type mockCacheStore struct {
 	store map[string]string
+	ttls  map[string]time.Duration
 }
 
 func newMockCacheStore() *mockCacheStore {
 	return &mockCacheStore{
 		store: make(map[string]string),
+		ttls:  make(map[string]time.Duration),
 	}
 }
 
 func (m *mockCacheStore) Set(ctx context.Context, key, value string, ttl time.Duration) error {
 	m.store[key] = value
+	m.ttls[key] = ttl
 	return nil
 }
 
@@ -58,20 +104,76 @@ func (m *mockCacheStore) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
-func (m *mockCacheStore) Increment(ctx context.Context, key string) (int64, error) {
-	return 0, nil
+func (m *mockCacheStore) Increment(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	n, _ := strconv.ParseInt(m.store[key], 10, 64)
+	n++
+	m.store[key] = strconv.FormatInt(n, 10)
+	if _, ok := m.ttls[key]; !ok {
+		m.ttls[key] = ttl
+	}
+	return n, nil
+}
+
+func (m *mockCacheStore) IncrementIfExists(ctx context.Context, key, counterKey string) (int64, error) {
+	if _, ok := m.store[key]; !ok {
+		return 0, userdomain.ErrNotFound
+	}
+	return m.Increment(ctx, counterKey, 0)
 }
 
 func (m *mockCacheStore) SetExpiry(ctx context.Context, key string, ttl time.Duration) error {
 	return nil
 }
 
+type mockOTPSender struct {
+	sent []authdomain.OTPMessage
+	err  error
+}
+
+func (m *mockOTPSender) Send(ctx context.Context, msg authdomain.OTPMessage) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+func (m *mockOTPSender) last() authdomain.OTPMessage {
+	if len(m.sent) == 0 {
+		return authdomain.OTPMessage{}
+	}
+	return m.sent[len(m.sent)-1]
+}
+
+var testOTPPolicy = config.OTPPolicy{
+	Length:            6,
+	Alphabet:          config.OTPAlphabetNumeric,
+	TTL:               2 * time.Minute,
+	ResendInterval:    time.Minute,
+	MaxVerifyAttempts: 5,
+}
+
+func otpRecordFor(pepper []byte, phone, code string) string {
+	now := time.Now()
+	b, _ := json.Marshal(otpRecord{
+		Hash:      hashOTP(pepper, phone, code),
+		Channel:   authdomain.OTPChannelSMS,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(testOTPPolicy.TTL),
+	})
+	return string(b)
+}
+
 func TestRequestOTP(t *testing.T) {
 	ctx := context.Background()
+	sender := &mockOTPSender{}
 	auc := &AuthUsecase{
 		users:     &mockUserRepository{},
 		cache:     newMockCacheStore(),
-		jwtSecret: []byte("test-secret"),
+		sender:    sender,
+		otpPepper: []byte("test-pepper"),
+		policy:    testOTPPolicy,
+		keys:      jwtkeys.NewHMAC([]byte("test-secret")),
 		tokenTTL:  24 * time.Hour,
 	}
 
@@ -94,8 +196,8 @@ func TestRequestOTP(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			otp, err := auc.RequestOTP(ctx, tt.phone)
-			
+			issued, err := auc.RequestOTP(ctx, tt.phone, authdomain.OTPChannelSMS)
+
 			if tt.wantError {
 				if err == nil {
 					t.Errorf("RequestOTP() expected error, got nil")
@@ -108,6 +210,15 @@ func TestRequestOTP(t *testing.T) {
 				return
 			}
 
+			if issued.ExpiresIn != testOTPPolicy.TTL || issued.ResendAfter != testOTPPolicy.ResendInterval {
+				t.Errorf("RequestOTP() issued = %+v", issued)
+			}
+
+			msg := sender.last()
+			if msg.Phone != tt.phone || msg.Channel != authdomain.OTPChannelSMS {
+				t.Errorf("RequestOTP() sent to %s via %s, want %s via sms", msg.Phone, msg.Channel, tt.phone)
+			}
+			otp := msg.Code
 			if len(otp) != 6 {
 				t.Errorf("RequestOTP() OTP length = %d, want 6", len(otp))
 			}
@@ -119,13 +230,179 @@ func TestRequestOTP(t *testing.T) {
 				}
 			}
 
-			key := otpKey(tt.phone)
-			storedOTP, err := auc.cache.Get(ctx, key)
+			rec, err := auc.loadOTPRecord(ctx, tt.phone)
 			if err != nil {
-				t.Errorf("RequestOTP() OTP not stored in cache: %v", err)
+				t.Fatalf("RequestOTP() OTP not stored in cache: %v", err)
+			}
+			if strings.Contains(auc.cache.(*mockCacheStore).store[otpKey(tt.phone)], otp) {
+				t.Errorf("RequestOTP() stored the plaintext OTP")
+			}
+			if !rec.matches(auc.otpPepper, tt.phone, otp) {
+				t.Errorf("RequestOTP() stored hash does not match the sent OTP")
+			}
+			if rec.Channel != authdomain.OTPChannelSMS || rec.RequestID == "" || rec.IssuedAt.IsZero() {
+				t.Errorf("RequestOTP() record metadata = %+v", rec)
+			}
+			if got := rec.ExpiresAt.Sub(rec.IssuedAt); got != testOTPPolicy.TTL {
+				t.Errorf("RequestOTP() record lifetime = %s, want %s", got, testOTPPolicy.TTL)
+			}
+		})
+	}
+}
+
+func TestRequestOTPDeliveryFailure(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		sendErr   error
+		wantError error
+	}{
+		{
+			name:      "channel not configured",
+			sendErr:   authdomain.ErrUnsupportedChannel,
+			wantError: ErrUnsupportedChannel,
+		},
+		{
+			name:      "provider error",
+			sendErr:   errors.New("gateway timeout"),
+			wantError: ErrOTPDeliveryFailed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := newMockCacheStore()
+			auc := &AuthUsecase{
+				cache:  cache,
+				sender: &mockOTPSender{err: tt.sendErr},
+				policy: testOTPPolicy,
+			}
+
+			_, err := auc.RequestOTP(ctx, "+15551234567", authdomain.OTPChannelVoice)
+			if !errors.Is(err, tt.wantError) {
+				t.Errorf("RequestOTP() error = %v, want %v", err, tt.wantError)
+			}
+			if _, err := cache.Get(ctx, otpKey("+15551234567")); err == nil {
+				t.Errorf("RequestOTP() OTP should not be kept when delivery fails")
+			}
+			if _, err := cache.Get(ctx, otpCooldownKey("+15551234567")); err == nil {
+				t.Errorf("RequestOTP() failed delivery should not start the resend cooldown")
+			}
+		})
+	}
+}
+
+func TestRequestOTPResendCooldown(t *testing.T) {
+	ctx := context.Background()
+	sender := &mockOTPSender{}
+	auc := &AuthUsecase{
+		cache:  newMockCacheStore(),
+		sender: sender,
+		policy: testOTPPolicy,
+	}
+	phone := "+15551234567"
+
+	if _, err := auc.RequestOTP(ctx, phone, authdomain.OTPChannelSMS); err != nil {
+		t.Fatalf("first RequestOTP() unexpected error: %v", err)
+	}
+
+	_, err := auc.RequestOTP(ctx, phone, authdomain.OTPChannelSMS)
+	var retry *RetryError
+	if !errors.As(err, &retry) || !errors.Is(err, ErrResendTooSoon) {
+		t.Fatalf("second RequestOTP() error = %v, want %v", err, ErrResendTooSoon)
+	}
+	if retry.RetryAfter <= 0 || retry.RetryAfter > testOTPPolicy.ResendInterval {
+		t.Errorf("second RequestOTP() retry after = %s, want (0, %s]", retry.RetryAfter, testOTPPolicy.ResendInterval)
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("RequestOTP() sent %d messages, want 1", len(sender.sent))
+	}
+
+	if _, err := auc.RequestOTP(ctx, "+15557654321", authdomain.OTPChannelSMS); err != nil {
+		t.Errorf("RequestOTP() for another phone unexpected error: %v", err)
+	}
+}
+
+func TestRequestOTPPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name     string
+		length   int
+		alphabet string
+		charset  string
+	}{
+		{
+			name:     "numeric",
+			length:   4,
+			alphabet: config.OTPAlphabetNumeric,
+			charset:  numericAlphabet,
+		},
+		{
+			name:     "alphanumeric",
+			length:   8,
+			alphabet: config.OTPAlphabetAlphanumeric,
+			charset:  alphanumericAlphabet,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &mockOTPSender{}
+			policy := testOTPPolicy
+			policy.Length, policy.Alphabet = tt.length, tt.alphabet
+			auc := &AuthUsecase{cache: newMockCacheStore(), sender: sender, policy: policy}
+
+			if _, err := auc.RequestOTP(ctx, "+15551234567", authdomain.OTPChannelSMS); err != nil {
+				t.Fatalf("RequestOTP() unexpected error: %v", err)
+			}
+			code := sender.last().Code
+			if len(code) != tt.length {
+				t.Errorf("RequestOTP() OTP length = %d, want %d", len(code), tt.length)
+			}
+			for _, char := range code {
+				if !strings.ContainsRune(tt.charset, char) {
+					t.Errorf("RequestOTP() OTP contains %c, not in %s", char, tt.charset)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestRequestOTPCountryLists(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		allowed []string
+		denied  []string
+		phone   string
+		wantErr error
+	}{
+		{name: "no lists", phone: "+989121234567"},
+		{name: "allowed region", allowed: []string{"IR", "US"}, phone: "+989121234567"},
+		{name: "region not in allow list", allowed: []string{"US"}, phone: "+989121234567", wantErr: ErrCountryNotAllowed},
+		{name: "denied region", denied: []string{"GB"}, phone: "+447400123456", wantErr: ErrCountryNotAllowed},
+		{name: "deny wins over allow", allowed: []string{"GB"}, denied: []string{"GB"}, phone: "+447400123456", wantErr: ErrCountryNotAllowed},
+		{name: "unknown region without allow list", denied: []string{"GB"}, phone: "+8001234567"},
+		{name: "unknown region with allow list", allowed: []string{"US"}, phone: "+8001234567", wantErr: ErrCountryNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &mockOTPSender{}
+			policy := testOTPPolicy
+			policy.AllowedCountries, policy.DeniedCountries = tt.allowed, tt.denied
+			auc := &AuthUsecase{cache: newMockCacheStore(), sender: sender, policy: policy}
+
+			_, err := auc.RequestOTP(ctx, tt.phone, authdomain.OTPChannelSMS)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RequestOTP() error = %v, want %v", err, tt.wantErr)
 			}
-			if storedOTP != otp {
-				t.Errorf("RequestOTP() stored OTP = %s, want %s", storedOTP, otp)
+			if tt.wantErr != nil && len(sender.sent) != 0 {
+				t.Errorf("RequestOTP() sent a code to a blocked region")
 			}
 		})
 	}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var ErrSessionNotFound = errors.New("session_not_found")
+
+const maxDeviceNameLen = 64
+
+// startSession records a login from the calling client and issues the first
+// token pair of the new session.
+func (auc *AuthUsecase) startSession(ctx context.Context, user *userdomain.User, deviceName string) (*TokenPair, error) {
+	client := authdomain.ClientInfoFromContext(ctx)
+	session := &authdomain.Session{
+		UserID:     user.ID,
+		DeviceName: cleanDeviceName(deviceName),
+		UserAgent:  client.UserAgent,
+		IP:         client.IP,
+		ExpiresAt:  time.Now().Add(auc.refreshTTL),
+	}
+	if err := auc.sessions.Create(ctx, session); err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	return auc.issueTokens(ctx, user, session.ID)
+}
+
+// ListSessions returns the user's active sessions and marks the one
+// currentID, the caller's own, as current.
+func (auc *AuthUsecase) ListSessions(ctx context.Context, userID, currentID string) ([]authdomain.Session, error) {
+	sessions, err := auc.sessions.ListActive(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends one of the user's sessions. Its refresh tokens stop
+// working and its access tokens are rejected from the next request on.
+func (auc *AuthUsecase) RevokeSession(ctx context.Context, userID, sessionID string) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return ErrSessionNotFound
+	}
+	err := auc.sessions.Revoke(ctx, userID, sessionID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrSessionNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return auc.endSession(ctx, sessionID)
+}
+
+// closeSession is RevokeSession for internal callers, which also want the
+// tokens cleaned up when the session row is already revoked or expired.
+func (auc *AuthUsecase) closeSession(ctx context.Context, userID, sessionID string) error {
+	err := auc.sessions.Revoke(ctx, userID, sessionID)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	return auc.endSession(ctx, sessionID)
+}
+
+func (auc *AuthUsecase) endSession(ctx context.Context, sessionID string) error {
+	if err := auc.refreshTokens.RevokeFamily(ctx, sessionID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	// Every access token of the session expires within one token lifetime.
+	if err := auc.cache.Set(ctx, deniedSessionKey(sessionID), "1", auc.tokenTTL+tokenLeeway); err != nil {
+		return fmt.Errorf("failed to revoke session access tokens: %w", err)
+	}
+	return nil
+}
+
+func deniedSessionKey(sid string) string {
+	return fmt.Sprintf("jwt:denied_session:%s", sid)
+}
+
+// cleanDeviceName drops control characters and caps the client-chosen name.
+func cleanDeviceName(name string) string {
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+	if r := []rune(name); len(r) > maxDeviceNameLen {
+		name = strings.TrimSpace(string(r[:maxDeviceNameLen]))
+	}
+	return name
+}
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type mockSessionRepository struct {
+	sessions map[string]*authdomain.Session
+}
+
+func newMockSessionRepository() *mockSessionRepository {
+	return &mockSessionRepository{sessions: make(map[string]*authdomain.Session)}
+}
+
+func (m *mockSessionRepository) Create(ctx context.Context, s *authdomain.Session) error {
+	s.ID = uuid.New().String()
+	s.CreatedAt = time.Now()
+	s.LastSeenAt = s.CreatedAt
+	cp := *s
+	m.sessions[s.ID] = &cp
+	return nil
+}
+
+func (m *mockSessionRepository) Touch(ctx context.Context, id, ip, userAgent string, expiresAt time.Time) error {
+	if s, ok := m.sessions[id]; ok && s.RevokedAt == nil {
+		s.LastSeenAt = time.Now()
+		s.ExpiresAt = expiresAt
+		if ip != "" {
+			s.IP = ip
+		}
+	}
+	return nil
+}
+
+func (m *mockSessionRepository) ListActive(ctx context.Context, userID string) ([]authdomain.Session, error) {
+	var out []authdomain.Session
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.RevokedAt == nil && s.ExpiresAt.After(time.Now()) {
+			out = append(out, *s)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastSeenAt.After(out[j].LastSeenAt) })
+	return out, nil
+}
+
+func (m *mockSessionRepository) Revoke(ctx context.Context, userID, id string) error {
+	s, ok := m.sessions[id]
+	if !ok || s.UserID != userID || s.RevokedAt != nil {
+		return pgx.ErrNoRows
+	}
+	now := time.Now()
+	s.RevokedAt = &now
+	return nil
+}
+
+func (m *mockSessionRepository) RevokeAllForUser(ctx context.Context, userID string) error {
+	now := time.Now()
+	for _, s := range m.sessions {
+		if s.UserID == userID && s.RevokedAt == nil {
+			s.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func TestSessionLifecycle(t *testing.T) {
+	auc, tokens := newRefreshTestUsecase()
+	sessions := auc.sessions.(*mockSessionRepository)
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+
+	phoneCtx := authdomain.WithClientInfo(context.Background(), authdomain.ClientInfo{IP: "203.0.113.7", UserAgent: "App/1.0"})
+	phonePair, err := auc.startSession(phoneCtx, user, "  Pixel 8\n")
+	if err != nil {
+		t.Fatalf("startSession() unexpected error: %v", err)
+	}
+	laptopPair, err := auc.startSession(context.Background(), user, "Laptop")
+	if err != nil {
+		t.Fatalf("startSession() unexpected error: %v", err)
+	}
+	ctx := context.Background()
+	laptop, err := auc.VerifyAccessToken(ctx, laptopPair.AccessToken)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken() unexpected error: %v", err)
+	}
+
+	list, err := auc.ListSessions(ctx, user.ID, laptop.SessionID)
+	if err != nil || len(list) != 2 {
+		t.Fatalf("ListSessions() = %v, %v, want 2 sessions", list, err)
+	}
+	var phoneSession authdomain.Session
+	for _, s := range list {
+		if s.Current != (s.ID == laptop.SessionID) {
+			t.Errorf("session %s current = %v", s.ID, s.Current)
+		}
+		if s.DeviceName == "Pixel 8" {
+			phoneSession = s
+		}
+	}
+	if phoneSession.IP != "203.0.113.7" || phoneSession.UserAgent != "App/1.0" {
+		t.Fatalf("phone session = %+v, want device name, IP and user agent recorded", phoneSession)
+	}
+
+	if err := auc.RevokeSession(ctx, "user-2", phoneSession.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("RevokeSession() of another user's session error = %v, want %v", err, ErrSessionNotFound)
+	}
+	if err := auc.RevokeSession(ctx, user.ID, "not-a-uuid"); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("RevokeSession() with malformed ID error = %v, want %v", err, ErrSessionNotFound)
+	}
+	if err := auc.RevokeSession(ctx, user.ID, phoneSession.ID); err != nil {
+		t.Fatalf("RevokeSession() unexpected error: %v", err)
+	}
+
+	if _, err := auc.VerifyAccessToken(ctx, phonePair.AccessToken); !errors.Is(err, ErrTokenRevoked) {
+		t.Errorf("VerifyAccessToken() for revoked session error = %v, want %v", err, ErrTokenRevoked)
+	}
+	if _, err := auc.Refresh(ctx, phonePair.RefreshToken); !errors.Is(err, ErrInvalidRefreshToken) {
+		t.Errorf("Refresh() for revoked session error = %v, want %v", err, ErrInvalidRefreshToken)
+	}
+	if _, err := auc.VerifyAccessToken(ctx, laptopPair.AccessToken); err != nil {
+		t.Errorf("VerifyAccessToken() for other session unexpected error: %v", err)
+	}
+	if err := auc.RevokeSession(ctx, user.ID, phoneSession.ID); !errors.Is(err, ErrSessionNotFound) {
+		t.Errorf("RevokeSession() twice error = %v, want %v", err, ErrSessionNotFound)
+	}
+	if list, _ := auc.ListSessions(ctx, user.ID, ""); len(list) != 1 {
+		t.Errorf("ListSessions() after revoke = %d sessions, want 1", len(list))
+	}
+
+	// Refreshing keeps the session and moves last seen.
+	before := sessions.sessions[laptop.SessionID].LastSeenAt
+	refreshed, err := auc.Refresh(authdomain.WithClientInfo(ctx, authdomain.ClientInfo{IP: "198.51.100.1"}), laptopPair.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh() unexpected error: %v", err)
+	}
+	again, err := auc.VerifyAccessToken(ctx, refreshed.AccessToken)
+	if err != nil || again.SessionID != laptop.SessionID {
+		t.Fatalf("refreshed token session = %v (%v), want %s", again, err, laptop.SessionID)
+	}
+	if s := sessions.sessions[laptop.SessionID]; !s.LastSeenAt.After(before) || s.IP != "198.51.100.1" {
+		t.Errorf("session after refresh = %+v, want last seen and IP updated", s)
+	}
+	for _, tok := range tokens.tokens {
+		if tok.FamilyID != phoneSession.ID && tok.FamilyID != laptop.SessionID {
+			t.Errorf("refresh token family %s belongs to no session", tok.FamilyID)
+		}
+	}
+}
+
+func TestCleanDeviceName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", ""},
+		{"  iPhone 15 ", "iPhone 15"},
+		{"bad\x00name\x1b", "badname"},
+		{string(make([]rune, 100)), ""},
+		{"ک" + string([]rune("abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyz")), "ک" + "abcdefghijklmnopqrstuvwxyzabcdefghijklmnopqrstuvwxyzabcdefghijk"},
+	}
+	for _, tt := range tests {
+		if got := cleanDeviceName(tt.in); got != tt.want {
+			t.Errorf("cleanDeviceName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid_token")
+	ErrTokenRevoked = errors.New("token_revoked")
+)
+
+// tokenLeeway is the clock skew allowed when checking an access token's
+// times. Denylist entries outlive the token by as much, or a revoked token
+// would be accepted again inside the leeway.
+const tokenLeeway = 30 * time.Second
+
+type accessClaims struct {
+	Phone string   `json:"phone"`
+	Roles []string `json:"roles,omitempty"`
+	// SessionID is the session the token was issued to; tokens from before
+	// sessions existed have none.
+	SessionID string `json:"sid,omitempty"`
+	// IssuedAtMs is iat in milliseconds, so a token issued right after a
+	// logout-all in the same second is not caught by its cutoff.
+	IssuedAtMs int64 `json:"iat_ms,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func (auc *AuthUsecase) parseAccessToken(tokenStr string) (*accessClaims, error) {
+	claims := &accessClaims{}
+	_, err := jwt.ParseWithClaims(tokenStr, claims, auc.keys.Keyfunc,
+		jwt.WithIssuer(auc.issuer),
+		jwt.WithAudience(auc.audience),
+		jwt.WithIssuedAt(),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(tokenLeeway),
+	)
+	if err != nil || claims.ID == "" || claims.Subject == "" || claims.IssuedAt == nil || claims.IssuedAtMs == 0 || claims.ExpiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// VerifyAccessToken checks the signature, issuer, audience and lifetime of an
+// access token, that neither it nor its session has been revoked and that its
+// user is not suspended or banned.
+func (auc *AuthUsecase) VerifyAccessToken(ctx context.Context, tokenStr string) (*authdomain.Principal, error) {
+	claims, err := auc.parseAccessToken(tokenStr)
+	if err != nil {
+		return nil, err
+	}
+
+	revoked, err := auc.isRevoked(ctx, claims)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return nil, ErrTokenRevoked
+	}
+	if err := auc.checkUserStatus(ctx, claims.Subject); err != nil {
+		if errors.Is(err, ErrAccountSuspended) || errors.Is(err, ErrAccountBanned) || errors.Is(err, ErrTokenRevoked) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to check user status: %w", err)
+	}
+	return &authdomain.Principal{
+		UserID:    claims.Subject,
+		Phone:     claims.Phone,
+		Roles:     claims.Roles,
+		TokenID:   claims.ID,
+		SessionID: claims.SessionID,
+	}, nil
+}
+
+func (auc *AuthUsecase) isRevoked(ctx context.Context, claims *accessClaims) (bool, error) {
+	_, err := auc.cache.Get(ctx, deniedTokenKey(claims.ID))
+	if err == nil {
+		return true, nil
+	}
+	if !errors.Is(err, userdomain.ErrNotFound) {
+		return false, err
+	}
+	if claims.SessionID != "" {
+		_, err := auc.cache.Get(ctx, deniedSessionKey(claims.SessionID))
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, userdomain.ErrNotFound) {
+			return false, err
+		}
+	}
+
+	val, err := auc.cache.Get(ctx, revokedBeforeKey(claims.Subject))
+	if errors.Is(err, userdomain.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	cutoff, err := strconv.ParseInt(val, 10, 64)
+	if err != nil {
+		return false, nil
+	}
+	return claims.IssuedAtMs <= cutoff, nil
+}
+
+// denyToken keeps the token's ID on the denylist for as long as the token
+// would otherwise be accepted.
+func (auc *AuthUsecase) denyToken(ctx context.Context, claims *accessClaims) error {
+	ttl := time.Until(claims.ExpiresAt.Time) + tokenLeeway
+	if ttl <= 0 {
+		return nil
+	}
+	return auc.cache.Set(ctx, deniedTokenKey(claims.ID), "1", ttl)
+}
+
+func deniedTokenKey(jti string) string {
+	return fmt.Sprintf("jwt:denied:%s", jti)
+}
+
+func revokedBeforeKey(userID string) string {
+	return fmt.Sprintf("jwt:revoked_before:%s", userID)
+}
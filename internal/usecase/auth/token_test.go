@@ -0,0 +1,88 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/infra/jwtkeys"
+)
+
+func TestVerifyAccessToken(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567", Roles: []string{"admin", "user"}}
+
+	token, err := auc.generateJWT(user, "")
+	if err != nil {
+		t.Fatalf("generateJWT() unexpected error: %v", err)
+	}
+	principal, err := auc.VerifyAccessToken(ctx, token)
+	if err != nil {
+		t.Fatalf("VerifyAccessToken() unexpected error: %v", err)
+	}
+	if principal.UserID != user.ID || principal.Phone != user.Phone || principal.TokenID == "" {
+		t.Errorf("VerifyAccessToken() principal = %+v", principal)
+	}
+	if !principal.HasRole("admin") || !principal.HasRole("user") {
+		t.Errorf("VerifyAccessToken() roles = %v, want [admin user]", principal.Roles)
+	}
+}
+
+func TestVerifyAccessTokenRejects(t *testing.T) {
+	ctx := context.Background()
+	user := &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+
+	tests := []struct {
+		name   string
+		signer func(*AuthUsecase) *AuthUsecase
+	}{
+		{
+			name: "wrong key",
+			signer: func(auc *AuthUsecase) *AuthUsecase {
+				cp := *auc
+				cp.keys = jwtkeys.NewHMAC([]byte("other-secret"))
+				return &cp
+			},
+		},
+		{
+			name: "expired",
+			signer: func(auc *AuthUsecase) *AuthUsecase {
+				cp := *auc
+				cp.tokenTTL = -time.Hour
+				return &cp
+			},
+		},
+		{
+			name: "wrong issuer",
+			signer: func(auc *AuthUsecase) *AuthUsecase {
+				cp := *auc
+				cp.issuer = "someone-else"
+				return &cp
+			},
+		},
+		{
+			name: "wrong audience",
+			signer: func(auc *AuthUsecase) *AuthUsecase {
+				cp := *auc
+				cp.audience = "billing-api"
+				return &cp
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auc, _ := newRefreshTestUsecase()
+			token, err := tt.signer(auc).generateJWT(user, "")
+			if err != nil {
+				t.Fatalf("generateJWT() unexpected error: %v", err)
+			}
+			if _, err := auc.VerifyAccessToken(ctx, token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("VerifyAccessToken() error = %v, want %v", err, ErrInvalidToken)
+			}
+		})
+	}
+}
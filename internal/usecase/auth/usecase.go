@@ -4,19 +4,49 @@ import (
 	"dekamond/internal/config"
 	"time"
 
+	auditdomain "dekamond/internal/domain/audit"
+	authdomain "dekamond/internal/domain/auth"
 	userdomain "dekamond/internal/domain/user"
 )
 
 
 type AuthUsecase struct {
-	users     userdomain.Repository
-	cache     userdomain.CacheStore
-	jwtSecret []byte
-	tokenTTL  time.Duration
+	users         userdomain.Repository
+	refreshTokens authdomain.RefreshTokenRepository
+	sessions      authdomain.SessionRepository
+	cache         userdomain.CacheStore
+	sender        authdomain.OTPSender
+	otpPepper     []byte
+	auditPhoneKey []byte
+	policy        config.OTPPolicy
+	confirmOld    bool
+	deletionGrace time.Duration
+	keys          authdomain.TokenKeys
+	issuer        string
+	audience      string
+	tokenTTL      time.Duration
+	refreshTTL    time.Duration
+	audit         auditdomain.Recorder
 }
 
-func New(users userdomain.Repository, cache userdomain.CacheStore, conf config.Config) *AuthUsecase {
-	return &AuthUsecase{users: users, cache: cache, jwtSecret: []byte(conf.JWTSecret), tokenTTL: 24 * time.Hour}
+func New(users userdomain.Repository, refreshTokens authdomain.RefreshTokenRepository, sessions authdomain.SessionRepository, cache userdomain.CacheStore, sender authdomain.OTPSender, keys authdomain.TokenKeys, conf config.Config, audit auditdomain.Recorder) *AuthUsecase {
+	return &AuthUsecase{
+		users:         users,
+		refreshTokens: refreshTokens,
+		sessions:      sessions,
+		cache:         cache,
+		sender:        sender,
+		otpPepper:     []byte(conf.OTPPepper),
+		auditPhoneKey: []byte(conf.AuditPhoneKey),
+		policy:        conf.OTPPolicy,
+		confirmOld:    conf.PhoneChangeConfirmOld,
+		deletionGrace: conf.AccountDeletionGrace,
+		keys:          keys,
+		issuer:        conf.JWTIssuer,
+		audience:      conf.JWTAudience,
+		tokenTTL:      conf.AccessTokenTTL,
+		refreshTTL:    conf.RefreshTokenTTL,
+		audit:         audit,
+	}
 }
 
-
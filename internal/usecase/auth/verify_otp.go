@@ -6,39 +6,84 @@ import (
 	"fmt"
 	"time"
 
+	auditdomain "dekamond/internal/domain/audit"
 	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/metrics"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 )
 
-func (auc *AuthUsecase) VerifyOTPAndIssueToken(ctx context.Context, phone, code string) (string, *userdomain.User, error) {
+var (
+	ErrInvalidOTP  = errors.New("invalid_or_expired_otp")
+	errOTPMismatch = fmt.Errorf("code mismatch: %w", ErrInvalidOTP)
+	errOTPNotFound = fmt.Errorf("no code issued or code expired: %w", ErrInvalidOTP)
+)
+
+// VerifyOTPAndIssueToken logs the user in, creating the account on first
+// login, and starts a session labelled deviceName.
+func (auc *AuthUsecase) VerifyOTPAndIssueToken(ctx context.Context, phone, code, deviceName string) (*TokenPair, *userdomain.User, error) {
+	tokens, user, err := auc.verifyOTP(ctx, phone, code, deviceName)
+	e := auditdomain.Event{Action: auditdomain.ActionLogin, Target: auc.phoneTarget(phone)}
+	if user != nil {
+		e.ActorID, e.Target = user.ID, user.ID
+	}
+	auc.record(ctx, e, err)
+	return tokens, user, err
+}
+
+func (auc *AuthUsecase) verifyOTP(ctx context.Context, phone, code, deviceName string) (*TokenPair, *userdomain.User, error) {
+	if err := auc.checkOTPLock(ctx, phone); err != nil {
+		if errors.Is(err, ErrOTPLocked) {
+			metrics.OTPVerifications.WithLabelValues(metrics.OTPLocked).Inc()
+		}
+		return nil, nil, err
+	}
 	if err := auc.validateOTP(ctx, phone, code); err != nil {
-		return "", nil, err
+		switch {
+		case errors.Is(err, errOTPMismatch):
+			metrics.OTPVerifications.WithLabelValues(metrics.OTPFailed).Inc()
+		case errors.Is(err, errOTPNotFound):
+			metrics.OTPVerifications.WithLabelValues(metrics.OTPExpired).Inc()
+		default:
+			return nil, nil, err
+		}
+		if ipErr := auc.registerIPFailure(ctx); ipErr != nil {
+			return nil, nil, ipErr
+		}
+		if errors.Is(err, errOTPMismatch) {
+			return nil, nil, auc.registerPhoneFailure(ctx, phone)
+		}
+		return nil, nil, ErrInvalidOTP
 	}
-	
+	metrics.OTPVerifications.WithLabelValues(metrics.OTPVerified).Inc()
+
 	auc.cache.Delete(ctx, fmt.Sprintf("otp:%s", phone))
+	auc.cache.Delete(ctx, otpAttemptsKey(phone))
 
 	user, err := auc.getOrCreateUser(ctx, phone)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
+	}
+	if err := restrictionError(user); err != nil {
+		return nil, nil, err
 	}
 
-	token, err := auc.generateJWT(user)
+	tokens, err := auc.startSession(ctx, user, deviceName)
 	if err != nil {
-		return "", nil, err
+		return nil, nil, err
 	}
-	return token, user, nil
+	return tokens, user, nil
 }
 
 func (auc *AuthUsecase) validateOTP(ctx context.Context, phone, code string) error {
-	key := fmt.Sprintf("otp:%s", phone)
-	storedOTP, err := auc.cache.Get(ctx, key)
+	rec, err := auc.loadOTPRecord(ctx, phone)
 	if err != nil {
-		return errors.New("invalid_or_expired_otp")
+		return err
 	}
-	if storedOTP != code {
-		return errors.New("invalid_or_expired_otp")
+	if !rec.matches(auc.otpPepper, phone, code) {
+		return errOTPMismatch
 	}
 	return nil
 }
@@ -47,10 +92,22 @@ func (auc *AuthUsecase) getOrCreateUser(ctx context.Context, phone string) (*use
 	user, err := auc.users.GetByPhone(ctx, phone)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
+			// Logging in during the deletion grace period cancels the deletion.
+			user, err = auc.users.Restore(ctx, phone)
+			if err == nil {
+				auc.record(ctx, auditdomain.Event{Action: auditdomain.ActionUserRestored, ActorID: user.ID, Target: user.ID}, nil)
+				return user, nil
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return nil, fmt.Errorf("failed to restore user: %w", err)
+			}
 			user, err = auc.users.Create(ctx, phone)
 			if err != nil {
+				auc.record(ctx, auditdomain.Event{Action: auditdomain.ActionUserCreated, Target: auc.phoneTarget(phone)}, err)
 				return nil, fmt.Errorf("failed to create user: %w", err)
 			}
+			auc.record(ctx, auditdomain.Event{Action: auditdomain.ActionUserCreated, ActorID: user.ID, Target: user.ID}, nil)
+			metrics.UserSignups.Inc()
 			return user, nil
 		}
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -58,14 +115,22 @@ func (auc *AuthUsecase) getOrCreateUser(ctx context.Context, phone string) (*use
 	return user, nil
 }
 
-func (auc *AuthUsecase) generateJWT(user *userdomain.User) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"sub": user.ID,
-		"phone": user.Phone,
-		"exp": time.Now().Add(auc.tokenTTL).Unix(),
-		"iat": time.Now().Unix(),
+func (auc *AuthUsecase) generateJWT(user *userdomain.User, sessionID string) (string, error) {
+	now := time.Now()
+	signed, err := auc.keys.Sign(accessClaims{
+		Phone:      user.Phone,
+		Roles:      user.Roles,
+		SessionID:  sessionID,
+		IssuedAtMs: now.UnixMilli(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.New().String(),
+			Issuer:    auc.issuer,
+			Audience:  jwt.ClaimStrings{auc.audience},
+			Subject:   user.ID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(auc.tokenTTL)),
+		},
 	})
-	signed, err := token.SignedString(auc.jwtSecret)
 	if err != nil {
 		return "", fmt.Errorf("sign token: %w", err)
 	}
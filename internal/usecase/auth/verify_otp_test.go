@@ -2,12 +2,16 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"testing"
 	"time"
 
 	userdomain "dekamond/internal/domain/user"
+	"dekamond/internal/infra/jwtkeys"
+	"dekamond/internal/metrics"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 )
 
 func TestVerifyOTPAndIssueToken(t *testing.T) {
@@ -22,13 +26,15 @@ func TestVerifyOTPAndIssueToken(t *testing.T) {
 		wantError      bool
 		wantErrorMsg   string
 		expectUser     bool
+		wantResult     string
+		wantSignup     bool
 	}{
 		{
 			name:  "valid OTP for existing user",
 			phone: "+15551234567",
 			code:  "123456",
 			setupCache: func(m *mockCacheStore) {
-				m.store["otp:+15551234567"] = "123456"
+				m.store["otp:+15551234567"] = otpRecordFor(nil, "+15551234567", "123456")
 			},
 			setupRepo: func(m *mockUserRepositoryWithStorage) {
 				m.users = map[string]*userdomain.User{
@@ -41,26 +47,29 @@ func TestVerifyOTPAndIssueToken(t *testing.T) {
 			},
 			wantError:  false,
 			expectUser: true,
+			wantResult: metrics.OTPVerified,
 		},
 		{
 			name:  "valid OTP for new user",
 			phone: "+15551234568",
 			code:  "654321",
 			setupCache: func(m *mockCacheStore) {
-				m.store["otp:+15551234568"] = "654321"
+				m.store["otp:+15551234568"] = otpRecordFor(nil, "+15551234568", "654321")
 			},
 			setupRepo: func(m *mockUserRepositoryWithStorage) {
 				m.users = make(map[string]*userdomain.User)
 			},
 			wantError:  false,
 			expectUser: true,
+			wantResult: metrics.OTPVerified,
+			wantSignup: true,
 		},
 		{
 			name:  "invalid OTP",
 			phone: "+15551234567",
 			code:  "wrong",
 			setupCache: func(m *mockCacheStore) {
-				m.store["otp:+15551234567"] = "123456"
+				m.store["otp:+15551234567"] = otpRecordFor(nil, "+15551234567", "123456")
 			},
 			setupRepo: func(m *mockUserRepositoryWithStorage) {
 				m.users = make(map[string]*userdomain.User)
@@ -68,6 +77,7 @@ func TestVerifyOTPAndIssueToken(t *testing.T) {
 			wantError:    true,
 			wantErrorMsg: "invalid_or_expired_otp",
 			expectUser:   false,
+			wantResult:   metrics.OTPFailed,
 		},
 		{
 			name:  "expired OTP",
@@ -81,6 +91,7 @@ func TestVerifyOTPAndIssueToken(t *testing.T) {
 			wantError:    true,
 			wantErrorMsg: "invalid_or_expired_otp",
 			expectUser:   false,
+			wantResult:   metrics.OTPExpired,
 		},
 	}
 
@@ -95,13 +106,28 @@ func TestVerifyOTPAndIssueToken(t *testing.T) {
 			tt.setupRepo(repo)
 
 			auc := &AuthUsecase{
-				users:     repo,
-				cache:     cache,
-				jwtSecret: []byte("test-secret"),
-				tokenTTL:  24 * time.Hour,
+				users:         repo,
+				refreshTokens: newMockRefreshTokenRepository(),
+				sessions:      newMockSessionRepository(),
+				cache:         cache,
+				policy:        testOTPPolicy,
+				keys:          jwtkeys.NewHMAC([]byte("test-secret")),
+				tokenTTL:      24 * time.Hour,
+				refreshTTL:    30 * 24 * time.Hour,
 			}
 
-			token, user, err := auc.VerifyOTPAndIssueToken(ctx, tt.phone, tt.code)
+			results := metrics.OTPVerifications.WithLabelValues(tt.wantResult)
+			resultsBefore := testutil.ToFloat64(results)
+			signupsBefore := testutil.ToFloat64(metrics.UserSignups)
+
+			tokens, user, err := auc.VerifyOTPAndIssueToken(ctx, tt.phone, tt.code, "")
+
+			if n := testutil.ToFloat64(results) - resultsBefore; n != 1 {
+				t.Errorf("otp_verifications_total{result=%q} grew by %v, want 1", tt.wantResult, n)
+			}
+			if n := testutil.ToFloat64(metrics.UserSignups) - signupsBefore; (n == 1) != tt.wantSignup {
+				t.Errorf("user_signups_total grew by %v, want signup %v", n, tt.wantSignup)
+			}
 
 			if tt.wantError {
 				if err == nil {
@@ -119,8 +145,8 @@ func TestVerifyOTPAndIssueToken(t *testing.T) {
 				return
 			}
 
-			if token == "" {
-				t.Errorf("VerifyOTPAndIssueToken() token is empty")
+			if tokens == nil || tokens.AccessToken == "" || tokens.RefreshToken == "" {
+				t.Errorf("VerifyOTPAndIssueToken() tokens = %+v, want access and refresh token", tokens)
 			}
 
 			if tt.expectUser && user == nil {
@@ -155,7 +181,7 @@ func TestValidateOTP(t *testing.T) {
 			phone: "+15551234567",
 			code:  "123456",
 			setupCache: func(m *mockCacheStore) {
-				m.store["otp:+15551234567"] = "123456"
+				m.store["otp:+15551234567"] = otpRecordFor(nil, "+15551234567", "123456")
 			},
 			wantError: false,
 		},
@@ -164,7 +190,7 @@ func TestValidateOTP(t *testing.T) {
 			phone: "+15551234567",
 			code:  "wrong",
 			setupCache: func(m *mockCacheStore) {
-				m.store["otp:+15551234567"] = "123456"
+				m.store["otp:+15551234567"] = otpRecordFor(nil, "+15551234567", "123456")
 			},
 			wantError: true,
 		},
@@ -184,7 +210,8 @@ func TestValidateOTP(t *testing.T) {
 			tt.setupCache(cache)
 
 			auc := &AuthUsecase{
-				cache: cache,
+				cache:  cache,
+				policy: testOTPPolicy,
 			}
 
 			err := auc.validateOTP(ctx, tt.phone, tt.code)
@@ -202,6 +229,22 @@ func TestValidateOTP(t *testing.T) {
 	}
 }
 
+func TestVerifyOTPSurfacesBrokenRecords(t *testing.T) {
+	ctx := context.Background()
+	auc, _ := newRefreshTestUsecase()
+	auc.cache.(*mockCacheStore).store[otpKey("+15551234567")] = "{not json"
+
+	expired := metrics.OTPVerifications.WithLabelValues(metrics.OTPExpired)
+	before := testutil.ToFloat64(expired)
+	_, _, err := auc.VerifyOTPAndIssueToken(ctx, "+15551234567", "123456", "")
+	if err == nil || errors.Is(err, ErrInvalidOTP) {
+		t.Errorf("VerifyOTPAndIssueToken() error = %v, want an internal error", err)
+	}
+	if n := testutil.ToFloat64(expired) - before; n != 0 {
+		t.Errorf("otp_verifications_total{result=\"expired\"} grew by %v, want 0", n)
+	}
+}
+
 func TestGetOrCreateUser(t *testing.T) {
 	ctx := context.Background()
 	
@@ -282,7 +325,9 @@ func TestGetOrCreateUser(t *testing.T) {
 }
 
 type mockUserRepositoryWithStorage struct {
-	users map[string]*userdomain.User
+	users   map[string]*userdomain.User
+	deleted map[string]*userdomain.User
+	changes []userdomain.PhoneChange
 }
 
 func (m *mockUserRepositoryWithStorage) GetByPhone(ctx context.Context, phone string) (*userdomain.User, error) {
@@ -312,10 +357,84 @@ func (m *mockUserRepositoryWithStorage) GetByID(ctx context.Context, id string)
 	return nil, pgx.ErrNoRows
 }
 
-func (m *mockUserRepositoryWithStorage) List(ctx context.Context, search string, limit, offset int) ([]userdomain.User, int, error) {
+func (m *mockUserRepositoryWithStorage) List(ctx context.Context, f userdomain.ListFilter) ([]userdomain.User, error) {
 	users := make([]userdomain.User, 0)
 	for _, user := range m.users {
 		users = append(users, *user)
 	}
-	return users, len(users), nil
+	return users, nil
+}
+
+func (m *mockUserRepositoryWithStorage) Count(ctx context.Context, f userdomain.ListFilter, exact bool) (int, error) {
+	return len(m.users), nil
+}
+
+func (m *mockUserRepositoryWithStorage) Stream(ctx context.Context, f userdomain.ListFilter, fn func(userdomain.User) error) error {
+	for _, user := range m.users {
+		if err := fn(*user); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockUserRepositoryWithStorage) UpdateProfile(ctx context.Context, id string, p userdomain.Profile, version int64) (*userdomain.User, error) {
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserRepositoryWithStorage) ChangePhone(ctx context.Context, c userdomain.PhoneChange) (*userdomain.User, error) {
+	user, exists := m.users[c.OldPhone]
+	if !exists || user.ID != c.UserID {
+		return nil, pgx.ErrNoRows
+	}
+	if _, taken := m.users[c.NewPhone]; taken {
+		return nil, userdomain.ErrPhoneTaken
+	}
+	delete(m.users, c.OldPhone)
+	user.Phone = c.NewPhone
+	m.users[c.NewPhone] = user
+	m.changes = append(m.changes, c)
+	return user, nil
+}
+
+func (m *mockUserRepositoryWithStorage) SoftDelete(ctx context.Context, id string) (time.Time, error) {
+	for phone, user := range m.users {
+		if user.ID == id {
+			if m.deleted == nil {
+				m.deleted = make(map[string]*userdomain.User)
+			}
+			delete(m.users, phone)
+			m.deleted[phone] = user
+			return time.Now(), nil
+		}
+	}
+	return time.Time{}, pgx.ErrNoRows
+}
+
+func (m *mockUserRepositoryWithStorage) Restore(ctx context.Context, phone string) (*userdomain.User, error) {
+	user, exists := m.deleted[phone]
+	if !exists {
+		return nil, pgx.ErrNoRows
+	}
+	delete(m.deleted, phone)
+	m.users[phone] = user
+	return user, nil
+}
+
+func (m *mockUserRepositoryWithStorage) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	return 0, nil
+}
+
+func (m *mockUserRepositoryWithStorage) Export(ctx context.Context, id string) (*userdomain.Export, error) {
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserRepositoryWithStorage) SetStatus(ctx context.Context, id string, c userdomain.StatusChange) (*userdomain.User, error) {
+	for _, user := range m.users {
+		if user.ID == id {
+			user.Restriction = c.Restriction
+			return user, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
 }
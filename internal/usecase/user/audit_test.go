@@ -0,0 +1,85 @@
+package user
+
+import (
+	"context"
+	"testing"
+
+	auditdomain "dekamond/internal/domain/audit"
+	authdomain "dekamond/internal/domain/auth"
+	userdomain "dekamond/internal/domain/user"
+)
+
+type mockRecorder struct {
+	events []auditdomain.Event
+}
+
+func (m *mockRecorder) Record(ctx context.Context, e auditdomain.Event) {
+	m.events = append(m.events, e)
+}
+
+func TestGetByIDAuditsReadsOfOthers(t *testing.T) {
+	repo := newMockUserRepository()
+	repo.users["user-1"] = &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+
+	tests := []struct {
+		name       string
+		principal  *authdomain.Principal
+		id         string
+		wantEvent  bool
+		wantResult auditdomain.Outcome
+	}{
+		{name: "own account", principal: &authdomain.Principal{UserID: "user-1"}, id: "user-1"},
+		{name: "no caller", id: "user-1"},
+		{name: "admin read", principal: &authdomain.Principal{UserID: "admin-1"}, id: "user-1", wantEvent: true, wantResult: auditdomain.OutcomeSuccess},
+		{name: "admin read of missing user", principal: &authdomain.Principal{UserID: "admin-1"}, id: "user-2", wantEvent: true, wantResult: auditdomain.OutcomeFailure},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &mockRecorder{}
+			uc := New(repo, 0, "test-secret", "audit-key", recorder)
+			ctx := context.Background()
+			if tt.principal != nil {
+				ctx = authdomain.WithPrincipal(ctx, tt.principal)
+			}
+			_, _ = uc.GetByID(ctx, tt.id)
+
+			if !tt.wantEvent {
+				if len(recorder.events) != 0 {
+					t.Fatalf("recorded %+v, want nothing", recorder.events)
+				}
+				return
+			}
+			if len(recorder.events) != 1 {
+				t.Fatalf("recorded %d events, want 1", len(recorder.events))
+			}
+			e := recorder.events[0]
+			if e.Action != auditdomain.ActionUserViewed || e.Target != tt.id || e.Outcome != tt.wantResult {
+				t.Errorf("event = %+v", e)
+			}
+		})
+	}
+}
+
+func TestListIsAudited(t *testing.T) {
+	recorder := &mockRecorder{}
+	uc := New(newMockUserRepository(), 0, "test-secret", "audit-key", recorder)
+
+	_, _ = uc.List(context.Background(), ListQuery{PhonePrefix: "+98912", Status: "banned"})
+	_, _ = uc.List(context.Background(), ListQuery{Status: "deleted"})
+
+	if len(recorder.events) != 2 {
+		t.Fatalf("recorded %d events, want 2", len(recorder.events))
+	}
+	ok, failed := recorder.events[0], recorder.events[1]
+	if ok.Action != auditdomain.ActionUsersListed || ok.Outcome != auditdomain.OutcomeSuccess ||
+		ok.Details["phone_prefix"] != auditdomain.PhoneTarget([]byte("audit-key"), "+98912") || ok.Details["status"] != "banned" {
+		t.Errorf("event = %+v", ok)
+	}
+	if _, set := ok.Details["role"]; set {
+		t.Errorf("unset filter recorded: %+v", ok.Details)
+	}
+	if failed.Outcome != auditdomain.OutcomeFailure || failed.Details["error"] != "invalid_status" {
+		t.Errorf("event = %+v", failed)
+	}
+}
@@ -0,0 +1,68 @@
+package user
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+)
+
+// cursor is the payload of an opaque List cursor. It records the order it was
+// issued for, so it can't be replayed against a different sort.
+type cursor struct {
+	Sort  userdomain.SortField `json:"s"`
+	Desc  bool                 `json:"d,omitempty"`
+	Value string               `json:"v"`
+	ID    string               `json:"i"`
+}
+
+// encodeCursor returns base64(payload) + "." + base64(HMAC-SHA256(payload)).
+func (uuc *UserUsecase) encodeCursor(f userdomain.ListFilter, u userdomain.User) string {
+	payload, _ := json.Marshal(cursor{Sort: f.Sort, Desc: f.Desc, Value: sortValue(u, f.Sort), ID: u.ID})
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(uuc.cursorMAC(payload))
+}
+
+func (uuc *UserUsecase) decodeCursor(s string, f userdomain.ListFilter) (*userdomain.Keyset, error) {
+	enc := base64.RawURLEncoding
+	p, m, ok := strings.Cut(s, ".")
+	if !ok {
+		return nil, &FieldError{Field: "cursor"}
+	}
+	payload, err := enc.DecodeString(p)
+	if err != nil {
+		return nil, &FieldError{Field: "cursor"}
+	}
+	mac, err := enc.DecodeString(m)
+	if err != nil || !hmac.Equal(mac, uuc.cursorMAC(payload)) {
+		return nil, &FieldError{Field: "cursor"}
+	}
+	var c cursor
+	if err := json.Unmarshal(payload, &c); err != nil || c.Sort != f.Sort || c.Desc != f.Desc {
+		return nil, &FieldError{Field: "cursor"}
+	}
+	return &userdomain.Keyset{Value: c.Value, ID: c.ID}, nil
+}
+
+func (uuc *UserUsecase) cursorMAC(payload []byte) []byte {
+	h := hmac.New(sha256.New, uuc.cursorSecret)
+	h.Write(payload)
+	return h.Sum(nil)
+}
+
+func sortValue(u userdomain.User, sort userdomain.SortField) string {
+	switch sort {
+	case userdomain.SortUpdatedAt:
+		return u.UpdatedAt.Format(time.RFC3339Nano)
+	case userdomain.SortPhone:
+		return u.Phone
+	case userdomain.SortDisplayName:
+		return u.DisplayName
+	default:
+		return u.CreatedAt.Format(time.RFC3339Nano)
+	}
+}
@@ -0,0 +1,32 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// Export returns everything stored about the user.
+func (uuc *UserUsecase) Export(ctx context.Context, id string) (*userdomain.Export, error) {
+	if id == "" {
+		return nil, ErrInvalidID
+	}
+	export, err := uuc.users.Export(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to export user: %w", err)
+	}
+	return export, nil
+}
+
+// PurgeDeleted hard-deletes users whose grace period has run out.
+func (uuc *UserUsecase) PurgeDeleted(ctx context.Context) (int64, error) {
+	return uuc.users.PurgeDeleted(ctx, time.Now().Add(-uuc.deletionGrace))
+}
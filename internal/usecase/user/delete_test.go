@@ -0,0 +1,52 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+)
+
+func TestExport(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockUserRepository()
+	repo.users["+15551234567"] = &userdomain.User{ID: "user-1", Phone: "+15551234567"}
+	uc := &UserUsecase{users: repo}
+
+	tests := []struct {
+		name    string
+		id      string
+		wantErr error
+	}{
+		{name: "existing user", id: "user-1"},
+		{name: "unknown user", id: "user-2", wantErr: ErrNotFound},
+		{name: "empty ID", id: "", wantErr: ErrInvalidID},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			export, err := uc.Export(ctx, tt.id)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Export() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && export.User.ID != tt.id {
+				t.Errorf("Export() user = %s, want %s", export.User.ID, tt.id)
+			}
+		})
+	}
+}
+
+func TestPurgeDeletedUsesGracePeriod(t *testing.T) {
+	repo := newMockUserRepository()
+	uc := New(repo, 48*time.Hour, "test-secret", "audit-key", nil)
+
+	if _, err := uc.PurgeDeleted(context.Background()); err != nil {
+		t.Fatalf("PurgeDeleted() unexpected error: %v", err)
+	}
+	want := time.Now().Add(-48 * time.Hour)
+	if d := repo.purgedBefore.Sub(want); d < -time.Minute || d > time.Minute {
+		t.Errorf("PurgeDeleted() cutoff = %v, want about %v", repo.purgedBefore, want)
+	}
+}
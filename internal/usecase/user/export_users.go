@@ -0,0 +1,31 @@
+package user
+
+import (
+	"context"
+	"strconv"
+
+	auditdomain "dekamond/internal/domain/audit"
+	userdomain "dekamond/internal/domain/user"
+)
+
+// StreamUsers calls fn for every user matching q's filters, in q's sort order.
+// Paging, cursors and Count are ignored.
+func (uuc *UserUsecase) StreamUsers(ctx context.Context, q ListQuery, fn func(userdomain.User) error) error {
+	rows := 0
+	err := uuc.streamUsers(ctx, q, func(u userdomain.User) error {
+		rows++
+		return fn(u)
+	})
+	details := uuc.auditDetails(q)
+	details["rows"] = strconv.Itoa(rows)
+	uuc.record(ctx, auditdomain.Event{Action: auditdomain.ActionUsersExported, Details: details}, err)
+	return err
+}
+
+func (uuc *UserUsecase) streamUsers(ctx context.Context, q ListQuery, fn func(userdomain.User) error) error {
+	f, err := q.filter()
+	if err != nil {
+		return err
+	}
+	return uuc.users.Stream(ctx, f, fn)
+}
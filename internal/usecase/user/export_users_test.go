@@ -0,0 +1,85 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+)
+
+func TestStreamUsers(t *testing.T) {
+	ctx := context.Background()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	errStop := errors.New("client went away")
+
+	tests := []struct {
+		name      string
+		query     ListQuery
+		stopAfter int
+		wantIDs   []string
+		wantErr   error
+		wantField string
+	}{
+		{
+			name:    "all users oldest first, ignoring paging",
+			query:   ListQuery{Sort: "created_at", Page: 2, Limit: 1},
+			wantIDs: []string{"u1", "u2", "u3"},
+		},
+		{
+			name:    "exact phone filter",
+			query:   ListQuery{Phone: "+15550000002"},
+			wantIDs: []string{"u2"},
+		},
+		{
+			name:      "stops at callback error",
+			query:     ListQuery{},
+			stopAfter: 1,
+			wantIDs:   []string{"u3"},
+			wantErr:   errStop,
+		},
+		{
+			name:      "invalid filter",
+			query:     ListQuery{Status: "deleted"},
+			wantField: "status",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockUserRepository()
+			for i, id := range []string{"u1", "u2", "u3"} {
+				repo.users[id] = &userdomain.User{ID: id, Phone: "+1555000000" + id[1:], CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+			}
+			uc := New(repo, 0, "test-secret", "audit-key", nil)
+
+			var ids []string
+			err := uc.StreamUsers(ctx, tt.query, func(u userdomain.User) error {
+				ids = append(ids, u.ID)
+				if tt.stopAfter > 0 && len(ids) == tt.stopAfter {
+					return errStop
+				}
+				return nil
+			})
+
+			if tt.wantField != "" {
+				var fe *FieldError
+				if !errors.As(err, &fe) || fe.Field != tt.wantField {
+					t.Fatalf("StreamUsers() error = %v, want invalid_%s", err, tt.wantField)
+				}
+				if len(ids) != 0 {
+					t.Errorf("StreamUsers() streamed %v before rejecting the query", ids)
+				}
+				return
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("StreamUsers() error = %v, want %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(ids, tt.wantIDs) {
+				t.Errorf("StreamUsers() ids = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
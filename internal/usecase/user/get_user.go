@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 
+	auditdomain "dekamond/internal/domain/audit"
+	authdomain "dekamond/internal/domain/auth"
 	userdomain "dekamond/internal/domain/user"
 )
 
@@ -12,7 +14,16 @@ var (
 	ErrInvalidID = errors.New("invalid user id")
 )
 
+// GetByID loads a user. Reads of someone else's account are audited.
 func (uuc *UserUsecase) GetByID(ctx context.Context, id string) (*userdomain.User, error) {
+	usr, err := uuc.getByID(ctx, id)
+	if p, ok := authdomain.PrincipalFromContext(ctx); ok && p.UserID != id {
+		uuc.record(ctx, auditdomain.Event{Action: auditdomain.ActionUserViewed, Target: id}, err)
+	}
+	return usr, err
+}
+
+func (uuc *UserUsecase) getByID(ctx context.Context, id string) (*userdomain.User, error) {
 	if id == "" {
 		return nil, ErrInvalidID
 	}
@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"sort"
 	"testing"
 	"time"
 
@@ -11,7 +12,10 @@ import (
 )
 
 type mockUserRepository struct {
-	users map[string]*userdomain.User
+	users        map[string]*userdomain.User
+	purgedBefore time.Time
+	lastFilter   userdomain.ListFilter
+	countedExact bool
 }
 
 func newMockUserRepository() *mockUserRepository {
@@ -48,25 +52,122 @@ func (m *mockUserRepository) GetByID(ctx context.Context, id string) (*userdomai
 	return nil, pgx.ErrNoRows
 }
 
-func (m *mockUserRepository) List(ctx context.Context, phone string, limit, offset int) ([]userdomain.User, int, error) {
+// List only supports ordering by created_at.
+func (m *mockUserRepository) List(ctx context.Context, f userdomain.ListFilter) ([]userdomain.User, error) {
+	m.lastFilter = f
+	users := m.matching(f)
+	sort.Slice(users, func(i, j int) bool {
+		return keysetLess(users[i], users[j].CreatedAt, users[j].ID) != f.Desc
+	})
+	later := func(u userdomain.User, k *userdomain.Keyset) bool {
+		at, _ := time.Parse(time.RFC3339Nano, k.Value)
+		if u.CreatedAt.Equal(at) && u.ID == k.ID {
+			return false
+		}
+		return keysetLess(u, at, k.ID) == f.Desc
+	}
+	kept := users[:0]
+	for _, u := range users {
+		if f.After != nil && !later(u, f.After) {
+			continue
+		}
+		if f.Before != nil && (later(u, f.Before) || u.ID == f.Before.ID) {
+			continue
+		}
+		kept = append(kept, u)
+	}
+	users = kept
+
+	if f.Before != nil {
+		if len(users) > f.Limit {
+			users = users[len(users)-f.Limit:]
+		}
+		return users, nil
+	}
+	start := min(f.Offset, len(users))
+	end := min(f.Offset+f.Limit, len(users))
+	return users[start:end], nil
+}
+
+func (m *mockUserRepository) Count(ctx context.Context, f userdomain.ListFilter, exact bool) (int, error) {
+	m.countedExact = exact
+	return len(m.matching(f)), nil
+}
+
+func (m *mockUserRepository) Stream(ctx context.Context, f userdomain.ListFilter, fn func(userdomain.User) error) error {
+	f.After, f.Before, f.Limit, f.Offset = nil, nil, len(m.users), 0
+	users, _ := m.List(ctx, f)
+	for _, u := range users {
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *mockUserRepository) matching(f userdomain.ListFilter) []userdomain.User {
 	users := make([]userdomain.User, 0)
 	for _, user := range m.users {
-		if phone == "" || user.Phone == phone {
+		if f.Phone == "" || user.Phone == f.Phone {
 			users = append(users, *user)
 		}
 	}
-	
-	start := offset
-	end := offset + limit
-	if start > len(users) {
-		start = len(users)
+	return users
+}
+
+func keysetLess(u userdomain.User, at time.Time, id string) bool {
+	if !u.CreatedAt.Equal(at) {
+		return u.CreatedAt.Before(at)
 	}
-	if end > len(users) {
-		end = len(users)
+	return u.ID < id
+}
+
+func (m *mockUserRepository) UpdateProfile(ctx context.Context, id string, p userdomain.Profile, version int64) (*userdomain.User, error) {
+	for key, user := range m.users {
+		if user.ID != id {
+			continue
+		}
+		if user.Version != version {
+			return nil, userdomain.ErrVersionConflict
+		}
+		updated := *user
+		updated.Profile = p
+		updated.Version++
+		updated.UpdatedAt = time.Now()
+		m.users[key] = &updated
+		return &updated, nil
 	}
-	
-	paginatedUsers := users[start:end]
-	return paginatedUsers, len(users), nil
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserRepository) ChangePhone(ctx context.Context, c userdomain.PhoneChange) (*userdomain.User, error) {
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserRepository) SoftDelete(ctx context.Context, id string) (time.Time, error) {
+	return time.Time{}, pgx.ErrNoRows
+}
+
+func (m *mockUserRepository) Restore(ctx context.Context, phone string) (*userdomain.User, error) {
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	m.purgedBefore = before
+	return 0, nil
+}
+
+func (m *mockUserRepository) Export(ctx context.Context, id string) (*userdomain.Export, error) {
+	for _, user := range m.users {
+		if user.ID == id {
+			return &userdomain.Export{ExportedAt: time.Now(), User: *user}, nil
+		}
+	}
+	return nil, pgx.ErrNoRows
+}
+
+func (m *mockUserRepository) SetStatus(ctx context.Context, id string, c userdomain.StatusChange) (*userdomain.User, error) {
+	return nil, pgx.ErrNoRows
 }
 
 func TestGetByID(t *testing.T) {
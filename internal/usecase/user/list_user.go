@@ -3,42 +3,225 @@ package user
 import (
 	"context"
 	"strings"
+	"time"
 
+	auditdomain "dekamond/internal/domain/audit"
+	authdomain "dekamond/internal/domain/auth"
 	userdomain "dekamond/internal/domain/user"
 )
 
+// ListQuery filters and orders the user listing. Sort names a column, with a
+// leading "-" for descending order; it defaults to "-created_at".
+//
+// After and Before take a cursor from a previous Page and replace Page-based
+// offsets. Count is "exact", "estimate" or "none"; it defaults to "exact" for
+// offset pages and "none" for cursor pages.
 type ListQuery struct {
-	Phone string
-	Page  int
-	Limit int
+	Phone         string
+	PhonePrefix   string
+	PhoneContains string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Status        string
+	Role          string
+	Sort          string
+	After         string
+	Before        string
+	Count         string
+	Page          int
+	Limit         int
 }
 
+// Page is one page of a listing. Total is nil when counting was skipped and
+// TotalEstimated marks a planner estimate. Page is only set for offset pages.
 type Page[T any] struct {
-	Items []T `json:"items"`
-	Total int `json:"total"`
-	Page  int `json:"page"`
-	Limit int `json:"limit"`
+	Items          []T    `json:"items"`
+	Total          *int   `json:"total,omitempty"`
+	TotalEstimated bool   `json:"total_estimated,omitempty"`
+	Page           int    `json:"page,omitempty"`
+	Limit          int    `json:"limit"`
+	NextCursor     string `json:"next_cursor,omitempty"`
+	PrevCursor     string `json:"prev_cursor,omitempty"`
 }
 
+const (
+	CountExact    = "exact"
+	CountEstimate = "estimate"
+	CountNone     = "none"
+)
+
+// minPhoneContains keeps substring searches selective enough for the trigram
+// index to help.
+const minPhoneContains = 3
+
 func (uuc *UserUsecase) List(ctx context.Context, q ListQuery) (Page[userdomain.User], error) {
-	q.Phone = strings.TrimSpace(q.Phone)
+	page, err := uuc.list(ctx, q)
+	uuc.record(ctx, auditdomain.Event{Action: auditdomain.ActionUsersListed, Details: uuc.auditDetails(q)}, err)
+	return page, err
+}
 
-	if q.Page < 1 {
-		q.Page = 1
+func (uuc *UserUsecase) list(ctx context.Context, q ListQuery) (Page[userdomain.User], error) {
+	f, err := q.filter()
+	if err != nil {
+		return Page[userdomain.User]{}, err
 	}
 	if q.Limit < 1 || q.Limit > 100 {
 		q.Limit = 20
 	}
-	offset := (q.Page - 1) * q.Limit
 
-	items, total, err := uuc.users.List(ctx, q.Phone, q.Limit, offset)
+	count := CountExact
+	switch {
+	case q.After != "" && q.Before != "":
+		return Page[userdomain.User]{}, &FieldError{Field: "cursor"}
+	case q.After != "":
+		f.After, err = uuc.decodeCursor(q.After, f)
+		count, q.Page = CountNone, 0
+	case q.Before != "":
+		f.Before, err = uuc.decodeCursor(q.Before, f)
+		count, q.Page = CountNone, 0
+	default:
+		if q.Page < 1 {
+			q.Page = 1
+		}
+		f.Offset = (q.Page - 1) * q.Limit
+	}
+	if err != nil {
+		return Page[userdomain.User]{}, err
+	}
+	switch q.Count {
+	case "":
+	case CountExact, CountEstimate, CountNone:
+		count = q.Count
+	default:
+		return Page[userdomain.User]{}, &FieldError{Field: "count"}
+	}
+
+	// One extra row tells whether there is another page in the direction
+	// being read.
+	f.Limit = q.Limit + 1
+	items, err := uuc.users.List(ctx, f)
 	if err != nil {
 		return Page[userdomain.User]{}, err
 	}
-	return Page[userdomain.User]{
-		Items: items,
-		Total: total,
-		Page:  q.Page,
-		Limit: q.Limit,
-	}, nil
+	more := len(items) > q.Limit
+	if more && f.Before != nil {
+		items = items[1:]
+	} else if more {
+		items = items[:q.Limit]
+	}
+
+	page := Page[userdomain.User]{Items: items, Page: q.Page, Limit: q.Limit}
+	if len(items) > 0 {
+		if more || f.Before != nil {
+			page.NextCursor = uuc.encodeCursor(f, items[len(items)-1])
+		}
+		if (more && f.Before != nil) || f.After != nil || q.Page > 1 {
+			page.PrevCursor = uuc.encodeCursor(f, items[0])
+		}
+	}
+
+	if count != CountNone {
+		total, err := uuc.users.Count(ctx, f, count == CountExact)
+		if err != nil {
+			return Page[userdomain.User]{}, err
+		}
+		page.Total = &total
+		page.TotalEstimated = count == CountEstimate
+	}
+	return page, nil
+}
+
+// auditDetails lists the filters that were set, so the audit log shows what
+// was searched for. Phone filters are stored as PhoneTarget hashes, like
+// every other number in the log.
+func (uuc *UserUsecase) auditDetails(q ListQuery) map[string]string {
+	details := map[string]string{}
+	for k, v := range map[string]string{
+		"phone":          q.Phone,
+		"phone_prefix":   q.PhonePrefix,
+		"phone_contains": q.PhoneContains,
+	} {
+		if v != "" {
+			details[k] = auditdomain.PhoneTarget(uuc.auditPhoneKey, v)
+		}
+	}
+	for k, v := range map[string]string{
+		"status": q.Status,
+		"role":   q.Role,
+	} {
+		if v != "" {
+			details[k] = v
+		}
+	}
+	if q.CreatedAfter != nil {
+		details["created_after"] = q.CreatedAfter.UTC().Format(time.RFC3339)
+	}
+	if q.CreatedBefore != nil {
+		details["created_before"] = q.CreatedBefore.UTC().Format(time.RFC3339)
+	}
+	return details
+}
+
+func (q ListQuery) filter() (userdomain.ListFilter, error) {
+	f := userdomain.ListFilter{
+		Phone:         strings.TrimSpace(q.Phone),
+		CreatedAfter:  q.CreatedAfter,
+		CreatedBefore: q.CreatedBefore,
+		Sort:          userdomain.SortCreatedAt,
+		Desc:          true,
+	}
+
+	if p := strings.TrimSpace(q.PhonePrefix); p != "" {
+		digits := strings.TrimPrefix(p, "+")
+		if !isDigits(digits) || len(digits) > 15 {
+			return f, &FieldError{Field: "phone_prefix"}
+		}
+		f.PhonePrefix = "+" + digits
+	}
+	if c := strings.TrimSpace(q.PhoneContains); c != "" {
+		if !isDigits(c) || len(c) < minPhoneContains || len(c) > 15 {
+			return f, &FieldError{Field: "phone_contains"}
+		}
+		f.PhoneContains = c
+	}
+	if f.CreatedAfter != nil && f.CreatedBefore != nil && !f.CreatedAfter.Before(*f.CreatedBefore) {
+		return f, &FieldError{Field: "created_range"}
+	}
+
+	switch s := userdomain.Status(q.Status); s {
+	case "", userdomain.StatusActive, userdomain.StatusSuspended, userdomain.StatusBanned:
+		f.Status = s
+	default:
+		return f, &FieldError{Field: "status"}
+	}
+
+	if q.Role != "" {
+		if !authdomain.IsRole(q.Role) {
+			return f, &FieldError{Field: "role"}
+		}
+		f.Role = q.Role
+	}
+
+	if q.Sort != "" {
+		field, desc := strings.CutPrefix(q.Sort, "-")
+		switch s := userdomain.SortField(field); s {
+		case userdomain.SortCreatedAt, userdomain.SortUpdatedAt, userdomain.SortPhone, userdomain.SortDisplayName:
+			f.Sort, f.Desc = s, desc
+		default:
+			return f, &FieldError{Field: "sort"}
+		}
+	}
+	return f, nil
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
 }
@@ -2,6 +2,10 @@ package user
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
@@ -44,7 +48,7 @@ func TestList(t *testing.T) {
 					{ID: "user-1", Phone: "+15551234567"},
 					{ID: "user-2", Phone: "+15551234568"},
 				},
-				Total: 2,
+				Total: intPtr(2),
 				Page:  1,
 				Limit: 10,
 			},
@@ -75,7 +79,7 @@ func TestList(t *testing.T) {
 				Items: []userdomain.User{
 					{ID: "user-1", Phone: "+15551234567"},
 				},
-				Total: 1,
+				Total: intPtr(1),
 				Page:  1,
 				Limit: 10,
 			},
@@ -106,7 +110,7 @@ func TestList(t *testing.T) {
 				Items: []userdomain.User{
 					{ID: "user-2", Phone: "+15551234568"},
 				},
-				Total: 2,
+				Total: intPtr(2),
 				Page:  2,
 				Limit:  1,
 			},
@@ -132,7 +136,7 @@ func TestList(t *testing.T) {
 				Items: []userdomain.User{
 					{ID: "user-1", Phone: "+15551234567"},
 				},
-				Total: 1,
+				Total: intPtr(1),
 				Page:  1,
 				Limit: 20,
 			},
@@ -158,7 +162,7 @@ func TestList(t *testing.T) {
 				Items: []userdomain.User{
 					{ID: "user-1", Phone: "+15551234567"},
 				},
-				Total: 1,
+				Total: intPtr(1),
 				Page:  1,
 				Limit: 20,
 			},
@@ -171,7 +175,7 @@ func TestList(t *testing.T) {
 			repo := newMockUserRepository()
 			tt.setupRepo(repo)
 
-			uc := &UserUsecase{users: repo}
+			uc := New(repo, 0, "test-secret", "audit-key", nil)
 			page, err := uc.List(ctx, tt.query)
 
 			if tt.wantErr {
@@ -194,8 +198,8 @@ func TestList(t *testing.T) {
 				t.Errorf("List() limit = %d, want %d", page.Limit, tt.wantPage.Limit)
 			}
 
-			if page.Total != tt.wantPage.Total {
-				t.Errorf("List() total = %d, want %d", page.Total, tt.wantPage.Total)
+			if page.Total == nil || *page.Total != *tt.wantPage.Total {
+				t.Errorf("List() total = %v, want %d", page.Total, *tt.wantPage.Total)
 			}
 
 			if len(page.Items) != len(tt.wantPage.Items) {
@@ -204,3 +208,220 @@ func TestList(t *testing.T) {
 		})
 	}
 }
+
+func TestListFilter(t *testing.T) {
+	ctx := context.Background()
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := after.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name      string
+		query     ListQuery
+		want      userdomain.ListFilter
+		wantField string
+	}{
+		{
+			name:  "defaults to newest first",
+			query: ListQuery{},
+			want:  userdomain.ListFilter{Sort: userdomain.SortCreatedAt, Desc: true, Limit: 21},
+		},
+		{
+			name: "all filters",
+			query: ListQuery{
+				PhonePrefix:   "98912",
+				PhoneContains: "1234",
+				CreatedAfter:  &after,
+				CreatedBefore: &before,
+				Status:        "suspended",
+				Role:          "admin",
+				Sort:          "phone",
+				Page:          3,
+				Limit:         10,
+			},
+			want: userdomain.ListFilter{
+				PhonePrefix:   "+98912",
+				PhoneContains: "1234",
+				CreatedAfter:  &after,
+				CreatedBefore: &before,
+				Status:        userdomain.StatusSuspended,
+				Role:          "admin",
+				Sort:          userdomain.SortPhone,
+				Limit:         11,
+				Offset:        20,
+			},
+		},
+		{
+			name:  "descending sort",
+			query: ListQuery{Sort: "-display_name"},
+			want:  userdomain.ListFilter{Sort: userdomain.SortDisplayName, Desc: true, Limit: 21},
+		},
+		{
+			name:      "unknown sort field",
+			query:     ListQuery{Sort: "password"},
+			wantField: "sort",
+		},
+		{
+			name:      "unknown status",
+			query:     ListQuery{Status: "deleted"},
+			wantField: "status",
+		},
+		{
+			name:      "unknown role",
+			query:     ListQuery{Role: "root"},
+			wantField: "role",
+		},
+		{
+			name:      "prefix with wildcard",
+			query:     ListQuery{PhonePrefix: "+98%"},
+			wantField: "phone_prefix",
+		},
+		{
+			name:      "substring too short",
+			query:     ListQuery{PhoneContains: "12"},
+			wantField: "phone_contains",
+		},
+		{
+			name:      "empty date range",
+			query:     ListQuery{CreatedAfter: &before, CreatedBefore: &after},
+			wantField: "created_range",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newMockUserRepository()
+			uc := &UserUsecase{users: repo}
+			_, err := uc.List(ctx, tt.query)
+
+			if tt.wantField != "" {
+				var fe *FieldError
+				if !errors.As(err, &fe) || fe.Field != tt.wantField {
+					t.Fatalf("List() error = %v, want invalid_%s", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("List() unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(repo.lastFilter, tt.want) {
+				t.Errorf("List() filter = %+v, want %+v", repo.lastFilter, tt.want)
+			}
+		})
+	}
+}
+
+func TestListCursor(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockUserRepository()
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, id := range []string{"u1", "u2", "u3", "u4", "u5"} {
+		repo.users[id] = &userdomain.User{ID: id, Phone: "+1555000000" + id[1:], CreatedAt: base.Add(time.Duration(i) * time.Hour)}
+	}
+	// Two users created at the same instant are ordered by ID.
+	repo.users["u6"] = &userdomain.User{ID: "u6", Phone: "+15550000006", CreatedAt: repo.users["u5"].CreatedAt}
+	uc := New(repo, 0, "test-secret", "audit-key", nil)
+
+	ids := func(p Page[userdomain.User]) []string {
+		out := make([]string, 0, len(p.Items))
+		for _, u := range p.Items {
+			out = append(out, u.ID)
+		}
+		return out
+	}
+
+	first, err := uc.List(ctx, ListQuery{Limit: 2})
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if got := ids(first); !reflect.DeepEqual(got, []string{"u6", "u5"}) {
+		t.Fatalf("first page = %v, want [u6 u5]", got)
+	}
+	if first.PrevCursor != "" || first.NextCursor == "" {
+		t.Fatalf("first page cursors = %q/%q, want only next", first.PrevCursor, first.NextCursor)
+	}
+
+	second, err := uc.List(ctx, ListQuery{Limit: 2, After: first.NextCursor})
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if got := ids(second); !reflect.DeepEqual(got, []string{"u4", "u3"}) {
+		t.Fatalf("second page = %v, want [u4 u3]", got)
+	}
+	if second.Total != nil || second.Page != 0 {
+		t.Errorf("cursor page total = %v, page = %d, want neither", second.Total, second.Page)
+	}
+
+	last, err := uc.List(ctx, ListQuery{Limit: 2, After: second.NextCursor, Count: CountEstimate})
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if got := ids(last); !reflect.DeepEqual(got, []string{"u2", "u1"}) {
+		t.Fatalf("last page = %v, want [u2 u1]", got)
+	}
+	if last.NextCursor != "" {
+		t.Errorf("last page next cursor = %q, want none", last.NextCursor)
+	}
+	if last.Total == nil || *last.Total != 6 || !last.TotalEstimated || repo.countedExact {
+		t.Errorf("last page total = %v (estimated %v), want estimate of 6", last.Total, last.TotalEstimated)
+	}
+
+	back, err := uc.List(ctx, ListQuery{Limit: 2, Before: last.PrevCursor})
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if got := ids(back); !reflect.DeepEqual(got, []string{"u4", "u3"}) {
+		t.Fatalf("page before last = %v, want [u4 u3]", got)
+	}
+	if back.PrevCursor == "" || back.NextCursor == "" {
+		t.Errorf("page before last cursors = %q/%q, want both", back.PrevCursor, back.NextCursor)
+	}
+
+	start, err := uc.List(ctx, ListQuery{Limit: 2, Before: back.PrevCursor})
+	if err != nil {
+		t.Fatalf("List() unexpected error: %v", err)
+	}
+	if got := ids(start); !reflect.DeepEqual(got, []string{"u6", "u5"}) || start.PrevCursor != "" {
+		t.Errorf("first page read backwards = %v (prev %q), want [u6 u5] and no prev", got, start.PrevCursor)
+	}
+}
+
+func TestListCursorRejected(t *testing.T) {
+	ctx := context.Background()
+	repo := newMockUserRepository()
+	repo.users["u1"] = &userdomain.User{ID: "u1", Phone: "+15550000001", CreatedAt: time.Now()}
+	repo.users["u2"] = &userdomain.User{ID: "u2", Phone: "+15550000002", CreatedAt: time.Now()}
+	uc := New(repo, 0, "test-secret", "audit-key", nil)
+
+	page, err := uc.List(ctx, ListQuery{Limit: 1})
+	if err != nil || page.NextCursor == "" {
+		t.Fatalf("List() = %+v, %v, want a next cursor", page, err)
+	}
+	payload, mac, _ := strings.Cut(page.NextCursor, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"s":"created_at","d":true,"v":"2000-01-01T00:00:00Z","i":"u1"}`))
+
+	tests := []struct {
+		name  string
+		query ListQuery
+	}{
+		{name: "garbage", query: ListQuery{After: "not-a-cursor"}},
+		{name: "forged payload", query: ListQuery{After: forged + "." + mac}},
+		{name: "other secret", query: ListQuery{After: New(repo, 0, "other-secret", "audit-key", nil).encodeCursor(repo.lastFilter, *repo.users["u1"])}},
+		{name: "different sort", query: ListQuery{After: page.NextCursor, Sort: "created_at"}},
+		{name: "both directions", query: ListQuery{After: page.NextCursor, Before: payload + "." + mac}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := uc.List(ctx, tt.query)
+			var fe *FieldError
+			if !errors.As(err, &fe) || fe.Field != "cursor" {
+				t.Errorf("List() error = %v, want invalid_cursor", err)
+			}
+		})
+	}
+
+	if _, err := uc.List(ctx, ListQuery{Count: "all"}); err == nil || err.Error() != "invalid_count" {
+		t.Errorf("List() error = %v, want invalid_count", err)
+	}
+}
+
+func intPtr(n int) *int { return &n }
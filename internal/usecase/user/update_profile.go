@@ -0,0 +1,149 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	userdomain "dekamond/internal/domain/user"
+
+	"github.com/jackc/pgx/v5"
+	"golang.org/x/text/language"
+)
+
+// ErrPreconditionFailed means the caller's If-Match version is stale.
+var ErrPreconditionFailed = errors.New("precondition_failed")
+
+// FieldError reports which input field failed validation.
+type FieldError struct {
+	Field string
+}
+
+func (e *FieldError) Error() string { return "invalid_" + e.Field }
+
+// ProfileUpdate is a partial update: nil leaves a field as it is and an
+// empty string clears it.
+type ProfileUpdate struct {
+	DisplayName *string
+	Email       *string
+	AvatarURL   *string
+	Locale      *string
+	Timezone    *string
+}
+
+const (
+	maxDisplayNameLen = 64
+	maxEmailLen       = 254
+	maxAvatarURLLen   = 2048
+)
+
+// UpdateProfile applies upd to the user's profile. A non-zero ifMatch must
+// equal the stored version; zero skips that check, and an update that then
+// loses a race returns userdomain.ErrVersionConflict.
+func (uuc *UserUsecase) UpdateProfile(ctx context.Context, id string, upd ProfileUpdate, ifMatch int64) (*userdomain.User, error) {
+	usr, err := uuc.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if ifMatch != 0 && ifMatch != usr.Version {
+		return nil, ErrPreconditionFailed
+	}
+
+	p := usr.Profile
+	fields := []struct {
+		name  string
+		in    *string
+		out   *string
+		clean func(string) (string, bool)
+	}{
+		{"display_name", upd.DisplayName, &p.DisplayName, cleanDisplayName},
+		{"email", upd.Email, &p.Email, cleanEmail},
+		{"avatar_url", upd.AvatarURL, &p.AvatarURL, cleanAvatarURL},
+		{"locale", upd.Locale, &p.Locale, cleanLocale},
+		{"timezone", upd.Timezone, &p.Timezone, cleanTimezone},
+	}
+	for _, f := range fields {
+		if f.in == nil {
+			continue
+		}
+		v := strings.TrimSpace(*f.in)
+		if v != "" {
+			var ok bool
+			if v, ok = f.clean(v); !ok {
+				return nil, &FieldError{Field: f.name}
+			}
+		}
+		*f.out = v
+	}
+
+	if p == usr.Profile {
+		return usr, nil
+	}
+	updated, err := uuc.users.UpdateProfile(ctx, id, p, usr.Version)
+	switch {
+	case errors.Is(err, userdomain.ErrVersionConflict) && ifMatch != 0:
+		return nil, ErrPreconditionFailed
+	case errors.Is(err, userdomain.ErrVersionConflict):
+		return nil, err
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, ErrNotFound
+	case err != nil:
+		return nil, fmt.Errorf("failed to update profile: %w", err)
+	}
+	return updated, nil
+}
+
+func cleanDisplayName(s string) (string, bool) {
+	if utf8.RuneCountInString(s) > maxDisplayNameLen {
+		return "", false
+	}
+	for _, r := range s {
+		if unicode.IsControl(r) {
+			return "", false
+		}
+	}
+	return s, true
+}
+
+func cleanEmail(s string) (string, bool) {
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s || len(s) > maxEmailLen {
+		return "", false
+	}
+	return strings.ToLower(s), true
+}
+
+func cleanAvatarURL(s string) (string, bool) {
+	u, err := url.Parse(s)
+	if err != nil || u.Scheme != "https" || u.Host == "" || u.User != nil || len(s) > maxAvatarURLLen {
+		return "", false
+	}
+	return u.String(), true
+}
+
+// cleanLocale accepts BCP 47 tags and returns them in canonical form, e.g.
+// "fa_ir" becomes "fa-IR".
+func cleanLocale(s string) (string, bool) {
+	tag, err := language.Parse(strings.ReplaceAll(s, "_", "-"))
+	if err != nil {
+		return "", false
+	}
+	return tag.String(), true
+}
+
+// cleanTimezone accepts IANA zone names such as "Asia/Tehran".
+func cleanTimezone(s string) (string, bool) {
+	if s == "Local" {
+		return "", false
+	}
+	if _, err := time.LoadLocation(s); err != nil {
+		return "", false
+	}
+	return s, true
+}
@@ -0,0 +1,170 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	userdomain "dekamond/internal/domain/user"
+)
+
+func strPtr(s string) *string { return &s }
+
+func newProfileRepo() *mockUserRepository {
+	repo := newMockUserRepository()
+	repo.users["user-1"] = &userdomain.User{
+		ID:        "user-1",
+		Phone:     "+989121234567",
+		CreatedAt: time.Now(),
+		Version:   3,
+		Profile:   userdomain.Profile{DisplayName: "Old", Email: "old@example.com"},
+	}
+	return repo
+}
+
+func TestUpdateProfileValidation(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name      string
+		upd       ProfileUpdate
+		wantField string
+		want      userdomain.Profile
+	}{
+		{
+			name: "all fields",
+			upd: ProfileUpdate{
+				DisplayName: strPtr("  Sara  "),
+				Email:       strPtr("Sara@Example.com"),
+				AvatarURL:   strPtr("https://cdn.example.com/a.png"),
+				Locale:      strPtr("fa_ir"),
+				Timezone:    strPtr("Asia/Tehran"),
+			},
+			want: userdomain.Profile{
+				DisplayName: "Sara",
+				Email:       "sara@example.com",
+				AvatarURL:   "https://cdn.example.com/a.png",
+				Locale:      "fa-IR",
+				Timezone:    "Asia/Tehran",
+			},
+		},
+		{
+			name: "clear fields",
+			upd:  ProfileUpdate{DisplayName: strPtr(""), Email: strPtr("")},
+			want: userdomain.Profile{},
+		},
+		{
+			name: "untouched fields kept",
+			upd:  ProfileUpdate{Timezone: strPtr("UTC")},
+			want: userdomain.Profile{DisplayName: "Old", Email: "old@example.com", Timezone: "UTC"},
+		},
+		{name: "display name too long", upd: ProfileUpdate{DisplayName: strPtr(strings.Repeat("a", 65))}, wantField: "display_name"},
+		{name: "display name control char", upd: ProfileUpdate{DisplayName: strPtr("a\x00b")}, wantField: "display_name"},
+		{name: "email with name", upd: ProfileUpdate{Email: strPtr("Sara <sara@example.com>")}, wantField: "email"},
+		{name: "email malformed", upd: ProfileUpdate{Email: strPtr("sara@")}, wantField: "email"},
+		{name: "avatar http", upd: ProfileUpdate{AvatarURL: strPtr("http://example.com/a.png")}, wantField: "avatar_url"},
+		{name: "avatar userinfo", upd: ProfileUpdate{AvatarURL: strPtr("https://u:p@example.com/a.png")}, wantField: "avatar_url"},
+		{name: "bad locale", upd: ProfileUpdate{Locale: strPtr("not a locale")}, wantField: "locale"},
+		{name: "bad timezone", upd: ProfileUpdate{Timezone: strPtr("Mars/Olympus")}, wantField: "timezone"},
+		{name: "local timezone", upd: ProfileUpdate{Timezone: strPtr("Local")}, wantField: "timezone"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UserUsecase{users: newProfileRepo()}
+			user, err := uc.UpdateProfile(ctx, "user-1", tt.upd, 0)
+
+			if tt.wantField != "" {
+				var fe *FieldError
+				if !errors.As(err, &fe) || fe.Field != tt.wantField {
+					t.Fatalf("UpdateProfile() error = %v, want invalid_%s", err, tt.wantField)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("UpdateProfile() unexpected error: %v", err)
+			}
+			if user.Profile != tt.want {
+				t.Errorf("UpdateProfile() profile = %+v, want %+v", user.Profile, tt.want)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileVersioning(t *testing.T) {
+	ctx := context.Background()
+	rename := ProfileUpdate{DisplayName: strPtr("New")}
+
+	tests := []struct {
+		name        string
+		id          string
+		upd         ProfileUpdate
+		ifMatch     int64
+		wantErr     error
+		wantVersion int64
+	}{
+		{name: "matching version", id: "user-1", upd: rename, ifMatch: 3, wantVersion: 4},
+		{name: "If-Match *", id: "user-1", upd: rename, wantVersion: 4},
+		{name: "stale version", id: "user-1", upd: rename, ifMatch: 2, wantErr: ErrPreconditionFailed},
+		{name: "no change keeps version", id: "user-1", upd: ProfileUpdate{DisplayName: strPtr("Old")}, ifMatch: 3, wantVersion: 3},
+		{name: "unknown user", id: "missing", upd: rename, wantErr: ErrNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc := &UserUsecase{users: newProfileRepo()}
+			user, err := uc.UpdateProfile(ctx, tt.id, tt.upd, tt.ifMatch)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UpdateProfile() error = %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && user.Version != tt.wantVersion {
+				t.Errorf("UpdateProfile() version = %d, want %d", user.Version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+// racingRepo bumps the stored version between the read and the write, as a
+// concurrent request would.
+type racingRepo struct {
+	*mockUserRepository
+}
+
+func (r racingRepo) UpdateProfile(ctx context.Context, id string, p userdomain.Profile, version int64) (*userdomain.User, error) {
+	r.users["user-1"].Version++
+	return r.mockUserRepository.UpdateProfile(ctx, id, p, version)
+}
+
+// failingRepo fails every write the way a database outage would.
+type failingRepo struct {
+	*mockUserRepository
+}
+
+func (r failingRepo) UpdateProfile(ctx context.Context, id string, p userdomain.Profile, version int64) (*userdomain.User, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestUpdateProfileRepositoryError(t *testing.T) {
+	uc := &UserUsecase{users: failingRepo{newProfileRepo()}}
+	_, err := uc.UpdateProfile(context.Background(), "user-1", ProfileUpdate{DisplayName: strPtr("New")}, 3)
+	if err == nil || errors.Is(err, ErrNotFound) {
+		t.Errorf("UpdateProfile() error = %v, want the repository error", err)
+	}
+}
+
+func TestUpdateProfileLostRace(t *testing.T) {
+	ctx := context.Background()
+	rename := ProfileUpdate{DisplayName: strPtr("New")}
+
+	uc := &UserUsecase{users: racingRepo{newProfileRepo()}}
+	if _, err := uc.UpdateProfile(ctx, "user-1", rename, 0); !errors.Is(err, userdomain.ErrVersionConflict) {
+		t.Errorf("UpdateProfile() with If-Match * error = %v, want %v", err, userdomain.ErrVersionConflict)
+	}
+
+	uc = &UserUsecase{users: racingRepo{newProfileRepo()}}
+	if _, err := uc.UpdateProfile(ctx, "user-1", rename, 3); !errors.Is(err, ErrPreconditionFailed) {
+		t.Errorf("UpdateProfile() with If-Match error = %v, want %v", err, ErrPreconditionFailed)
+	}
+}
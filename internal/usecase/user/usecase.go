@@ -1,13 +1,37 @@
 package user
 
 import (
+	"context"
+	"time"
+
+	auditdomain "dekamond/internal/domain/audit"
 	userdomain "dekamond/internal/domain/user"
 )
 
 type UserUsecase struct {
-	users userdomain.Repository
+	users         userdomain.Repository
+	deletionGrace time.Duration
+	cursorSecret  []byte
+	auditPhoneKey []byte
+	audit         auditdomain.Recorder
 }
 
-func New(users userdomain.Repository) *UserUsecase {
-	return &UserUsecase{users: users}
+// New takes how long deleted users are kept before PurgeDeleted removes them,
+// the key that signs List cursors and the key phone filters are hashed with
+// in the audit log. audit may be nil.
+func New(users userdomain.Repository, deletionGrace time.Duration, cursorSecret, auditPhoneKey string, audit auditdomain.Recorder) *UserUsecase {
+	return &UserUsecase{
+		users:         users,
+		deletionGrace: deletionGrace,
+		cursorSecret:  []byte(cursorSecret),
+		auditPhoneKey: []byte(auditPhoneKey),
+		audit:         audit,
+	}
 }
+
+// record audits e with the outcome given by err, if auditing is enabled.
+func (uuc *UserUsecase) record(ctx context.Context, e auditdomain.Event, err error) {
+	if uuc.audit != nil {
+		uuc.audit.Record(ctx, auditdomain.Finish(e, err))
+	}
+}
\ No newline at end of file
@@ -0,0 +1,148 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	webhookdomain "dekamond/internal/domain/webhook"
+	"dekamond/internal/logging"
+)
+
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// leaseSlack is added to the request timeout to get how long a claimed
+// delivery is hidden from other dispatchers.
+const leaseSlack = 30 * time.Second
+
+// DispatchOnce moves new outbox events into deliveries and sends the
+// deliveries that are due. It returns how many were delivered. Failed sends
+// are recorded on the delivery; only repository errors are returned.
+func (w *WebhookUsecase) DispatchOnce(ctx context.Context) (int, error) {
+	batch := max(w.conf.BatchSize, 1)
+	if _, err := w.webhooks.FanOut(ctx, batch); err != nil {
+		return 0, fmt.Errorf("failed to fan out events: %w", err)
+	}
+	due, err := w.webhooks.ClaimDue(ctx, batch, w.conf.Timeout+leaseSlack)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim deliveries: %w", err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		delivered int
+		firstErr  error
+	)
+	sem := make(chan struct{}, max(w.conf.Concurrency, 1))
+	for _, d := range due {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(d webhookdomain.Delivery) {
+			defer func() { <-sem; wg.Done() }()
+			ok, err := w.deliver(ctx, d)
+			mu.Lock()
+			defer mu.Unlock()
+			if ok {
+				delivered++
+			}
+			if err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}(d)
+	}
+	wg.Wait()
+	return delivered, firstErr
+}
+
+func (w *WebhookUsecase) deliver(ctx context.Context, d webhookdomain.Delivery) (bool, error) {
+	body, err := json.Marshal(d.Event)
+	if err != nil {
+		return false, err
+	}
+	req := webhookdomain.Request{
+		URL:  d.URL,
+		Body: body,
+		Headers: map[string]string{
+			HeaderEvent:     string(d.Event.Type),
+			HeaderDelivery:  strconv.FormatInt(d.ID, 10),
+			HeaderSignature: Signature(d.Secret, time.Now(), body),
+		},
+	}
+
+	sendCtx, cancel := context.WithTimeout(ctx, w.conf.Timeout)
+	status, err := w.sender.Send(sendCtx, req)
+	cancel()
+	if err == nil && status >= 200 && status < 300 {
+		return true, w.webhooks.MarkDelivered(ctx, d.ID, status)
+	}
+
+	reason := fmt.Sprintf("unexpected status %d", status)
+	if err != nil {
+		reason = failureReason(err)
+		logging.FromContext(ctx).Warn("webhook delivery failed",
+			"delivery_id", d.ID, "endpoint_id", d.EndpointID, "err", err)
+	}
+	var retryAt *time.Time
+	if attempt := d.Attempts + 1; attempt < w.conf.MaxAttempts {
+		at := time.Now().Add(w.backoff(attempt))
+		retryAt = &at
+	}
+	return false, w.webhooks.MarkFailed(ctx, d.ID, status, reason, retryAt)
+}
+
+// failureReason is what is stored, and shown to admins, for a send that got
+// no response. The raw error is only logged: it would tell the caller which
+// internal hosts and ports exist.
+func failureReason(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, webhookdomain.ErrForbiddenAddress):
+		return webhookdomain.ErrForbiddenAddress.Error()
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	default:
+		return "request_failed"
+	}
+}
+
+// backoff is the wait after the given failed attempt: RetryBase doubled per
+// earlier attempt and capped at RetryMax, of which a random half is taken
+// so failing endpoints are not retried in lockstep.
+func (w *WebhookUsecase) backoff(attempt int) time.Duration {
+	d := w.conf.RetryMax
+	if shift := attempt - 1; shift < 32 {
+		if exp := w.conf.RetryBase << shift; exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	if d <= 1 {
+		return d
+	}
+	return d/2 + rand.N(d/2)
+}
+
+// Signature is the X-Webhook-Signature header for body sent at the given
+// time: "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">". Receivers
+// should recompute it with the endpoint secret and reject old timestamps.
+func Signature(secret string, at time.Time, body []byte) string {
+	ts := strconv.FormatInt(at.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
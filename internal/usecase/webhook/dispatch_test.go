@@ -0,0 +1,402 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"dekamond/internal/config"
+	userdomain "dekamond/internal/domain/user"
+	webhookdomain "dekamond/internal/domain/webhook"
+	webhookinfra "dekamond/internal/infra/webhook"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+type mockWebhookRepository struct {
+	mu         sync.Mutex
+	endpoints  []webhookdomain.Endpoint
+	outbox     []userdomain.Event
+	dispatched int
+	deliveries []*webhookdomain.Delivery
+}
+
+func (m *mockWebhookRepository) CreateEndpoint(ctx context.Context, e *webhookdomain.Endpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e.ID = uuid.New().String()
+	e.CreatedAt = time.Now()
+	m.endpoints = append(m.endpoints, *e)
+	return nil
+}
+
+func (m *mockWebhookRepository) ListEndpoints(ctx context.Context) ([]webhookdomain.Endpoint, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []webhookdomain.Endpoint
+	for _, e := range m.endpoints {
+		e.Secret = ""
+		out = append(out, e)
+	}
+	return out, nil
+}
+
+func (m *mockWebhookRepository) DeleteEndpoint(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, e := range m.endpoints {
+		if e.ID == id {
+			m.endpoints = append(m.endpoints[:i], m.endpoints[i+1:]...)
+			return nil
+		}
+	}
+	return pgx.ErrNoRows
+}
+
+func (m *mockWebhookRepository) FanOut(ctx context.Context, limit int) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for ; m.dispatched < len(m.outbox) && n < limit; m.dispatched++ {
+		ev := m.outbox[m.dispatched]
+		for _, e := range m.endpoints {
+			if len(e.Events) > 0 && !containsType(e.Events, ev.Type) {
+				continue
+			}
+			m.deliveries = append(m.deliveries, &webhookdomain.Delivery{
+				ID:            int64(len(m.deliveries) + 1),
+				EndpointID:    e.ID,
+				URL:           e.URL,
+				Secret:        e.Secret,
+				Event:         ev,
+				Status:        webhookdomain.StatusPending,
+				NextAttemptAt: time.Now(),
+				CreatedAt:     time.Now(),
+			})
+		}
+		n++
+	}
+	return n, nil
+}
+
+func containsType(types []userdomain.EventType, t userdomain.EventType) bool {
+	for _, x := range types {
+		if x == t {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *mockWebhookRepository) ClaimDue(ctx context.Context, limit int, lease time.Duration) ([]webhookdomain.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []webhookdomain.Delivery
+	now := time.Now()
+	for _, d := range m.deliveries {
+		if len(out) == limit {
+			break
+		}
+		if d.Status == webhookdomain.StatusPending && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = now.Add(lease)
+			out = append(out, *d)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockWebhookRepository) delivery(id int64) *webhookdomain.Delivery {
+	for _, d := range m.deliveries {
+		if d.ID == id {
+			return d
+		}
+	}
+	return nil
+}
+
+func (m *mockWebhookRepository) MarkDelivered(ctx context.Context, id int64, status int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := m.delivery(id)
+	now := time.Now()
+	d.Status, d.Attempts, d.LastStatus, d.LastError, d.DeliveredAt = webhookdomain.StatusDelivered, d.Attempts+1, status, "", &now
+	return nil
+}
+
+func (m *mockWebhookRepository) MarkFailed(ctx context.Context, id int64, status int, reason string, retryAt *time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := m.delivery(id)
+	d.Attempts++
+	d.LastStatus, d.LastError = status, reason
+	if retryAt == nil {
+		d.Status = webhookdomain.StatusDead
+	} else {
+		d.NextAttemptAt = *retryAt
+	}
+	return nil
+}
+
+func (m *mockWebhookRepository) ListDeliveries(ctx context.Context, endpointID string, status webhookdomain.DeliveryStatus, limit int) ([]webhookdomain.Delivery, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []webhookdomain.Delivery
+	for i := len(m.deliveries) - 1; i >= 0 && len(out) < limit; i-- {
+		d := m.deliveries[i]
+		if d.EndpointID == endpointID && (status == "" || d.Status == status) {
+			out = append(out, *d)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockWebhookRepository) Redeliver(ctx context.Context, endpointID string, id int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := m.delivery(id)
+	if d == nil || d.EndpointID != endpointID || d.Status != webhookdomain.StatusDead {
+		return pgx.ErrNoRows
+	}
+	d.Status, d.Attempts, d.NextAttemptAt = webhookdomain.StatusPending, 0, time.Now()
+	return nil
+}
+
+// makeDue lets the next DispatchOnce retry every pending delivery.
+func (m *mockWebhookRepository) makeDue() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, d := range m.deliveries {
+		d.NextAttemptAt = time.Now()
+	}
+}
+
+var testWebhookConfig = config.WebhookConfig{
+	BatchSize:   10,
+	Concurrency: 2,
+	Timeout:     2 * time.Second,
+	MaxAttempts: 3,
+	RetryBase:   time.Minute,
+	RetryMax:    time.Hour,
+	// The test receivers listen on loopback.
+	AllowedNetworks: []string{"127.0.0.1", "::1"},
+}
+
+func newTestSender() *webhookinfra.HTTPSender {
+	return webhookinfra.NewHTTPSender(time.Second, webhookdomain.NewAddressPolicy(testWebhookConfig.AllowedNetworks))
+}
+
+type received struct {
+	path   string
+	header http.Header
+	body   []byte
+}
+
+// newReceiver starts a local endpoint that answers with *status and keeps
+// what it received.
+func newReceiver(t *testing.T, status *atomic.Int32) (*httptest.Server, func() []received) {
+	var mu sync.Mutex
+	var got []received
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		got = append(got, received{path: r.URL.Path, header: r.Header.Clone(), body: body})
+		mu.Unlock()
+		w.WriteHeader(int(status.Load()))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() []received {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]received(nil), got...)
+	}
+}
+
+func TestDispatchDeliversSignedEvents(t *testing.T) {
+	ctx := context.Background()
+	var status atomic.Int32
+	status.Store(http.StatusNoContent)
+	srv, got := newReceiver(t, &status)
+
+	repo := &mockWebhookRepository{}
+	uc := New(repo, newTestSender(), testWebhookConfig)
+	secrets := map[string]string{}
+	for path, events := range map[string][]string{"/all": nil, "/signups": {"user.created"}} {
+		e, err := uc.CreateEndpoint(ctx, srv.URL+path, events)
+		if err != nil {
+			t.Fatalf("CreateEndpoint: %v", err)
+		}
+		secrets[path] = e.Secret
+	}
+
+	repo.outbox = []userdomain.Event{
+		{ID: "ev-1", Type: userdomain.EventCreated, OccurredAt: time.Now(), Data: map[string]any{"user_id": "user-1", "phone": "+15551234567"}},
+		{ID: "ev-2", Type: userdomain.EventLoggedIn, OccurredAt: time.Now(), Data: map[string]any{"user_id": "user-1"}},
+	}
+	n, err := uc.DispatchOnce(ctx)
+	if err != nil {
+		t.Fatalf("DispatchOnce: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("delivered %d, want 3 (two to the catch-all endpoint, one to the sign-up endpoint)", n)
+	}
+
+	for _, r := range got() {
+		var ev userdomain.Event
+		if err := json.Unmarshal(r.body, &ev); err != nil {
+			t.Fatalf("body is not an event: %s", r.body)
+		}
+		if r.path == "/signups" && ev.Type != userdomain.EventCreated {
+			t.Errorf("sign-up endpoint received %s", ev.Type)
+		}
+		if r.header.Get(HeaderEvent) != string(ev.Type) || r.header.Get(HeaderDelivery) == "" {
+			t.Errorf("headers = %v", r.header)
+		}
+		if ev.Data["user_id"] != "user-1" {
+			t.Errorf("data = %v", ev.Data)
+		}
+
+		sig := r.header.Get(HeaderSignature)
+		ts, _, _ := strings.Cut(strings.TrimPrefix(sig, "t="), ",")
+		unix, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil || time.Since(time.Unix(unix, 0)) > time.Minute {
+			t.Errorf("signature %q has no current timestamp", sig)
+		}
+		if Signature(secrets[r.path], time.Unix(unix, 0), r.body) != sig {
+			t.Errorf("signature %q does not verify with the secret of %s", sig, r.path)
+		}
+	}
+	if n, err := uc.DispatchOnce(ctx); err != nil || n != 0 {
+		t.Errorf("second DispatchOnce = %d, %v; want nothing left to send", n, err)
+	}
+}
+
+func TestDispatchRetriesThenDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	srv, got := newReceiver(t, &status)
+
+	repo := &mockWebhookRepository{}
+	uc := New(repo, newTestSender(), testWebhookConfig)
+	endpoint, _ := uc.CreateEndpoint(ctx, srv.URL, nil)
+	repo.outbox = []userdomain.Event{{ID: "ev-1", Type: userdomain.EventDeleted, Data: map[string]any{"user_id": "user-1"}}}
+
+	for attempt := 1; attempt <= testWebhookConfig.MaxAttempts; attempt++ {
+		before := time.Now()
+		if _, err := uc.DispatchOnce(ctx); err != nil {
+			t.Fatalf("DispatchOnce: %v", err)
+		}
+		d := repo.deliveries[0]
+		if d.Attempts != attempt || d.LastStatus != http.StatusInternalServerError {
+			t.Fatalf("after attempt %d: %+v", attempt, d)
+		}
+		if attempt < testWebhookConfig.MaxAttempts {
+			if d.Status != webhookdomain.StatusPending {
+				t.Fatalf("after attempt %d status = %s, want pending", attempt, d.Status)
+			}
+			wait := testWebhookConfig.RetryBase << (attempt - 1)
+			if delay := d.NextAttemptAt.Sub(before); delay < wait/2 || delay > wait+time.Second {
+				t.Errorf("after attempt %d retry in %s, want between %s and %s", attempt, delay, wait/2, wait)
+			}
+			if n, _ := uc.DispatchOnce(ctx); n != 0 || len(got()) != attempt {
+				t.Fatalf("retried before the backoff was over")
+			}
+			repo.makeDue()
+		}
+	}
+	if d := repo.deliveries[0]; d.Status != webhookdomain.StatusDead {
+		t.Fatalf("status = %s after %d attempts, want dead", d.Status, d.Attempts)
+	}
+
+	dead, err := uc.ListDeliveries(ctx, endpoint.ID, "dead", 0)
+	if err != nil || len(dead) != 1 {
+		t.Fatalf("ListDeliveries(dead) = %v, %v", dead, err)
+	}
+	if err := uc.Redeliver(ctx, endpoint.ID, dead[0].ID); err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if err := uc.Redeliver(ctx, endpoint.ID, dead[0].ID); !errors.Is(err, ErrDeliveryNotFound) {
+		t.Errorf("Redeliver of a pending delivery = %v, want %v", err, ErrDeliveryNotFound)
+	}
+	status.Store(http.StatusOK)
+	if n, err := uc.DispatchOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("DispatchOnce after redeliver = %d, %v", n, err)
+	}
+	if d := repo.deliveries[0]; d.Status != webhookdomain.StatusDelivered || d.Attempts != 1 {
+		t.Errorf("after redelivery: %+v", d)
+	}
+}
+
+func TestDispatchRecordsConnectionErrors(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	repo := &mockWebhookRepository{}
+	uc := New(repo, newTestSender(), testWebhookConfig)
+	_, _ = uc.CreateEndpoint(ctx, srv.URL, nil)
+	repo.outbox = []userdomain.Event{{ID: "ev-1", Type: userdomain.EventCreated}}
+
+	if _, err := uc.DispatchOnce(ctx); err != nil {
+		t.Fatalf("DispatchOnce: %v", err)
+	}
+	d := repo.deliveries[0]
+	if d.Status != webhookdomain.StatusPending || d.LastStatus != 0 || d.LastError != "request_failed" {
+		t.Errorf("delivery = %+v, want a pending retry with the connection error", d)
+	}
+}
+
+func TestDispatchRefusesInternalAddresses(t *testing.T) {
+	ctx := context.Background()
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	srv, got := newReceiver(t, &status)
+
+	// The endpoint passes the URL check, but the sender has no allowlist,
+	// as if the hostname had been re-pointed at loopback after creation.
+	repo := &mockWebhookRepository{}
+	uc := New(repo, webhookinfra.NewHTTPSender(time.Second, webhookdomain.NewAddressPolicy(nil)), testWebhookConfig)
+	_, _ = uc.CreateEndpoint(ctx, srv.URL, nil)
+	repo.outbox = []userdomain.Event{{ID: "ev-1", Type: userdomain.EventCreated}}
+
+	if _, err := uc.DispatchOnce(ctx); err != nil {
+		t.Fatalf("DispatchOnce: %v", err)
+	}
+	if n := len(got()); n != 0 {
+		t.Errorf("receiver got %d requests, want 0", n)
+	}
+	if d := repo.deliveries[0]; d.LastError != webhookdomain.ErrForbiddenAddress.Error() {
+		t.Errorf("LastError = %q, want %q", d.LastError, webhookdomain.ErrForbiddenAddress)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	uc := New(nil, nil, config.WebhookConfig{RetryBase: 10 * time.Second, RetryMax: time.Minute})
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{4, time.Minute},
+		{40, time.Minute},
+	}
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			if d := uc.backoff(tt.attempt); d < tt.max/2 || d > tt.max {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", tt.attempt, d, tt.max/2, tt.max)
+			}
+		}
+	}
+}
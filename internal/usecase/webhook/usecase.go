@@ -0,0 +1,155 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+
+	"dekamond/internal/config"
+	userdomain "dekamond/internal/domain/user"
+	webhookdomain "dekamond/internal/domain/webhook"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	ErrInvalidURL       = errors.New("invalid_url")
+	ErrUnknownEvent     = errors.New("unknown_event")
+	ErrInvalidStatus    = errors.New("invalid_status")
+	ErrEndpointNotFound = errors.New("endpoint_not_found")
+	ErrDeliveryNotFound = errors.New("delivery_not_found")
+)
+
+type WebhookUsecase struct {
+	webhooks webhookdomain.Repository
+	sender   webhookdomain.Sender
+	conf     config.WebhookConfig
+	policy   webhookdomain.AddressPolicy
+}
+
+func New(webhooks webhookdomain.Repository, sender webhookdomain.Sender, conf config.WebhookConfig) *WebhookUsecase {
+	return &WebhookUsecase{
+		webhooks: webhooks,
+		sender:   sender,
+		conf:     conf,
+		policy:   webhookdomain.NewAddressPolicy(conf.AllowedNetworks),
+	}
+}
+
+// CreateEndpoint registers rawURL for the given events, or for every event
+// if none are given. The returned endpoint carries its signing secret, which
+// is not shown again. URLs naming a forbidden address or localhost are
+// rejected up front; hostnames are checked again by the sender on every
+// connection.
+func (w *WebhookUsecase) CreateEndpoint(ctx context.Context, rawURL string, events []string) (*webhookdomain.Endpoint, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" || len(rawURL) > 2048 {
+		return nil, ErrInvalidURL
+	}
+	if !w.hostPermitted(u.Hostname()) {
+		return nil, ErrInvalidURL
+	}
+	types := []userdomain.EventType{}
+	for _, e := range events {
+		t := userdomain.EventType(e)
+		if !slices.Contains(userdomain.EventTypes, t) {
+			return nil, ErrUnknownEvent
+		}
+		if !slices.Contains(types, t) {
+			types = append(types, t)
+		}
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, err
+	}
+	endpoint := &webhookdomain.Endpoint{URL: u.String(), Secret: secret, Events: types}
+	if err := w.webhooks.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, fmt.Errorf("failed to create webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+func (w *WebhookUsecase) hostPermitted(host string) bool {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return w.policy.Permits(netip.IPv6Loopback()) || w.policy.Permits(netip.MustParseAddr("127.0.0.1"))
+	}
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return w.policy.Permits(addr)
+	}
+	return true
+}
+
+func (w *WebhookUsecase) ListEndpoints(ctx context.Context) ([]webhookdomain.Endpoint, error) {
+	endpoints, err := w.webhooks.ListEndpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if endpoints == nil {
+		endpoints = []webhookdomain.Endpoint{}
+	}
+	return endpoints, nil
+}
+
+func (w *WebhookUsecase) DeleteEndpoint(ctx context.Context, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return ErrEndpointNotFound
+	}
+	err := w.webhooks.DeleteEndpoint(ctx, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrEndpointNotFound
+	}
+	return err
+}
+
+// ListDeliveries returns the latest deliveries to the endpoint, optionally
+// only those with the given status, e.g. "dead".
+func (w *WebhookUsecase) ListDeliveries(ctx context.Context, endpointID, status string, limit int) ([]webhookdomain.Delivery, error) {
+	if _, err := uuid.Parse(endpointID); err != nil {
+		return nil, ErrEndpointNotFound
+	}
+	switch webhookdomain.DeliveryStatus(status) {
+	case "", webhookdomain.StatusPending, webhookdomain.StatusDelivered, webhookdomain.StatusDead:
+	default:
+		return nil, ErrInvalidStatus
+	}
+	if limit < 1 || limit > 200 {
+		limit = 50
+	}
+	deliveries, err := w.webhooks.ListDeliveries(ctx, endpointID, webhookdomain.DeliveryStatus(status), limit)
+	if err != nil {
+		return nil, err
+	}
+	if deliveries == nil {
+		deliveries = []webhookdomain.Delivery{}
+	}
+	return deliveries, nil
+}
+
+// Redeliver gives a dead delivery a fresh set of attempts.
+func (w *WebhookUsecase) Redeliver(ctx context.Context, endpointID string, id int64) error {
+	if _, err := uuid.Parse(endpointID); err != nil {
+		return ErrDeliveryNotFound
+	}
+	err := w.webhooks.Redeliver(ctx, endpointID, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrDeliveryNotFound
+	}
+	return err
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
@@ -0,0 +1,106 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	userdomain "dekamond/internal/domain/user"
+)
+
+func TestCreateEndpoint(t *testing.T) {
+	tests := []struct {
+		name       string
+		url        string
+		events     []string
+		wantEvents []userdomain.EventType
+		wantErr    error
+	}{
+		{name: "all events", url: "https://hooks.example.com/users", wantEvents: []userdomain.EventType{}},
+		{
+			name:       "duplicate events are dropped",
+			url:        "http://localhost:9000/hook",
+			events:     []string{"user.created", "user.deleted", "user.created"},
+			wantEvents: []userdomain.EventType{userdomain.EventCreated, userdomain.EventDeleted},
+		},
+		{name: "unknown event", url: "https://hooks.example.com", events: []string{"user.updated"}, wantErr: ErrUnknownEvent},
+		{name: "not http", url: "ftp://hooks.example.com", wantErr: ErrInvalidURL},
+		{name: "relative", url: "/hooks", wantErr: ErrInvalidURL},
+		{name: "empty", url: "", wantErr: ErrInvalidURL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &mockWebhookRepository{}
+			endpoint, err := New(repo, nil, testWebhookConfig).CreateEndpoint(context.Background(), tt.url, tt.events)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("err = %v, want %v", err, tt.wantErr)
+				}
+				if len(repo.endpoints) != 0 {
+					t.Error("invalid endpoint was stored")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("CreateEndpoint: %v", err)
+			}
+			if !reflect.DeepEqual(endpoint.Events, tt.wantEvents) {
+				t.Errorf("Events = %v, want %v", endpoint.Events, tt.wantEvents)
+			}
+			if !strings.HasPrefix(endpoint.Secret, "whsec_") || len(endpoint.Secret) != len("whsec_")+64 {
+				t.Errorf("Secret = %q", endpoint.Secret)
+			}
+		})
+	}
+}
+
+func TestCreateEndpointRejectsInternalAddresses(t *testing.T) {
+	conf := testWebhookConfig
+	conf.AllowedNetworks = []string{"10.1.2.0/24"}
+	tests := []struct {
+		url  string
+		want error
+	}{
+		{url: "http://127.0.0.1:6379", want: ErrInvalidURL},
+		{url: "http://localhost:8080/hook", want: ErrInvalidURL},
+		{url: "http://api.localhost/hook", want: ErrInvalidURL},
+		{url: "http://10.0.0.5/hook", want: ErrInvalidURL},
+		{url: "http://169.254.169.254/latest/meta-data", want: ErrInvalidURL},
+		{url: "http://100.64.0.1/hook", want: ErrInvalidURL},
+		{url: "http://0.0.0.0:8080", want: ErrInvalidURL},
+		{url: "http://[::1]:8080", want: ErrInvalidURL},
+		{url: "http://[::ffff:127.0.0.1]:8080", want: ErrInvalidURL},
+		{url: "http://[fd00::1]/hook", want: ErrInvalidURL},
+		{url: "http://10.1.2.3/hook"},
+		{url: "https://93.184.216.34/hook"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			_, err := New(&mockWebhookRepository{}, nil, conf).CreateEndpoint(context.Background(), tt.url, nil)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDeleteEndpoint(t *testing.T) {
+	ctx := context.Background()
+	repo := &mockWebhookRepository{}
+	uc := New(repo, nil, testWebhookConfig)
+	endpoint, _ := uc.CreateEndpoint(ctx, "https://hooks.example.com", nil)
+
+	if err := uc.DeleteEndpoint(ctx, endpoint.ID); err != nil {
+		t.Fatalf("DeleteEndpoint: %v", err)
+	}
+	if err := uc.DeleteEndpoint(ctx, endpoint.ID); !errors.Is(err, ErrEndpointNotFound) {
+		t.Errorf("second DeleteEndpoint = %v, want %v", err, ErrEndpointNotFound)
+	}
+	if err := uc.DeleteEndpoint(ctx, "not-a-uuid"); !errors.Is(err, ErrEndpointNotFound) {
+		t.Errorf("DeleteEndpoint(not-a-uuid) = %v, want %v", err, ErrEndpointNotFound)
+	}
+}